@@ -0,0 +1,489 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"website-copier/cmd/combine"
+	"website-copier/cmd/compare"
+	"website-copier/cmd/dedupe"
+	"website-copier/cmd/domains"
+	"website-copier/cmd/filter"
+	"website-copier/cmd/normalize"
+	"website-copier/cmd/provenance"
+	"website-copier/cmd/records"
+	"website-copier/cmd/suppress"
+	"website-copier/cmd/typos"
+	"website-copier/cmd/utils"
+	"website-copier/cmd/validate"
+)
+
+// commands maps each headless subcommand to its handler
+var commands = map[string]func(args []string) error{
+	"combine": runCombine,
+	"filter":  runFilter,
+	"compare": runCompare,
+}
+
+const usage = `Usage: datamerge <command> [options]
+
+Commands:
+  combine   Merge CSV/XLSX files into one file, removing duplicate emails
+  filter    Remove records whose email appears in one or more suppression lists
+  compare   List the records added, removed and changed between two exports
+
+Run "datamerge <command> -h" for the options of a command.
+Without a command the DataMerge Pro app starts its graphical interface.
+`
+
+// IsCommand reports whether arg names a headless subcommand
+func IsCommand(arg string) bool {
+	if _, ok := commands[arg]; ok {
+		return true
+	}
+	return arg == "help" || arg == "-h" || arg == "--help"
+}
+
+// Run executes the subcommand named by args[0] without starting the GUI
+func Run(args []string) error {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, usage)
+		return fmt.Errorf("no command given")
+	}
+	handler, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprint(os.Stderr, usage)
+		if IsCommand(args[0]) {
+			return nil
+		}
+		return fmt.Errorf("unknown command: %s", args[0])
+	}
+	err := handler(args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		// The flag package already printed the options
+		return nil
+	}
+	return err
+}
+
+// parseArgs parses the options of a command, which may come before or after
+// its file arguments, and returns the file arguments
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	var files []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		if fs.NArg() == 0 {
+			return files, nil
+		}
+		files = append(files, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
+
+func runCombine(args []string) error {
+	fs := flag.NewFlagSet("combine", flag.ContinueOnError)
+	var inputs stringList
+	fs.Var(&inputs, "in", "input file or folder, or one sheet of a workbook as FILE:SHEET (repeatable)")
+	out := fs.String("out", "", "output CSV or XLSX file")
+	columnOrder := fs.String("columns", "", "comma separated columns to place first in the output")
+	sortColumns := fs.Bool("sort-columns", false, "sort the remaining output columns alphabetically")
+	logPath := fs.String("log", "", "write the process log to this file instead of stderr")
+	strategy := fs.String("merge", dedupe.First, "which duplicate wins, one of: "+strings.Join(dedupe.Strategies, ", "))
+	fillBlanks := fs.Bool("fill-blanks", true, "fill blank fields of the winning duplicate from the others")
+	priority := fs.String("priority", "", "comma separated source files for the priority strategy, highest first (names or glob patterns)")
+	dateColumn := fs.String("date-column", "", "column compared by the newest strategy")
+	fuzzy := fs.Bool("fuzzy", false, "also find duplicates with different emails by comparing the names of records sharing an email domain or OrgName, numbered in a \""+dedupe.ClusterColumn+"\" column and listed in <out>_duplicates; a personal and a work address of the same person are only compared when both records have the same OrgName")
+	fuzzyMerge := fs.Bool("fuzzy-merge", false, "with --fuzzy: merge the duplicates found instead of leaving them for review")
+	fuzzyBlock := fs.String("fuzzy-block", strings.Join(dedupe.Blocks, ","), "with --fuzzy: comma separated keys records must share to be compared: "+strings.Join(dedupe.Blocks, ", "))
+	fuzzyThreshold := fs.Float64("fuzzy-threshold", dedupe.DefaultThreshold, "with --fuzzy: similarity between 0 and 1 from which records are duplicates")
+	fuzzyMaxBlock := fs.Int("fuzzy-max-block", dedupe.DefaultMaxBlock, "with --fuzzy: most records sharing a key and a name initial that are compared pair by pair; larger groups are skipped and logged")
+	workers := fs.Int("workers", 0, "number of files read at the same time (default: one per CPU)")
+	domainCategory := fs.Bool("domain-category", false, "add a \""+domains.Column+"\" column: "+strings.Join(domains.Categories, ", "))
+	domainLists := domainListsFlag(fs)
+	provenanceColumns := provenanceFlag(fs, provenance.Names)
+	fixTypos := fs.Bool("typos", false, "suggest fixes for misspelt domains such as gmial.com in a \""+typos.Column+"\" column and list them in <out>_corrections")
+	autoCorrect := fs.Bool("typos-fix", false, "with --typos: replace misspelt emails instead of only suggesting the fix")
+	knownDomains := fs.String("known-domains", "", "with --typos: file of known domains, one per line, extending the bundled list (also read from "+typos.KnownPath()+")")
+	maxDistance := fs.Int("typos-distance", typos.DefaultMaxDistance, "with --typos: largest number of edits between a domain and a known domain")
+	rules := normalizeFlag(fs)
+	columns := columnFlags(fs)
+	output := outputFlags(fs)
+	validation := validateFlags(fs)
+	files, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	inputs = append(inputs, files...)
+	normalizeOpts, err := normalize.FromNames(splitList(*rules))
+	if err != nil {
+		return err
+	}
+	mapping, err := columns.mapping()
+	if err != nil {
+		return err
+	}
+	inputs = sheetInputs(inputs, &mapping)
+	provenanceOpts, err := provenance.Parse(splitList(*provenanceColumns), provenance.Names)
+	if err != nil {
+		return err
+	}
+
+	closeLog, err := setupLogger(*logPath)
+	if err != nil {
+		return err
+	}
+	defer closeLog()
+
+	result, err := combine.Run(combine.Config{
+		Inputs:     inputs,
+		OutputPath: *out,
+		Normalize:  normalizeOpts,
+		Mapping:    mapping,
+		Schema:     records.SchemaOptions{Order: splitList(*columnOrder), Sort: *sortColumns},
+		Dedupe: dedupe.Options{
+			Strategy:   *strategy,
+			FillBlanks: *fillBlanks,
+			Priority:   splitList(*priority),
+			DateColumn: *dateColumn,
+			Fuzzy: dedupe.FuzzyOptions{
+				Enabled:   *fuzzy,
+				Block:     splitList(*fuzzyBlock),
+				Threshold: *fuzzyThreshold,
+				AutoMerge: *fuzzyMerge,
+				MaxBlock:  *fuzzyMaxBlock,
+			},
+		},
+		Validate: validation.options(),
+		Typos: typos.Options{
+			Enabled:      *fixTypos,
+			AutoCorrect:  *autoCorrect,
+			MaxDistance:  *maxDistance,
+			KnownDomains: *knownDomains,
+		},
+		Workers: *workers,
+		Output:  output.options(),
+
+		DomainCategory: *domainCategory,
+		DomainLists:    *domainLists,
+		Provenance:     provenance.Options{Columns: provenanceOpts},
+	})
+	if err != nil {
+		return err
+	}
+	utils.LogMessage(fmt.Sprintf("Combined %d files into %d unique records", result.Files, result.Records))
+	return nil
+}
+
+func runFilter(args []string) error {
+	fs := flag.NewFlagSet("filter", flag.ContinueOnError)
+	var inputs stringList
+	fs.Var(&inputs, "in", "input file or folder, or one sheet of a workbook as FILE:SHEET (repeatable)")
+	var dbs stringList
+	fs.Var(&dbs, "db", "suppression list CSV, TSV or XLSX file with the emails to remove, optionally labelled as Label=FILE (repeatable)")
+	dbHash := fs.String("db-hash", "auto", "hash algorithm of the list entries: auto (detect hex digests), none, md5, sha1 or sha256")
+	dbColumns := fs.String("db-columns", "", "comma separated list columns holding emails or rules (default: every email-like column, or the Domain, Rule, Pattern and hash columns of sheets without one)")
+	out := fs.String("out", "", "output CSV or XLSX file")
+	mode := fs.String("mode", filter.ModeExclude, "exclude: keep records in no list, keep: keep only records found in a list, split: write records only in the input, records in both and list entries matching no record to three files named after --out")
+	rejected := fs.String("rejected", "", "also write the records left out of the output with the reason for each to this CSV or XLSX file")
+	columnList := fs.String("columns", "", "comma separated output columns in order (default: every column of every input)")
+	logPath := fs.String("log", "", "write the process log to this file instead of stderr")
+	excludeDomains := fs.String("exclude-domains", "", "comma separated domain categories to treat as listed, from: "+strings.Join(domains.Categories, ", "))
+	domainLists := domainListsFlag(fs)
+	provenanceColumns := provenanceFlag(fs, provenance.RecordNames)
+	rules := normalizeFlag(fs)
+	columns := columnFlags(fs)
+	output := outputFlags(fs)
+	validation := validateFlags(fs)
+	files, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	inputs = append(inputs, files...)
+	normalizeOpts, err := normalize.FromNames(splitList(*rules))
+	if err != nil {
+		return err
+	}
+	mapping, err := columns.mapping()
+	if err != nil {
+		return err
+	}
+	inputs = sheetInputs(inputs, &mapping)
+	categories, err := domains.ParseCategories(splitList(*excludeDomains))
+	if err != nil {
+		return err
+	}
+	provenanceOpts, err := provenance.Parse(splitList(*provenanceColumns), provenance.RecordNames)
+	if err != nil {
+		return err
+	}
+
+	closeLog, err := setupLogger(*logPath)
+	if err != nil {
+		return err
+	}
+	defer closeLog()
+
+	hash, err := suppress.ParseHash(*dbHash)
+	if err != nil {
+		return err
+	}
+	var lists []suppress.List
+	for _, db := range dbs {
+		list := parseList(db, splitList(*dbColumns))
+		list.Hash = hash
+		lists = append(lists, list)
+	}
+
+	_, err = filter.Run(filter.Config{
+		InputPaths:       inputs,
+		Lists:            lists,
+		OutputFilePath:   *out,
+		Mode:             *mode,
+		RejectedFilePath: *rejected,
+		Normalize:        normalizeOpts,
+		Mapping:          mapping,
+		Validate:         validation.options(),
+		Columns:          splitList(*columnList),
+		Output:           output.options(),
+
+		ExcludeCategories: categories,
+		DomainLists:       *domainLists,
+		Provenance:        provenance.Options{Columns: provenanceOpts},
+	})
+	return err
+}
+
+func runCompare(args []string) error {
+	fs := flag.NewFlagSet("compare", flag.ContinueOnError)
+	oldPath := fs.String("old", "", "earlier CSV, TSV or XLSX export")
+	newPath := fs.String("new", "", "later CSV, TSV or XLSX export")
+	out := fs.String("out", "", "output CSV, XLSX or JSON file")
+	logPath := fs.String("log", "", "write the process log to this file instead of stderr")
+	rules := normalizeFlag(fs)
+	columns := columnFlags(fs)
+	output := outputFlags(fs)
+	// The files can also be given as "compare OLD NEW", before or after the options
+	var files []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+		if fs.NArg() == 0 {
+			break
+		}
+		files = append(files, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+	if *oldPath == "" && len(files) > 0 {
+		*oldPath, files = files[0], files[1:]
+	}
+	if *newPath == "" && len(files) > 0 {
+		*newPath, files = files[0], files[1:]
+	}
+	if len(files) > 0 {
+		return fmt.Errorf("unexpected arguments: %s", strings.Join(files, " "))
+	}
+	normalizeOpts, err := normalize.FromNames(splitList(*rules))
+	if err != nil {
+		return err
+	}
+	mapping, err := columns.mapping()
+	if err != nil {
+		return err
+	}
+
+	closeLog, err := setupLogger(*logPath)
+	if err != nil {
+		return err
+	}
+	defer closeLog()
+
+	_, err = compare.Run(compare.Config{
+		OldPath:        *oldPath,
+		NewPath:        *newPath,
+		OutputFilePath: *out,
+		Normalize:      normalizeOpts,
+		Mapping:        mapping,
+		Output:         output.options(),
+	})
+	return err
+}
+
+// parseList reads a --db value, either a file or Label=FILE
+func parseList(value string, columns []string) suppress.List {
+	if _, err := os.Stat(value); err != nil {
+		if label, path, ok := strings.Cut(value, "="); ok && label != "" {
+			return suppress.List{Label: label, Path: path, Columns: columns}
+		}
+	}
+	return suppress.List{Label: suppress.DefaultLabel(value), Path: value, Columns: columns}
+}
+
+// normalizeFlag registers the --normalize flag listing the email normalization rules to apply
+func normalizeFlag(fs *flag.FlagSet) *string {
+	var names []string
+	for _, rule := range normalize.Rules {
+		names = append(names, rule.Name)
+	}
+	return fs.String("normalize", strings.Join(normalize.DefaultOptions().Names(), ","),
+		"comma separated email normalization rules, any of: "+strings.Join(names, ", "))
+}
+
+// mappingFlags holds the --mapping and --column flags
+type mappingFlags struct {
+	file      *string
+	columns   stringList
+	dialect   records.Dialect
+	headerRow *int
+	skip      stringList
+}
+
+func columnFlags(fs *flag.FlagSet) *mappingFlags {
+	m := &mappingFlags{}
+	m.file = fs.String("mapping", "", "JSON column mapping file with global and per-file header and CSV overrides")
+	fs.Var(&m.columns, "column", "pin a field to a header for every file, e.g. Email=\"Work Email\" (repeatable)")
+	fs.StringVar(&m.dialect.Delimiter, "delimiter", "", "CSV delimiter of every input, e.g. ; or tab (default: detected)")
+	fs.StringVar(&m.dialect.Quote, "quote", "", "CSV quote character of every input, e.g. ' or none (default: detected)")
+	fs.StringVar(&m.dialect.Encoding, "encoding", "", "CSV encoding of every input, one of: "+strings.Join(records.Encodings, ", ")+" (default: detected)")
+	fs.Var(&m.skip, "skip-sheet", "leave out a sheet of every workbook, or of one as FILE:SHEET (repeatable)")
+	m.headerRow = fs.Int("header-row", 0, "row holding the headers of every input sheet, counting from 1 (default: the best match among the first rows)")
+	return m
+}
+
+// mapping builds the column mapping from the mapping file and the --column overrides
+func (m *mappingFlags) mapping() (records.ColumnMapping, error) {
+	var mapping records.ColumnMapping
+	if *m.file != "" {
+		var err error
+		if mapping, err = records.LoadColumnMapping(*m.file); err != nil {
+			return mapping, err
+		}
+	}
+	for _, column := range m.columns {
+		role, header, ok := strings.Cut(column, "=")
+		parsed, valid := records.ParseRole(role)
+		if !ok || !valid || header == "" {
+			return mapping, fmt.Errorf("invalid --column %q, expected Name=Header, Email=Header or OrgName=Header", column)
+		}
+		mapping.SetOverride("", parsed, header)
+	}
+	if err := m.dialect.Validate(); err != nil {
+		return mapping, err
+	}
+	mapping.CSV = mapping.CSV.Merge(m.dialect)
+	if *m.headerRow < 0 {
+		return mapping, fmt.Errorf("invalid --header-row %d, expected a row number from 1", *m.headerRow)
+	}
+	if *m.headerRow > 0 {
+		mapping.HeaderRow = *m.headerRow
+	}
+	mapping.SkipSheets = append(mapping.SkipSheets, m.skip...)
+	return mapping, nil
+}
+
+// sheetInputs turns inputs naming a sheet, like leads.xlsx:Contacts, into the
+// workbook limited to the sheets named
+func sheetInputs(inputs []string, mapping *records.ColumnMapping) []string {
+	var files []string
+	seen := make(map[string]bool)
+	for _, input := range inputs {
+		file, sheet := records.SplitSheetInput(input)
+		if sheet != "" {
+			mapping.Sheets = append(mapping.Sheets, input)
+		}
+		if !seen[file] {
+			seen[file] = true
+			files = append(files, file)
+		}
+	}
+	return files
+}
+
+// sheetFlags holds the flags splitting XLSX output into sheets
+type sheetFlags struct {
+	rows   *int
+	column *string
+}
+
+func outputFlags(fs *flag.FlagSet) *sheetFlags {
+	return &sheetFlags{
+		rows:   fs.Int("sheet-rows", 0, "XLSX output: records per sheet before starting a new sheet (default: as many as Excel allows)"),
+		column: fs.String("split-by", "", "XLSX output: put records on one sheet per value of this column"),
+	}
+}
+
+func (s *sheetFlags) options() records.WriterOptions {
+	return records.WriterOptions{SheetRows: *s.rows, SplitColumn: *s.column}
+}
+
+// validationFlags holds the email validation flags
+type validationFlags struct {
+	enabled, splitMultiple, quarantineRoles *bool
+}
+
+func validateFlags(fs *flag.FlagSet) *validationFlags {
+	return &validationFlags{
+		enabled:         fs.Bool("validate", false, "check the syntax of every email and write unusable rows to <out>_quarantine"),
+		splitMultiple:   fs.Bool("split-multiple", false, "with --validate: split cells holding several addresses into one record each instead of quarantining them"),
+		quarantineRoles: fs.Bool("quarantine-roles", false, "with --validate: also quarantine role accounts such as info@ and admin@"),
+	}
+}
+
+func (v *validationFlags) options() validate.Options {
+	return validate.Options{Enabled: *v.enabled, SplitMultiple: *v.splitMultiple, QuarantineRoles: *v.quarantineRoles}
+}
+
+// provenanceFlag adds the --provenance flag offering the given columns
+func provenanceFlag(fs *flag.FlagSet, names []string) *string {
+	return fs.String("provenance", "", "comma separated columns telling where each record came from: all or any of "+strings.Join(names, ", "))
+}
+
+func domainListsFlag(fs *flag.FlagSet) *string {
+	return fs.String("domain-lists", "", "folder with disposable.txt, free.txt and education.txt lists extending the bundled ones (also read from "+domains.ListsDir()+")")
+}
+
+// splitList splits a comma separated flag value, dropping empty items
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// setupLogger points the shared logger at the given file, or at stderr when no file is given
+func setupLogger(logPath string) (func(), error) {
+	if logPath == "" {
+		utils.Logger = log.New(os.Stderr, "", log.Ldate|log.Ltime)
+		return func() {}, nil
+	}
+	if err := utils.InitializeLogger(logPath); err != nil {
+		return nil, fmt.Errorf("failed to open log file: %v", err)
+	}
+	return func() {
+		if c, ok := utils.Logger.Writer().(io.Closer); ok {
+			c.Close()
+		}
+	}, nil
+}
+
+// stringList collects the values of a repeatable flag
+type stringList []string
+
+func (s *stringList) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringList) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
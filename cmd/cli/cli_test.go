@@ -0,0 +1,59 @@
+package cli
+
+import (
+	"flag"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestParseArgs(t *testing.T) {
+	tests := []struct {
+		name  string
+		args  []string
+		files []string
+		out   string
+	}{
+		{"options first", []string{"--out", "out.csv", "a.csv", "b.csv"}, []string{"a.csv", "b.csv"}, "out.csv"},
+		{"options last", []string{"a.csv", "--out", "out.csv"}, []string{"a.csv"}, "out.csv"},
+		{"options between", []string{"a.csv", "--out", "out.csv", "b.csv"}, []string{"a.csv", "b.csv"}, "out.csv"},
+		{"no files", []string{"--out", "out.csv"}, nil, "out.csv"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			out := fs.String("out", "", "")
+			files, err := parseArgs(fs, tt.args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(files, tt.files) {
+				t.Errorf("files = %q, want %q", files, tt.files)
+			}
+			if *out != tt.out {
+				t.Errorf("out = %q, want %q", *out, tt.out)
+			}
+		})
+	}
+}
+
+func TestRunHelp(t *testing.T) {
+	// Keep the printed options out of the test output
+	stderr := os.Stderr
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer devNull.Close()
+	os.Stderr = devNull
+	defer func() { os.Stderr = stderr }()
+
+	for _, command := range []string{"combine", "filter", "compare"} {
+		if err := Run([]string{command, "-h"}); err != nil {
+			t.Errorf("%s -h: got %v, want no error", command, err)
+		}
+	}
+	if err := Run([]string{"combine", "--no-such-option"}); err == nil {
+		t.Error("an unknown option should be an error")
+	}
+}
@@ -0,0 +1,368 @@
+package combine
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"website-copier/cmd/dedupe"
+	"website-copier/cmd/domains"
+	"website-copier/cmd/normalize"
+	"website-copier/cmd/pipeline"
+	"website-copier/cmd/provenance"
+	"website-copier/cmd/records"
+	"website-copier/cmd/typos"
+	"website-copier/cmd/utils"
+	"website-copier/cmd/validate"
+)
+
+// Config describes a combine job independently of the GUI
+type Config struct {
+	Inputs     []string // Files or folders to combine
+	OutputPath string   // Destination CSV or XLSX file, appended to when its headers match
+
+	Normalize normalize.Options     // Rules used to decide whether two emails are the same person
+	Mapping   records.ColumnMapping // Header overrides for the Name, Email and OrgName fields
+	Schema    records.SchemaOptions // Order of the output columns
+	Dedupe    dedupe.Options        // How records sharing an email are merged
+	Validate  validate.Options      // Email validation; invalid rows go to a quarantine file next to the output
+	Typos     typos.Options         // Misspelt domain correction; every fix goes to a corrections report next to the output
+	Workers   int                   // Files read at the same time; 0 means one per CPU
+	Output    records.WriterOptions // Sheet splitting of XLSX output
+
+	// Provenance adds columns telling where each record came from
+	Provenance provenance.Options
+
+	// DomainCategory adds a column telling whether each email is disposable, free, education or corporate
+	DomainCategory bool
+	DomainLists    string // Folder with extra domain lists; the bundled lists are always used
+}
+
+// Result summarises a finished combine job
+type Result struct {
+	Files       int
+	Records     int
+	Quarantined int
+	Corrections int
+	Clusters    int // Fuzzy duplicate clusters, merged or left for review
+	Skipped     int // Fuzzy matching blocks too large to compare
+	Appended    bool
+}
+
+// Run combines every CSV/XLSX file found in the inputs into the output file,
+// removing duplicate emails along the way
+func Run(cfg Config) (Result, error) {
+	var result Result
+
+	if len(cfg.Inputs) == 0 {
+		return result, fmt.Errorf("no input folder or files given")
+	}
+	if cfg.OutputPath == "" {
+		return result, fmt.Errorf("no output file given")
+	}
+	if err := records.CheckOutputFile(cfg.OutputPath); err != nil {
+		return result, err
+	}
+	if err := cfg.Dedupe.Validate(); err != nil {
+		return result, err
+	}
+	if cfg.Provenance.Time.IsZero() {
+		cfg.Provenance.Time = time.Now()
+	}
+
+	// Check if the output file exists
+	var existingHeaders []string
+	if _, err := os.Stat(cfg.OutputPath); err == nil {
+		// File exists, load headers
+		existingHeaders, err = records.GetHeaders(cfg.OutputPath)
+		if err != nil {
+			return result, fmt.Errorf("error reading existing file headers: %v", err)
+		}
+	}
+
+	files, err := CollectFiles(cfg.Inputs)
+	if err != nil {
+		return result, err
+	}
+
+	// Update UI with file count
+	result.Files = len(files)
+	utils.LogMessage(fmt.Sprintf("Total files to process: %d", result.Files))
+
+	if result.Files == 0 {
+		return result, fmt.Errorf("no CSV or XLSX files found in the selected input")
+	}
+
+	var classifier *domains.Classifier
+	if cfg.DomainCategory {
+		if classifier, err = domains.Load(cfg.DomainLists); err != nil {
+			return result, err
+		}
+	}
+	var corrector *typos.Corrector
+	if cfg.Typos.Enabled {
+		if corrector, err = typos.Load(cfg.Typos); err != nil {
+			return result, err
+		}
+	}
+
+	var quarantine *quarantineFile
+	if cfg.Validate.Enabled {
+		if quarantine, err = createQuarantine(cfg, files); err != nil {
+			return result, err
+		}
+		defer quarantine.close()
+	}
+
+	err = pipeline.Run(pipeline.Config{
+		Files:      files,
+		Workers:    cfg.Workers,
+		Reader:     records.ReaderOptions{Mapping: cfg.Mapping},
+		Validate:   cfg.Validate,
+		Quarantine: quarantine.write,
+		Typos:      corrector,
+		Domains:    classifier,
+		Normalize:  cfg.Normalize,
+		Dedupe:     cfg.Dedupe,
+	}, func(out pipeline.Output) error {
+		if out.Read == 0 && (out.Validation == nil || out.Validation.Quarantined() == 0) {
+			// Every sheet was skipped, most likely for lack of a Name or Email column
+			if quarantine != nil {
+				quarantine.close()
+				os.Remove(quarantine.path)
+			}
+			return fmt.Errorf("no records were read from the inputs, see the log for the files and sheets skipped")
+		}
+		result.Records = len(out.Records)
+		utils.LogMessage(fmt.Sprintf("Merged %d records into %d using the %s strategy", out.Read, len(out.Records), cfg.Dedupe.Strategy))
+		if cfg.DomainCategory {
+			logCategories(out.Records)
+		}
+		if out.Validation != nil {
+			utils.LogMessage(out.Validation.Summary())
+			if err := quarantine.close(); err != nil {
+				return err
+			}
+			result.Quarantined = quarantine.count
+			utils.LogMessage(fmt.Sprintf("Quarantined %d records with unusable emails to %s", quarantine.count, quarantine.path))
+		}
+		if corrector != nil {
+			if err := writeCorrections(cfg, files, corrector, &result); err != nil {
+				return err
+			}
+		}
+		if out.Fuzzy != nil {
+			if err := writeDuplicates(cfg, out.Fuzzy, &result); err != nil {
+				return err
+			}
+		}
+		return writeOutput(cfg, existingHeaders, out, &result)
+	})
+	return result, err
+}
+
+// logCategories logs how many records fall in each domain category
+func logCategories(list []records.Record) {
+	counts := make(map[string]int)
+	for _, record := range list {
+		counts[record.OthersMap[domains.Column]]++
+	}
+	parts := make([]string, len(domains.Categories))
+	for i, category := range domains.Categories {
+		parts[i] = fmt.Sprintf("%d %s", counts[category], category)
+	}
+	utils.LogMessage("Domain categories: " + strings.Join(parts, ", "))
+}
+
+// quarantineFile streams the records set aside by the validator to a file next to the output
+type quarantineFile struct {
+	path   string
+	writer *validate.QuarantineWriter
+	count  int
+	err    error // First write error, reported when the file is closed
+}
+
+// createQuarantine creates the quarantine file. Records are quarantined while
+// the inputs are read, so its columns come from the header rows of every input
+// sheet, read up front.
+func createQuarantine(cfg Config, files []string) (*quarantineFile, error) {
+	var sources []*records.Columns
+	for _, file := range files {
+		sheets, err := records.GetSheetHeaders(file, records.HeaderOptions{Mapping: cfg.Mapping})
+		if err != nil {
+			// The error is logged when the file is read
+			continue
+		}
+		for _, sheet := range sheets {
+			// Sheets without a Name or Email column are skipped by the reader too
+			if cols := cfg.Mapping.ResolveColumns(records.SheetKey(file, sheet.Sheet), sheet.Headers); cols.Name != -1 && cols.Email != -1 {
+				sources = append(sources, &cols)
+			}
+		}
+	}
+
+	path := validate.QuarantinePath(cfg.OutputPath)
+	schema := records.UnifiedSchema(sources, cfg.Schema)
+	writer, err := validate.CreateQuarantineWriter(path, schema.Headers, cfg.Output)
+	if err != nil {
+		return nil, fmt.Errorf("error writing quarantine file: %v", err)
+	}
+	return &quarantineFile{path: path, writer: writer}, nil
+}
+
+// write adds a record to the file; a nil file drops it
+func (q *quarantineFile) write(record validate.Quarantined) {
+	if q == nil {
+		return
+	}
+	if q.err == nil {
+		q.err = q.writer.Write(record)
+	}
+	q.count++
+}
+
+// close finishes the file, returning the first error met writing it. Closing
+// it again does nothing.
+func (q *quarantineFile) close() error {
+	if q.writer == nil {
+		return nil
+	}
+	err := q.writer.Close()
+	q.writer = nil
+	if q.err != nil {
+		err = q.err
+	}
+	if err != nil {
+		return fmt.Errorf("error writing quarantine file: %v", err)
+	}
+	return nil
+}
+
+// writeCorrections writes the corrections report next to the output file
+func writeCorrections(cfg Config, files []string, corrector *typos.Corrector, result *Result) error {
+	utils.LogMessage(corrector.Summary())
+	corrections := corrector.Corrections(files)
+	result.Corrections = len(corrections)
+
+	path := typos.ReportPath(cfg.OutputPath)
+	if err := typos.WriteReport(path, corrections, cfg.Output); err != nil {
+		return fmt.Errorf("error writing corrections report: %v", err)
+	}
+	utils.LogMessage(fmt.Sprintf("Wrote %d domain corrections to %s", len(corrections), path))
+	return nil
+}
+
+// writeDuplicates writes the fuzzy matching decisions next to the output file
+func writeDuplicates(cfg Config, fuzzy *dedupe.FuzzyResult, result *Result) error {
+	result.Clusters = fuzzy.Clusters
+	if cfg.Dedupe.Fuzzy.AutoMerge {
+		utils.LogMessage(fmt.Sprintf("Fuzzy matching merged %d clusters of similar records", fuzzy.Clusters))
+	} else {
+		utils.LogMessage(fmt.Sprintf("Fuzzy matching found %d clusters of similar records, numbered in the %q column for review", fuzzy.Clusters, dedupe.ClusterColumn))
+	}
+	result.Skipped = len(fuzzy.Skipped)
+	for _, block := range fuzzy.Skipped {
+		utils.LogMessage(fmt.Sprintf("Fuzzy matching skipped %s: its %d records are too many to compare pair by pair", block, block.Records))
+	}
+
+	path := dedupe.ReportPath(cfg.OutputPath)
+	if err := dedupe.WriteReport(path, fuzzy.Decisions, cfg.Output); err != nil {
+		return fmt.Errorf("error writing duplicates report: %v", err)
+	}
+	utils.LogMessage(fmt.Sprintf("Wrote %d fuzzy match decisions to %s", len(fuzzy.Decisions), path))
+	return nil
+}
+
+// writeOutput appends to the existing output file if its headers match, or
+// writes a new file laid out under the unified schema of all sources
+func writeOutput(cfg Config, existingHeaders []string, out pipeline.Output, result *Result) error {
+	if cfg.Provenance.Enabled() {
+		for _, record := range out.Records {
+			cfg.Provenance.Stamp(record)
+		}
+	}
+	if len(existingHeaders) > 0 && records.ValidateHeaders(existingHeaders) {
+		if err := records.AppendRecords(cfg.OutputPath, existingHeaders, out.Records); err != nil {
+			return fmt.Errorf("error appending to output file: %v", err)
+		}
+		result.Appended = true
+		utils.LogMessage(fmt.Sprintf("Records appended to existing file: %s", cfg.OutputPath))
+		return nil
+	}
+
+	if len(existingHeaders) > 0 {
+		utils.LogMessage("Existing file headers do not match requirements, creating a new file.")
+	}
+	// Added columns follow the input columns unless ordered otherwise
+	var added []string
+	if cfg.Dedupe.Fuzzy.Enabled && !cfg.Dedupe.Fuzzy.AutoMerge {
+		added = append(added, dedupe.ClusterColumn)
+	}
+	if cfg.Typos.Enabled && !cfg.Typos.AutoCorrect {
+		added = append(added, typos.Column)
+	}
+	if cfg.DomainCategory {
+		added = append(added, domains.Column)
+	}
+	added = append(added, cfg.Provenance.Headers()...)
+	sources := out.Sources
+	if len(added) > 0 {
+		sources = append(sources, &records.Columns{Headers: added, Name: -1, Email: -1, OrgName: -1})
+	}
+	schema := records.UnifiedSchema(sources, cfg.Schema)
+	if err := records.WriteRecords(cfg.OutputPath, schema, out.Records, cfg.Output); err != nil {
+		return fmt.Errorf("error writing output file: %v", err)
+	}
+	utils.LogMessage(fmt.Sprintf("Processing completed, duplicates removed! Output file saved to %s", cfg.OutputPath))
+	return nil
+}
+
+// CollectFiles expands the given files and folders into the list of CSV/XLSX files to process
+func CollectFiles(inputs []string) ([]string, error) {
+	var files []string
+	for _, inputPath := range inputs {
+		fileInfo, err := os.Stat(inputPath)
+		if err != nil {
+			return nil, fmt.Errorf("error accessing path: %v", err)
+		}
+
+		if !fileInfo.IsDir() {
+			// Single file selected
+			if !isSupportedFile(inputPath) {
+				return nil, fmt.Errorf("unsupported file type selected: %s", inputPath)
+			}
+			files = append(files, inputPath)
+			continue
+		}
+
+		// Walk through the folder and process each file
+		err = filepath.Walk(inputPath, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				utils.LogMessage(fmt.Sprintf("Error accessing file: %s - %v", path, err))
+				return nil // Continue to the next file
+			}
+			if info.IsDir() {
+				return nil
+			}
+			if isSupportedFile(path) {
+				files = append(files, path)
+			} else {
+				// Log and skip non-CSV and non-XLSX files
+				utils.LogMessage(fmt.Sprintf("Skipping unsupported file type: %s", path))
+			}
+			return nil
+		})
+		if err != nil {
+			utils.LogMessage(fmt.Sprintf("Error walking the directory: %v", err))
+		}
+	}
+	return files, nil
+}
+
+// isSupportedFile checks if the file is either a CSV or XLSX file
+func isSupportedFile(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".csv" || ext == ".xlsx"
+}
@@ -0,0 +1,206 @@
+package compare
+
+import (
+	"fmt"
+	"strings"
+
+	"website-copier/cmd/normalize"
+	"website-copier/cmd/records"
+	"website-copier/cmd/utils"
+)
+
+// Kinds of change between two snapshots
+const (
+	Added   = "added"
+	Removed = "removed"
+	Changed = "changed"
+)
+
+// FieldChange is one field that differs between the old and new record
+type FieldChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// Change is a record found in both snapshots with different fields
+type Change struct {
+	Key    string // Normalized email
+	Old    records.Record
+	New    records.Record
+	Fields []FieldChange
+}
+
+// Changelog describes the field changes on one line
+func (c Change) Changelog() string {
+	parts := make([]string, len(c.Fields))
+	for i, f := range c.Fields {
+		parts[i] = fmt.Sprintf("%s: %q -> %q", f.Field, f.Old, f.New)
+	}
+	return strings.Join(parts, "; ")
+}
+
+// Diff is the difference between an old and a new snapshot
+type Diff struct {
+	Schema    records.Columns  // Fields of both snapshots, as laid out in the output
+	Added     []records.Record // In new snapshot order
+	Removed   []records.Record // In old snapshot order
+	Changed   []Change         // In new snapshot order
+	Unchanged int
+	// Columns found in one snapshot only, which are not compared
+	OldOnly, NewOnly []string
+}
+
+// snapshot indexes the records of one export by normalized email
+type snapshot struct {
+	keys       []string
+	byKey      map[string]records.Record
+	duplicates int
+	blank      int
+}
+
+func newSnapshot(list []records.Record, normalizer *normalize.Normalizer) snapshot {
+	s := snapshot{byKey: make(map[string]records.Record)}
+	for _, record := range list {
+		key := normalizer.Normalize(record.Email)
+		if key == "" {
+			s.blank++
+			continue
+		}
+		// The first record of an email stands for it, as in the combined files
+		if _, ok := s.byKey[key]; ok {
+			s.duplicates++
+			continue
+		}
+		s.keys = append(s.keys, key)
+		s.byKey[key] = record
+	}
+	return s
+}
+
+func (s snapshot) logSkipped(name string) {
+	if s.duplicates > 0 || s.blank > 0 {
+		utils.LogMessage(fmt.Sprintf("Skipped %d repeated emails (the first record of each is compared) and %d records without an email in the %s file", s.duplicates, s.blank, name))
+	}
+}
+
+// Compare keys both snapshots by normalized email and sorts every email into
+// added, removed, changed or unchanged. Name, OrgName and every other column
+// found in both snapshots are compared, so that a column added to or dropped
+// from the export does not mark every record as changed.
+func Compare(oldRecords, newRecords []records.Record, opts normalize.Options) Diff {
+	normalizer := normalize.New(opts)
+	old := newSnapshot(oldRecords, normalizer)
+	cur := newSnapshot(newRecords, normalizer)
+	old.logSkipped("old")
+	cur.logSkipped("new")
+
+	var diff Diff
+	oldSources, newSources := sources(oldRecords), sources(newRecords)
+	diff.Schema = records.UnifiedSchema(append(oldSources, newSources...), records.SchemaOptions{})
+	oldColumns, newColumns := extraColumns(oldSources), extraColumns(newSources)
+	compared := make(map[int]bool)
+	for i, header := range diff.Schema.Headers {
+		switch {
+		case i == diff.Schema.Name || i == diff.Schema.OrgName:
+			compared[i] = true
+		case i == diff.Schema.Email:
+		case oldColumns[header] && newColumns[header]:
+			compared[i] = true
+		case oldColumns[header]:
+			diff.OldOnly = append(diff.OldOnly, header)
+		case newColumns[header]:
+			diff.NewOnly = append(diff.NewOnly, header)
+		}
+	}
+	if len(diff.OldOnly) > 0 {
+		utils.LogMessage(fmt.Sprintf("Columns only in the old file, not compared: %s", strings.Join(diff.OldOnly, ", ")))
+	}
+	if len(diff.NewOnly) > 0 {
+		utils.LogMessage(fmt.Sprintf("Columns only in the new file, not compared: %s", strings.Join(diff.NewOnly, ", ")))
+	}
+
+	for _, key := range cur.keys {
+		record := cur.byKey[key]
+		before, ok := old.byKey[key]
+		if !ok {
+			diff.Added = append(diff.Added, record)
+			continue
+		}
+		fields := diff.compareFields(before, record, compared)
+		if len(fields) == 0 {
+			diff.Unchanged++
+			continue
+		}
+		diff.Changed = append(diff.Changed, Change{Key: key, Old: before, New: record, Fields: fields})
+	}
+	for _, key := range old.keys {
+		if _, ok := cur.byKey[key]; !ok {
+			diff.Removed = append(diff.Removed, old.byKey[key])
+		}
+	}
+	return diff
+}
+
+// compareFields lists the compared schema fields that differ, in schema order
+func (d *Diff) compareFields(old, new records.Record, compared map[int]bool) []FieldChange {
+	var fields []FieldChange
+	for i, header := range d.Schema.Headers {
+		if !compared[i] {
+			continue
+		}
+		before, after := d.field(old, i), d.field(new, i)
+		if strings.TrimSpace(before) != strings.TrimSpace(after) {
+			fields = append(fields, FieldChange{Field: header, Old: before, New: after})
+		}
+	}
+	return fields
+}
+
+// field returns the value of a schema column of a record
+func (d *Diff) field(record records.Record, index int) string {
+	switch index {
+	case d.Schema.Name:
+		return record.Name
+	case d.Schema.OrgName:
+		return record.OrgName
+	case d.Schema.Email:
+		return record.Email
+	}
+	return record.OthersMap[d.Schema.Headers[index]]
+}
+
+// Fields returns every schema column of a record by header
+func (d *Diff) Fields(record records.Record) map[string]string {
+	fields := make(map[string]string, len(d.Schema.Headers))
+	for i, header := range d.Schema.Headers {
+		fields[header] = d.field(record, i)
+	}
+	return fields
+}
+
+// sources returns the distinct header mappings of the records
+func sources(list []records.Record) []*records.Columns {
+	var columns []*records.Columns
+	seen := make(map[*records.Columns]bool)
+	for _, record := range list {
+		if record.Columns != nil && !seen[record.Columns] {
+			seen[record.Columns] = true
+			columns = append(columns, record.Columns)
+		}
+	}
+	return columns
+}
+
+// extraColumns returns the headers of the sources besides the Name, Email and OrgName ones
+func extraColumns(sources []*records.Columns) map[string]bool {
+	columns := make(map[string]bool)
+	for _, source := range sources {
+		for i, header := range source.Headers {
+			if i != source.Name && i != source.Email && i != source.OrgName {
+				columns[header] = true
+			}
+		}
+	}
+	return columns
+}
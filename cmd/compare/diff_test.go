@@ -0,0 +1,125 @@
+package compare
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"website-copier/cmd/normalize"
+	"website-copier/cmd/records"
+)
+
+// loadSnapshot writes an export into a temporary folder and reads it back
+func loadSnapshot(t *testing.T, name, content string) []records.Record {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	list, _, err := records.LoadRecords(path, records.ReaderOptions{EmailOnly: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return list
+}
+
+func emails(list []records.Record) []string {
+	var out []string
+	for _, r := range list {
+		out = append(out, r.Email)
+	}
+	return out
+}
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		name      string
+		old, new  string
+		opts      normalize.Options
+		added     []string
+		removed   []string
+		changed   map[string][]FieldChange // By normalized email
+		unchanged int
+		oldOnly   []string
+		newOnly   []string
+	}{
+		{
+			name:    "added, removed and changed",
+			old:     "Name,Email,Company\nAnn,ann@example.com,Acme\nBob,bob@example.com,Acme\nCat,cat@example.com,Globex\n",
+			new:     "Name,Email,Company\nAnn,ann@example.com,Acme\nCat,cat@example.com,Initech\nDan,dan@example.com,Acme\n",
+			opts:    normalize.DefaultOptions(),
+			added:   []string{"dan@example.com"},
+			removed: []string{"bob@example.com"},
+			changed: map[string][]FieldChange{
+				"cat@example.com": {{Field: "OrgName", Old: "Globex", New: "Initech"}},
+			},
+			unchanged: 1,
+		},
+		{
+			name:      "emails matched after normalization",
+			old:       "Name,Email\nAnn,Ann@Example.com\n",
+			new:       "Name,Email\nAnn, ann@example.com \n",
+			opts:      normalize.DefaultOptions(),
+			unchanged: 1,
+		},
+		{
+			name:    "emails compared as written",
+			old:     "Name,Email\nAnn,Ann@Example.com\n",
+			new:     "Name,Email\nAnn,ann@example.com\n",
+			added:   []string{"ann@example.com"},
+			removed: []string{"Ann@Example.com"},
+		},
+		{
+			name:      "surrounding spaces are not a change",
+			old:       "Name,Email,Phone\nAnn,ann@example.com,555\n",
+			new:       "Name,Email,Phone\nAnn ,ann@example.com, 555\n",
+			opts:      normalize.DefaultOptions(),
+			unchanged: 1,
+		},
+		{
+			name:      "columns in one snapshot only",
+			old:       "Name,Email,Fax\nAnn,ann@example.com,123\n",
+			new:       "Email,Name,Phone\nann@example.com,Ann,555\n",
+			opts:      normalize.DefaultOptions(),
+			unchanged: 1,
+			oldOnly:   []string{"Fax"},
+			newOnly:   []string{"Phone"},
+		},
+		{
+			name: "first record of a repeated email",
+			old:  "Name,Email\nAnn,ann@example.com\nAnnie,ann@example.com\n,\n",
+			new:  "Name,Email\nAnne,ann@example.com\n",
+			opts: normalize.DefaultOptions(),
+			changed: map[string][]FieldChange{
+				"ann@example.com": {{Field: "Name", Old: "Ann", New: "Anne"}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diff := Compare(loadSnapshot(t, "old.csv", tt.old), loadSnapshot(t, "new.csv", tt.new), tt.opts)
+			if got := emails(diff.Added); !reflect.DeepEqual(got, tt.added) {
+				t.Errorf("added = %q, want %q", got, tt.added)
+			}
+			if got := emails(diff.Removed); !reflect.DeepEqual(got, tt.removed) {
+				t.Errorf("removed = %q, want %q", got, tt.removed)
+			}
+			changed := make(map[string][]FieldChange)
+			for _, c := range diff.Changed {
+				changed[c.Key] = c.Fields
+			}
+			if len(changed) > 0 || len(tt.changed) > 0 {
+				if !reflect.DeepEqual(changed, tt.changed) {
+					t.Errorf("changed = %v, want %v", changed, tt.changed)
+				}
+			}
+			if diff.Unchanged != tt.unchanged {
+				t.Errorf("unchanged = %d, want %d", diff.Unchanged, tt.unchanged)
+			}
+			if !reflect.DeepEqual(diff.OldOnly, tt.oldOnly) || !reflect.DeepEqual(diff.NewOnly, tt.newOnly) {
+				t.Errorf("columns only in old, new = %q, %q, want %q, %q", diff.OldOnly, diff.NewOnly, tt.oldOnly, tt.newOnly)
+			}
+		})
+	}
+}
@@ -0,0 +1,218 @@
+package compare
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"website-copier/cmd/normalize"
+	"website-copier/cmd/records"
+	"website-copier/cmd/utils"
+)
+
+// Columns added around the record columns of the CSV and XLSX output
+const (
+	ColumnChange  = "Change"
+	ColumnChanges = "Changes"
+)
+
+// Config describes a compare job independently of the GUI
+type Config struct {
+	OldPath        string // Earlier export, CSV, TSV or XLSX
+	NewPath        string // Later export, CSV, TSV or XLSX
+	OutputFilePath string // Destination CSV, XLSX or JSON file
+
+	Normalize normalize.Options     // Rules applied to the emails of both exports before keying
+	Mapping   records.ColumnMapping // Header overrides for both exports
+	// Output controls the sheet splitting of XLSX output
+	Output records.WriterOptions
+}
+
+// Result summarises a finished compare job
+type Result struct {
+	Old       int `json:"old_records"`
+	New       int `json:"new_records"`
+	Added     int `json:"added"`
+	Removed   int `json:"removed"`
+	Changed   int `json:"changed"`
+	Unchanged int `json:"unchanged"`
+}
+
+// Summary describes the result for the user
+func (r Result) Summary() string {
+	return fmt.Sprintf("%d added, %d removed, %d changed, %d unchanged (%d old records, %d new records).",
+		r.Added, r.Removed, r.Changed, r.Unchanged, r.Old, r.New)
+}
+
+// CheckOutputFile reports an error if the output file type is not supported
+func CheckOutputFile(filename string) error {
+	if strings.ToLower(filepath.Ext(filename)) == ".json" {
+		return nil
+	}
+	if err := records.CheckOutputFile(filename); err != nil {
+		return fmt.Errorf("output file must have a .csv, .xlsx or .json extension: %s", filename)
+	}
+	return nil
+}
+
+// Run loads both exports, compares them and writes the differences
+func Run(cfg Config) (Result, error) {
+	if cfg.OldPath == "" || cfg.NewPath == "" {
+		return Result{}, fmt.Errorf("an old and a new file are needed")
+	}
+	if cfg.OutputFilePath == "" {
+		return Result{}, fmt.Errorf("no output file given")
+	}
+	if err := CheckOutputFile(cfg.OutputFilePath); err != nil {
+		return Result{}, err
+	}
+
+	opts := records.ReaderOptions{EmailOnly: true, Mapping: cfg.Mapping}
+	oldRecords, _, err := records.LoadRecords(cfg.OldPath, opts)
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to load old file: %v", err)
+	}
+	utils.LogMessage(fmt.Sprintf("Loaded %d records from old file: %s", len(oldRecords), cfg.OldPath))
+	newRecords, _, err := records.LoadRecords(cfg.NewPath, opts)
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to load new file: %v", err)
+	}
+	utils.LogMessage(fmt.Sprintf("Loaded %d records from new file: %s", len(newRecords), cfg.NewPath))
+
+	diff := Compare(oldRecords, newRecords, cfg.Normalize)
+	result := Result{
+		Old:       len(oldRecords),
+		New:       len(newRecords),
+		Added:     len(diff.Added),
+		Removed:   len(diff.Removed),
+		Changed:   len(diff.Changed),
+		Unchanged: diff.Unchanged,
+	}
+
+	if strings.ToLower(filepath.Ext(cfg.OutputFilePath)) == ".json" {
+		err = writeJSON(cfg, diff, result)
+	} else {
+		err = writeTable(cfg.OutputFilePath, diff, cfg.Output)
+	}
+	if err != nil {
+		return result, fmt.Errorf("failed to write output file: %v", err)
+	}
+
+	utils.LogMessage(result.Summary())
+	utils.LogMessage(fmt.Sprintf("Wrote the differences to output file: %s", cfg.OutputFilePath))
+	return result, nil
+}
+
+// writeTable writes one row per added, removed or changed record: the kind of
+// change, the record (its old values when removed, its new values otherwise)
+// and the field changes
+func writeTable(filename string, diff Diff, opts records.WriterOptions) error {
+	schema := records.Columns{
+		Headers: append(append([]string{ColumnChange}, diff.Schema.Headers...), ColumnChanges),
+		Name:    shift(diff.Schema.Name),
+		Email:   shift(diff.Schema.Email),
+		OrgName: shift(diff.Schema.OrgName),
+	}
+	writer, err := records.CreateSchemaWriter(filename, schema, opts)
+	if err != nil {
+		return err
+	}
+
+	write := func(change string, record records.Record, changelog string) error {
+		out := record
+		out.OthersMap = make(map[string]string, len(record.OthersMap)+2)
+		for k, v := range record.OthersMap {
+			out.OthersMap[k] = v
+		}
+		out.OthersMap[ColumnChange] = change
+		out.OthersMap[ColumnChanges] = changelog
+		return writer.Write(out)
+	}
+	for _, record := range diff.Added {
+		if err := write(Added, record, ""); err != nil {
+			writer.Close()
+			return err
+		}
+	}
+	for _, record := range diff.Removed {
+		if err := write(Removed, record, ""); err != nil {
+			writer.Close()
+			return err
+		}
+	}
+	for _, change := range diff.Changed {
+		if err := write(Changed, change.New, change.Changelog()); err != nil {
+			writer.Close()
+			return err
+		}
+	}
+	return writer.Close()
+}
+
+// shift moves a column index past the leading Change column
+func shift(index int) int {
+	if index == -1 {
+		return -1
+	}
+	return index + 1
+}
+
+// jsonReport is the layout of the JSON output
+type jsonReport struct {
+	OldFile string              `json:"old_file"`
+	NewFile string              `json:"new_file"`
+	Summary Result              `json:"summary"`
+	OldOnly []string            `json:"old_only_columns"`
+	NewOnly []string            `json:"new_only_columns"`
+	Added   []map[string]string `json:"added"`
+	Removed []map[string]string `json:"removed"`
+	Changed []jsonChange        `json:"changed"`
+}
+
+type jsonChange struct {
+	Email   string            `json:"email"`
+	Changes []FieldChange     `json:"changes"`
+	Old     map[string]string `json:"old"`
+	New     map[string]string `json:"new"`
+}
+
+func writeJSON(cfg Config, diff Diff, result Result) error {
+	report := jsonReport{
+		OldFile: cfg.OldPath,
+		NewFile: cfg.NewPath,
+		Summary: result,
+		OldOnly: append([]string{}, diff.OldOnly...),
+		NewOnly: append([]string{}, diff.NewOnly...),
+		Added:   make([]map[string]string, 0, len(diff.Added)),
+		Removed: make([]map[string]string, 0, len(diff.Removed)),
+		Changed: make([]jsonChange, 0, len(diff.Changed)),
+	}
+	for _, record := range diff.Added {
+		report.Added = append(report.Added, diff.Fields(record))
+	}
+	for _, record := range diff.Removed {
+		report.Removed = append(report.Removed, diff.Fields(record))
+	}
+	for _, change := range diff.Changed {
+		report.Changed = append(report.Changed, jsonChange{
+			Email:   change.New.Email,
+			Changes: change.Fields,
+			Old:     diff.Fields(change.Old),
+			New:     diff.Fields(change.New),
+		})
+	}
+
+	file, err := os.Create(cfg.OutputFilePath)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
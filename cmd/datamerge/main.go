@@ -0,0 +1,18 @@
+// Command datamerge runs the combine, filter and compare jobs headless. Unlike
+// the DataMerge Pro app it doesn't link the GUI toolkit, so it builds and runs
+// on servers without X11 or OpenGL.
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"website-copier/cmd/cli"
+)
+
+func main() {
+	if err := cli.Run(os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,280 @@
+package dedupe
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"website-copier/cmd/records"
+)
+
+// Strategies deciding which duplicate wins
+const (
+	First    = "first"     // First record in file order
+	Last     = "last"      // Last record in file order
+	NonEmpty = "non-empty" // Record with the most filled-in fields
+	Priority = "priority"  // Record from the highest priority source
+	Newest   = "newest"    // Record with the newest date in DateColumn
+)
+
+// Strategies lists every strategy for the CLI and GUI
+var Strategies = []string{First, Last, NonEmpty, Priority, Newest}
+
+// Options configures how duplicates are merged
+type Options struct {
+	Strategy   string
+	FillBlanks bool     // Fill blank fields of the winner from the other duplicates
+	Priority   []string // Source files for the priority strategy, highest first; paths, base names or glob patterns
+	DateColumn string   // Column compared by the newest strategy
+	Fuzzy      FuzzyOptions
+}
+
+// DefaultOptions keeps the first record and fills its gaps from the others
+func DefaultOptions() Options {
+	return Options{Strategy: First, FillBlanks: true}
+}
+
+// Validate checks that the strategy is known and has what it needs
+func (o Options) Validate() error {
+	if err := o.Fuzzy.Validate(); err != nil {
+		return err
+	}
+	switch o.Strategy {
+	case First, Last, NonEmpty:
+		return nil
+	case Priority:
+		if len(o.Priority) == 0 {
+			return fmt.Errorf("the priority strategy needs a list of source files")
+		}
+		return nil
+	case Newest:
+		if o.DateColumn == "" {
+			return fmt.Errorf("the newest strategy needs a date column")
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown merge strategy: %s", o.Strategy)
+}
+
+// Set collects records by key and merges duplicates once every record is in.
+// The result does not depend on the order records are added in.
+type Set struct {
+	opts     Options
+	fileRank map[string]int
+	groups   map[string][]records.Record
+}
+
+// New creates a set; files gives the file order used to break ties
+func New(opts Options, files []string) *Set {
+	s := &Set{
+		opts:     opts,
+		fileRank: make(map[string]int),
+		groups:   make(map[string][]records.Record),
+	}
+	for i, file := range files {
+		s.fileRank[file] = i
+	}
+	return s
+}
+
+// Add files a record under its key
+func (s *Set) Add(key string, record records.Record) {
+	s.groups[key] = append(s.groups[key], record)
+}
+
+// Len returns the number of distinct keys
+func (s *Set) Len() int {
+	return len(s.groups)
+}
+
+// Resolve merges every group of duplicates into one record. Records are
+// returned in the file order of the first occurrence of each key.
+func (s *Set) Resolve() []records.Record {
+	type merged struct {
+		first  records.Record
+		record records.Record
+	}
+	result := make([]merged, 0, len(s.groups))
+	for _, group := range s.groups {
+		sort.SliceStable(group, func(a, b int) bool {
+			return s.before(group[a], group[b])
+		})
+		result = append(result, merged{first: group[0], record: s.merge(group)})
+	}
+	sort.Slice(result, func(a, b int) bool {
+		return s.before(result[a].first, result[b].first)
+	})
+
+	out := make([]records.Record, len(result))
+	for i, m := range result {
+		out[i] = m.record
+	}
+	return out
+}
+
+// before orders records by file order, then sheet and row. Records split from
+// the same row are ordered by email.
+func (s *Set) before(a, b records.Record) bool {
+	ra, rb := s.fileRank[a.FilePath], s.fileRank[b.FilePath]
+	if ra != rb {
+		return ra < rb
+	}
+	if a.Before(b) || b.Before(a) {
+		return a.Before(b)
+	}
+	if a.FilePath != b.FilePath {
+		return a.FilePath < b.FilePath
+	}
+	return a.Email < b.Email
+}
+
+// merge picks the winner of a group sorted in file order and fills its blanks
+func (s *Set) merge(group []records.Record) records.Record {
+	if len(group) == 1 {
+		return group[0]
+	}
+
+	ranked := append([]records.Record(nil), group...)
+	switch s.opts.Strategy {
+	case Last:
+		for i, j := 0, len(ranked)-1; i < j; i, j = i+1, j-1 {
+			ranked[i], ranked[j] = ranked[j], ranked[i]
+		}
+	case NonEmpty:
+		sort.SliceStable(ranked, func(a, b int) bool {
+			return filledFields(ranked[a]) > filledFields(ranked[b])
+		})
+	case Priority:
+		sort.SliceStable(ranked, func(a, b int) bool {
+			return s.priorityRank(ranked[a].FilePath) < s.priorityRank(ranked[b].FilePath)
+		})
+	case Newest:
+		sort.SliceStable(ranked, func(a, b int) bool {
+			return parseDate(ranked[a].Value(s.opts.DateColumn)).After(parseDate(ranked[b].Value(s.opts.DateColumn)))
+		})
+	}
+
+	winner := copyRecord(ranked[0])
+	if s.opts.FillBlanks || s.opts.Strategy == NonEmpty {
+		for _, other := range ranked[1:] {
+			fillBlanks(&winner, other)
+		}
+	}
+	winner.Sources = s.sources(group)
+	return winner
+}
+
+// sources lists the files and sheets a group was read from, once each and in
+// file order. Records merged before bring the sources they were merged from.
+func (s *Set) sources(group []records.Record) []string {
+	var list []string
+	seen := make(map[string]bool)
+	for _, r := range group {
+		from := r.Sources
+		if len(from) == 0 {
+			from = []string{r.Source()}
+		}
+		for _, source := range from {
+			if !seen[source] {
+				seen[source] = true
+				list = append(list, source)
+			}
+		}
+	}
+	sort.SliceStable(list, func(a, b int) bool {
+		fa, _ := records.SplitSheet(list[a])
+		fb, _ := records.SplitSheet(list[b])
+		return s.fileRank[fa] < s.fileRank[fb]
+	})
+	return list
+}
+
+// priorityRank returns the index of the first priority entry matching the file,
+// or the length of the list for files that are not listed
+func (s *Set) priorityRank(file string) int {
+	for i, pattern := range s.opts.Priority {
+		if pattern == file || pattern == filepath.Base(file) {
+			return i
+		}
+		if ok, _ := filepath.Match(pattern, file); ok {
+			return i
+		}
+		if ok, _ := filepath.Match(pattern, filepath.Base(file)); ok {
+			return i
+		}
+	}
+	return len(s.opts.Priority)
+}
+
+func filledFields(r records.Record) int {
+	n := 0
+	for _, v := range []string{r.Name, r.OrgName, r.Email} {
+		if strings.TrimSpace(v) != "" {
+			n++
+		}
+	}
+	for _, v := range r.OthersMap {
+		if strings.TrimSpace(v) != "" {
+			n++
+		}
+	}
+	return n
+}
+
+// fillBlanks copies the fields that are blank in dst from src
+func fillBlanks(dst *records.Record, src records.Record) {
+	if strings.TrimSpace(dst.Name) == "" {
+		dst.Name = src.Name
+	}
+	if strings.TrimSpace(dst.OrgName) == "" {
+		dst.OrgName = src.OrgName
+	}
+	for k, v := range src.OthersMap {
+		if strings.TrimSpace(dst.OthersMap[k]) == "" && strings.TrimSpace(v) != "" {
+			dst.OthersMap[k] = v
+		}
+	}
+}
+
+// copyRecord copies a record so merging never touches the source maps
+func copyRecord(r records.Record) records.Record {
+	others := make(map[string]string, len(r.OthersMap))
+	for k, v := range r.OthersMap {
+		others[k] = v
+	}
+	r.OthersMap = others
+	return r
+}
+
+// dateLayouts are the date formats understood by the newest strategy
+var dateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"2006/01/02",
+	"02.01.2006",
+	"01/02/2006 15:04:05",
+	"01/02/2006 15:04",
+	"01/02/2006",
+	"1/2/2006",
+	"01-02-06",
+	"2 Jan 2006",
+	"Jan 2, 2006",
+	"January 2, 2006",
+}
+
+// parseDate parses a date in any of the known layouts, returning the zero
+// time (older than everything) when it cannot
+func parseDate(value string) time.Time {
+	value = strings.TrimSpace(value)
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
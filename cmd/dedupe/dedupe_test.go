@@ -0,0 +1,232 @@
+package dedupe
+
+import (
+	"reflect"
+	"testing"
+
+	"website-copier/cmd/records"
+)
+
+var files = []string{"/in/crm.csv", "/in/events.xlsx", "/in/web.csv"}
+
+// contact builds a record of a file and row; fields holds the columns other
+// than Name, with Email and OrgName going to their own fields. The email
+// defaults to ann@example.com.
+func contact(file string, row int, name string, fields map[string]string) records.Record {
+	record := records.Record{Name: name, Email: "ann@example.com", OthersMap: make(map[string]string), FilePath: file, Row: row}
+	for k, v := range fields {
+		switch k {
+		case "Email":
+			record.Email = v
+		case "OrgName":
+			record.OrgName = v
+		default:
+			record.OthersMap[k] = v
+		}
+	}
+	return record
+}
+
+func TestMergeStrategies(t *testing.T) {
+	crm := contact("/in/crm.csv", 2, "Ann", map[string]string{"Phone": "", "City": "Oslo", "Updated": "2024-03-01"})
+	events := contact("/in/events.xlsx", 5, "", map[string]string{"Phone": "555", "City": "Bergen", "Updated": "2025-01-15"})
+	web := contact("/in/web.csv", 9, "Ann Lee", map[string]string{"Phone": "556", "City": " ", "Updated": "not a date"})
+	all := []records.Record{web, crm, events} // Added out of file order on purpose
+
+	tests := []struct {
+		name  string
+		opts  Options
+		group []records.Record
+		want  records.Record
+	}{
+		{
+			name:  "first",
+			opts:  Options{Strategy: First},
+			group: all,
+			want:  crm,
+		},
+		{
+			name:  "first fills blanks",
+			opts:  Options{Strategy: First, FillBlanks: true},
+			group: all,
+			want:  contact("/in/crm.csv", 2, "Ann", map[string]string{"Phone": "555", "City": "Oslo", "Updated": "2024-03-01"}),
+		},
+		{
+			name:  "last",
+			opts:  Options{Strategy: Last},
+			group: all,
+			want:  web,
+		},
+		{
+			name:  "last fills blanks only",
+			opts:  Options{Strategy: Last, FillBlanks: true},
+			group: all,
+			want:  contact("/in/web.csv", 9, "Ann Lee", map[string]string{"Phone": "556", "City": "Bergen", "Updated": "not a date"}),
+		},
+		{
+			name:  "non-empty",
+			opts:  Options{Strategy: NonEmpty},
+			group: []records.Record{crm, events},
+			// Both have four filled fields, so file order breaks the tie; non-empty always fills
+			want: contact("/in/crm.csv", 2, "Ann", map[string]string{"Phone": "555", "City": "Oslo", "Updated": "2024-03-01"}),
+		},
+		{
+			name:  "non-empty most filled",
+			opts:  Options{Strategy: NonEmpty},
+			group: []records.Record{contact("/in/crm.csv", 2, "", nil), events},
+			want:  events,
+		},
+		{
+			name:  "priority",
+			opts:  Options{Strategy: Priority, Priority: []string{"web.csv", "/in/events.xlsx"}},
+			group: all,
+			want:  web,
+		},
+		{
+			name:  "priority glob",
+			opts:  Options{Strategy: Priority, Priority: []string{"*.xlsx"}},
+			group: all,
+			want:  events,
+		},
+		{
+			name:  "priority unlisted files follow in file order",
+			opts:  Options{Strategy: Priority, Priority: []string{"other.csv"}},
+			group: []records.Record{web, events},
+			want:  events,
+		},
+		{
+			name:  "newest",
+			opts:  Options{Strategy: Newest, DateColumn: "Updated"},
+			group: all,
+			want:  events,
+		},
+		{
+			name:  "newest unparsable date loses",
+			opts:  Options{Strategy: Newest, DateColumn: "Updated"},
+			group: []records.Record{web, crm},
+			want:  crm,
+		},
+		{
+			name: "newest tie",
+			opts: Options{Strategy: Newest, DateColumn: "Updated"},
+			group: []records.Record{
+				contact("/in/web.csv", 3, "Web", map[string]string{"Updated": "01/15/2025"}),
+				contact("/in/events.xlsx", 7, "Events", map[string]string{"Updated": "2025-01-15"}),
+			},
+			want: contact("/in/events.xlsx", 7, "Events", map[string]string{"Updated": "2025-01-15"}),
+		},
+		{
+			name: "newest without any date",
+			opts: Options{Strategy: Newest, DateColumn: "Updated"},
+			group: []records.Record{
+				contact("/in/web.csv", 3, "Web", map[string]string{"Updated": "soon"}),
+				contact("/in/crm.csv", 4, "CRM", nil),
+			},
+			want: contact("/in/crm.csv", 4, "CRM", nil),
+		},
+		{
+			name: "same file",
+			opts: Options{Strategy: Last},
+			group: []records.Record{
+				contact("/in/crm.csv", 8, "Later", nil),
+				contact("/in/crm.csv", 3, "Earlier", nil),
+			},
+			want: contact("/in/crm.csv", 8, "Later", nil),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			set := New(tt.opts, files)
+			for _, record := range tt.group {
+				set.Add("ann@example.com", record)
+			}
+			got := set.Resolve()
+			if len(got) != 1 {
+				t.Fatalf("Resolve() returned %d records, want 1", len(got))
+			}
+			got[0].Sources = nil // Checked by TestMergeSources
+			if !reflect.DeepEqual(got[0], tt.want) {
+				t.Errorf("Resolve() = %+v, want %+v", got[0], tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeLeavesSourcesAlone(t *testing.T) {
+	crm := contact("/in/crm.csv", 2, "Ann", map[string]string{"Phone": ""})
+	web := contact("/in/web.csv", 2, "Ann", map[string]string{"Phone": "555"})
+	set := New(Options{Strategy: First, FillBlanks: true}, files)
+	set.Add("ann", crm)
+	set.Add("ann", web)
+	set.Resolve()
+	if crm.OthersMap["Phone"] != "" {
+		t.Errorf("filling blanks changed the source record: %v", crm.OthersMap)
+	}
+}
+
+func TestMergeSources(t *testing.T) {
+	leads := contact("/in/events.xlsx", 4, "Ann", nil)
+	leads.Sheet = "Leads"
+	visits := contact("/in/events.xlsx", 2, "Ann", nil)
+	visits.Sheet, visits.SheetIndex = "Visits", 1
+	// A record merged before, e.g. by exact matching ahead of fuzzy matching
+	merged := contact("/in/web.csv", 5, "Ann", nil)
+	merged.Sources = []string{"/in/web.csv", "/in/crm.csv"}
+
+	set := New(Options{Strategy: First}, files)
+	for _, record := range []records.Record{merged, visits, leads, contact("/in/crm.csv", 3, "Ann", nil)} {
+		set.Add("ann@example.com", record)
+	}
+	got := set.Resolve()
+	want := []string{"/in/crm.csv", "/in/events.xlsx:Leads", "/in/events.xlsx:Visits", "/in/web.csv"}
+	if len(got) != 1 || !reflect.DeepEqual(got[0].Sources, want) {
+		t.Errorf("Resolve() = %+v, want one record with sources %q", got, want)
+	}
+}
+
+func TestResolveOrder(t *testing.T) {
+	add := []records.Record{
+		{Email: "c", FilePath: "/in/web.csv", Row: 2},
+		{Email: "b", FilePath: "/in/crm.csv", Row: 7},
+		{Email: "a", FilePath: "/in/events.xlsx", Row: 2},
+		{Email: "d", FilePath: "/in/crm.csv", Row: 3},
+		{Email: "a", FilePath: "/in/crm.csv", Row: 9},
+	}
+	set := New(DefaultOptions(), files)
+	for _, record := range add {
+		set.Add(record.Email, record)
+	}
+	if set.Len() != 4 {
+		t.Errorf("Len() = %d, want 4", set.Len())
+	}
+
+	var got []string
+	for _, record := range set.Resolve() {
+		got = append(got, record.Email+"@"+record.FilePath)
+	}
+	// a first appears in crm.csv, which comes before events.xlsx
+	want := []string{"d@/in/crm.csv", "b@/in/crm.csv", "a@/in/crm.csv", "c@/in/web.csv"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Resolve() order = %q, want %q", got, want)
+	}
+}
+
+func TestOptionsValidate(t *testing.T) {
+	tests := []struct {
+		opts Options
+		ok   bool
+	}{
+		{DefaultOptions(), true},
+		{Options{Strategy: NonEmpty}, true},
+		{Options{Strategy: Priority}, false},
+		{Options{Strategy: Priority, Priority: []string{"crm.csv"}}, true},
+		{Options{Strategy: Newest}, false},
+		{Options{Strategy: Newest, DateColumn: "Updated"}, true},
+		{Options{Strategy: "oldest"}, false},
+	}
+	for _, tt := range tests {
+		if err := tt.opts.Validate(); (err == nil) != tt.ok {
+			t.Errorf("%+v.Validate() = %v, want ok %v", tt.opts, err, tt.ok)
+		}
+	}
+}
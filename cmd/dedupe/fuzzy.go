@@ -0,0 +1,353 @@
+package dedupe
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"website-copier/cmd/records"
+)
+
+// Keys records are blocked by before their names are compared
+const (
+	BlockDomain = "domain" // Email domain
+	BlockOrg    = "org"    // Organization name
+)
+
+// Blocks lists every blocking key for the CLI and GUI
+var Blocks = []string{BlockDomain, BlockOrg}
+
+// Decisions taken on a pair of records
+const (
+	Merged = "merged" // Above the threshold and merged
+	Review = "review" // Above the threshold and left for review
+	Below  = "below threshold"
+)
+
+// DefaultThreshold is the score from which two records are taken for the same person
+const DefaultThreshold = 0.92
+
+// DefaultMaxBlock is the number of records a block may hold to be compared.
+// Every pair of a block is scored, so larger blocks are skipped.
+const DefaultMaxBlock = 1000
+
+// nearMiss is how far below the threshold pairs are still reported
+const nearMiss = 0.05
+
+// ClusterColumn holds the cluster number of the records found similar but not merged
+const ClusterColumn = "Duplicate Cluster"
+
+// FuzzyOptions configures the matching of records whose emails differ
+type FuzzyOptions struct {
+	Enabled   bool
+	Block     []string // Keys records must share to be compared; default every key
+	Threshold float64  // Score between 0 and 1 from which records are duplicates; 0 means DefaultThreshold
+	AutoMerge bool     // Merge the clusters instead of marking them for review
+	MaxBlock  int      // Records a block may hold to be compared; 0 means DefaultMaxBlock
+}
+
+// Validate checks the blocking keys and the threshold
+func (o FuzzyOptions) Validate() error {
+	if !o.Enabled {
+		return nil
+	}
+	for _, block := range o.Block {
+		if block != BlockDomain && block != BlockOrg {
+			return fmt.Errorf("unknown blocking key: %s (expected %s)", block, strings.Join(Blocks, " or "))
+		}
+	}
+	if o.Threshold < 0 || o.Threshold > 1 {
+		return fmt.Errorf("the fuzzy threshold must be between 0 and 1")
+	}
+	if o.MaxBlock < 0 {
+		return fmt.Errorf("the fuzzy block size must not be negative")
+	}
+	return nil
+}
+
+// Decision is the score of two records sharing a block
+type Decision struct {
+	Cluster   int // 1-based cluster of the pair, 0 when they ended up apart
+	A, B      records.Record
+	NameScore float64
+	OrgScore  float64 // -1 when either organization is blank
+	Score     float64
+	Decision  string
+}
+
+// FuzzyBlock is a group of records compared with each other: those sharing
+// the value of a blocking key whose names start with the same letter
+type FuzzyBlock struct {
+	Key     string // BlockDomain or BlockOrg
+	Value   string // Email domain or organization
+	Initial string
+	Records int
+}
+
+func (b FuzzyBlock) String() string {
+	return fmt.Sprintf("%s %s, names starting with %s", b.Key, b.Value, b.Initial)
+}
+
+// FuzzyResult is the outcome of fuzzy matching
+type FuzzyResult struct {
+	Records   []records.Record
+	Clusters  int
+	Decisions []Decision
+	Skipped   []FuzzyBlock // Blocks holding more records than MaxBlock, left uncompared
+}
+
+// Fuzzy finds the records whose names (and organizations) are similar within
+// a block and links them into clusters. Clusters are merged with the strategy
+// of the set, or marked with their number in ClusterColumn for review.
+// The records are expected in file order, e.g. as returned by Resolve.
+func (s *Set) Fuzzy(list []records.Record, opts FuzzyOptions) FuzzyResult {
+	if opts.Threshold == 0 {
+		opts.Threshold = DefaultThreshold
+	}
+	if opts.MaxBlock == 0 {
+		opts.MaxBlock = DefaultMaxBlock
+	}
+	blocks := opts.Block
+	if len(blocks) == 0 {
+		blocks = Blocks
+	}
+
+	names := make([]string, len(list))
+	orgs := make([]string, len(list))
+	for i, r := range list {
+		names[i] = nameKey(r.Name)
+		orgs[i] = orgKey(r.OrgName)
+	}
+
+	// Group the records by block, only comparing names with the same initial
+	// so large domains like gmail.com stay manageable
+	grouped := make(map[FuzzyBlock][]int)
+	for i, r := range list {
+		if names[i] == "" {
+			continue
+		}
+		initial := string([]rune(names[i])[0])
+		for _, block := range blocks {
+			var key string
+			switch block {
+			case BlockDomain:
+				if at := strings.LastIndex(r.Email, "@"); at != -1 {
+					key = strings.ToLower(strings.TrimSpace(r.Email[at+1:]))
+				}
+			case BlockOrg:
+				key = orgs[i]
+			}
+			if key != "" {
+				id := FuzzyBlock{Key: block, Value: key, Initial: initial}
+				grouped[id] = append(grouped[id], i)
+			}
+		}
+	}
+	keys := make([]FuzzyBlock, 0, len(grouped))
+	for key := range grouped {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(a, b int) bool {
+		if keys[a].Key != keys[b].Key {
+			return keys[a].Key < keys[b].Key
+		}
+		if keys[a].Value != keys[b].Value {
+			return keys[a].Value < keys[b].Value
+		}
+		return keys[a].Initial < keys[b].Initial
+	})
+
+	// Score every pair once and link those above the threshold
+	parent := make([]int, len(list))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	type pair struct{ a, b int }
+	seen := make(map[pair]bool)
+	var pairs []pair
+	var decisions []Decision
+	var skipped []FuzzyBlock
+	for _, key := range keys {
+		members := grouped[key]
+		if len(members) > opts.MaxBlock {
+			key.Records = len(members)
+			skipped = append(skipped, key)
+			continue
+		}
+		for x := 0; x < len(members); x++ {
+			for y := x + 1; y < len(members); y++ {
+				p := pair{members[x], members[y]}
+				if seen[p] {
+					continue
+				}
+				seen[p] = true
+				d := Decision{A: list[p.a], B: list[p.b], OrgScore: -1}
+				d.NameScore = JaroWinkler(names[p.a], names[p.b])
+				d.Score = d.NameScore
+				if orgs[p.a] != "" && orgs[p.b] != "" {
+					d.OrgScore = JaroWinkler(orgs[p.a], orgs[p.b])
+					d.Score = (3*d.NameScore + d.OrgScore) / 4
+				}
+				switch {
+				case d.Score >= opts.Threshold:
+					d.Decision = Review
+					if opts.AutoMerge {
+						d.Decision = Merged
+					}
+					if ra, rb := find(p.a), find(p.b); ra != rb {
+						// The earlier record stays the root so clusters are numbered in file order
+						parent[max(ra, rb)] = min(ra, rb)
+					}
+				case d.Score >= opts.Threshold-nearMiss:
+					d.Decision = Below
+				default:
+					continue
+				}
+				pairs = append(pairs, p)
+				decisions = append(decisions, d)
+			}
+		}
+	}
+
+	// Number the clusters of more than one record in file order
+	size := make(map[int]int)
+	for i := range list {
+		size[find(i)]++
+	}
+	cluster := make(map[int]int)
+	result := FuzzyResult{Skipped: skipped}
+	for i := range list {
+		root := find(i)
+		if size[root] > 1 && cluster[root] == 0 {
+			result.Clusters++
+			cluster[root] = result.Clusters
+		}
+	}
+	for i, p := range pairs {
+		if root := find(p.a); root == find(p.b) {
+			decisions[i].Cluster = cluster[root]
+		}
+	}
+	sort.SliceStable(decisions, func(a, b int) bool {
+		ca, cb := decisions[a].Cluster, decisions[b].Cluster
+		if ca == 0 || cb == 0 {
+			return ca != 0 && cb == 0
+		}
+		return ca < cb
+	})
+	result.Decisions = decisions
+
+	members := make(map[int][]records.Record)
+	for i, r := range list {
+		if root := find(i); cluster[root] != 0 {
+			members[root] = append(members[root], r)
+		}
+	}
+	for i, r := range list {
+		root := find(i)
+		switch {
+		case cluster[root] == 0:
+			result.Records = append(result.Records, r)
+		case opts.AutoMerge:
+			if root == i {
+				result.Records = append(result.Records, s.merge(members[root]))
+			}
+		default:
+			r = copyRecord(r)
+			r.OthersMap[ClusterColumn] = strconv.Itoa(cluster[root])
+			result.Records = append(result.Records, r)
+		}
+	}
+	return result
+}
+
+// nameKey lower-cases a name and sorts its words, so "Smith, John" and
+// "john smith" compare equal
+func nameKey(name string) string {
+	words := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	sort.Strings(words)
+	return strings.Join(words, " ")
+}
+
+// legalSuffixes are dropped from organization names before comparing them
+var legalSuffixes = map[string]bool{
+	"inc": true, "incorporated": true, "llc": true, "ltd": true, "limited": true,
+	"corp": true, "corporation": true, "co": true, "company": true, "plc": true,
+	"gmbh": true, "ag": true, "sa": true, "sarl": true, "bv": true, "the": true,
+}
+
+// orgKey is like nameKey but also drops legal suffixes such as Inc
+func orgKey(org string) string {
+	var kept []string
+	for _, word := range strings.Fields(nameKey(org)) {
+		if !legalSuffixes[word] {
+			kept = append(kept, word)
+		}
+	}
+	return strings.Join(kept, " ")
+}
+
+// JaroWinkler returns the Jaro-Winkler similarity of two strings, between 0
+// (nothing in common) and 1 (equal)
+func JaroWinkler(a, b string) float64 {
+	s, t := []rune(a), []rune(b)
+	if len(s) == 0 && len(t) == 0 {
+		return 1
+	}
+	if len(s) == 0 || len(t) == 0 {
+		return 0
+	}
+
+	window := max(len(s), len(t))/2 - 1
+	if window < 0 {
+		window = 0
+	}
+	matchedS := make([]bool, len(s))
+	matchedT := make([]bool, len(t))
+	matches := 0
+	for i := range s {
+		for j := max(0, i-window); j < min(len(t), i+window+1); j++ {
+			if !matchedT[j] && s[i] == t[j] {
+				matchedS[i], matchedT[j] = true, true
+				matches++
+				break
+			}
+		}
+	}
+	if matches == 0 {
+		return 0
+	}
+	transpositions, j := 0, 0
+	for i := range s {
+		if !matchedS[i] {
+			continue
+		}
+		for !matchedT[j] {
+			j++
+		}
+		if s[i] != t[j] {
+			transpositions++
+		}
+		j++
+	}
+	m := float64(matches)
+	jaro := (m/float64(len(s)) + m/float64(len(t)) + (m-float64(transpositions/2))/m) / 3
+
+	// Boost strings sharing a prefix of up to four characters
+	prefix := 0
+	for prefix < min(4, len(s), len(t)) && s[prefix] == t[prefix] {
+		prefix++
+	}
+	return jaro + float64(prefix)*0.1*(1-jaro)
+}
@@ -0,0 +1,211 @@
+package dedupe
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"website-copier/cmd/records"
+)
+
+func TestJaroWinkler(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want float64
+	}{
+		{"", "", 1},
+		{"abc", "", 0},
+		{"abc", "abc", 1},
+		{"abc", "xyz", 0},
+		{"martha", "marhta", 0.9611},
+		{"dwayne", "duane", 0.84},
+		{"dixon", "dicksonx", 0.8133},
+		{"jon smith", "john smith", 0.9733},
+		{"rené", "rene", 0.8833},
+	}
+	for _, tt := range tests {
+		if got := JaroWinkler(tt.a, tt.b); math.Abs(got-tt.want) > 0.0001 {
+			t.Errorf("JaroWinkler(%q, %q) = %.4f, want %.4f", tt.a, tt.b, got, tt.want)
+		}
+		if got, back := JaroWinkler(tt.a, tt.b), JaroWinkler(tt.b, tt.a); got != back {
+			t.Errorf("JaroWinkler(%q, %q) = %.4f but %.4f the other way", tt.a, tt.b, got, back)
+		}
+	}
+}
+
+func TestKeys(t *testing.T) {
+	tests := []struct {
+		value string
+		name  string
+		org   string
+	}{
+		{"Smith, John", "john smith", "john smith"},
+		{"  JOHN   smith ", "john smith", "john smith"},
+		{"Acme Corp.", "acme corp", "acme"},
+		{"The Acme Company, Inc", "acme company inc the", "acme"},
+		{"", "", ""},
+	}
+	for _, tt := range tests {
+		if got := nameKey(tt.value); got != tt.name {
+			t.Errorf("nameKey(%q) = %q, want %q", tt.value, got, tt.name)
+		}
+		if got := orgKey(tt.value); got != tt.org {
+			t.Errorf("orgKey(%q) = %q, want %q", tt.value, got, tt.org)
+		}
+	}
+}
+
+func TestFuzzyBlocking(t *testing.T) {
+	tests := []struct {
+		name     string
+		people   []records.Record
+		opts     FuzzyOptions
+		clusters []string // Cluster column of each record
+	}{
+		{
+			name: "same domain",
+			people: []records.Record{
+				contact("a.csv", 1, "Jon Smith", map[string]string{"Email": "jon@acme.com"}),
+				contact("a.csv", 2, "John Smith", map[string]string{"Email": "jsmith@acme.com"}),
+				contact("a.csv", 3, "Ann Lee", map[string]string{"Email": "ann@acme.com"}),
+			},
+			clusters: []string{"1", "1", ""},
+		},
+		{
+			name: "different domains without organizations",
+			people: []records.Record{
+				contact("a.csv", 1, "Jon Smith", map[string]string{"Email": "jon@acme.com"}),
+				contact("a.csv", 2, "John Smith", map[string]string{"Email": "jsmith@other.com"}),
+			},
+			clusters: []string{"", ""},
+		},
+		{
+			name: "same organization",
+			people: []records.Record{
+				contact("a.csv", 1, "Jon Smith", map[string]string{"Email": "jon@acme.com", "OrgName": "Acme Inc"}),
+				contact("a.csv", 2, "John Smith", map[string]string{"Email": "js@gmail.com", "OrgName": "ACME"}),
+			},
+			clusters: []string{"1", "1"},
+		},
+		{
+			name: "personal address without an organization",
+			people: []records.Record{
+				contact("a.csv", 1, "Jon Smith", map[string]string{"Email": "jon@acme.com", "OrgName": "Acme Inc"}),
+				contact("a.csv", 2, "John Smith", map[string]string{"Email": "js@gmail.com"}),
+			},
+			clusters: []string{"", ""},
+		},
+		{
+			name: "domain block only",
+			people: []records.Record{
+				contact("a.csv", 1, "Jon Smith", map[string]string{"Email": "jon@acme.com", "OrgName": "Acme Inc"}),
+				contact("a.csv", 2, "John Smith", map[string]string{"Email": "js@gmail.com", "OrgName": "ACME"}),
+			},
+			opts:     FuzzyOptions{Block: []string{BlockDomain}},
+			clusters: []string{"", ""},
+		},
+		{
+			name: "different initials are not compared",
+			people: []records.Record{
+				contact("a.csv", 1, "Kate Hill", map[string]string{"Email": "kate@acme.com"}),
+				contact("a.csv", 2, "Cate Hill", map[string]string{"Email": "cate@acme.com"}),
+			},
+			clusters: []string{"", ""},
+		},
+		{
+			name: "names in another order",
+			people: []records.Record{
+				contact("a.csv", 1, "Smith, John", map[string]string{"Email": "j@acme.com"}),
+				contact("a.csv", 2, "John Smith", map[string]string{"Email": "john@acme.com"}),
+			},
+			clusters: []string{"1", "1"},
+		},
+		{
+			name: "organizations disagree",
+			people: []records.Record{
+				contact("a.csv", 1, "Jon Smith", map[string]string{"Email": "jon@acme.com", "OrgName": "Acme"}),
+				contact("a.csv", 2, "John Smith", map[string]string{"Email": "jsmith@acme.com", "OrgName": "Globex"}),
+			},
+			clusters: []string{"", ""},
+		},
+		{
+			name: "clusters in file order",
+			people: []records.Record{
+				contact("a.csv", 1, "Ann Lee", map[string]string{"Email": "a@x.com"}),
+				contact("a.csv", 2, "Jon Smith", map[string]string{"Email": "jon@y.com"}),
+				contact("a.csv", 3, "Anne Lee", map[string]string{"Email": "anne@x.com"}),
+				contact("a.csv", 4, "John Smith", map[string]string{"Email": "js@y.com"}),
+			},
+			clusters: []string{"1", "2", "1", "2"},
+		},
+		{
+			name: "blank names are left alone",
+			people: []records.Record{
+				contact("a.csv", 1, "", map[string]string{"Email": "a@x.com"}),
+				contact("a.csv", 2, "", map[string]string{"Email": "b@x.com"}),
+			},
+			clusters: []string{"", ""},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.Enabled = true
+			result := New(DefaultOptions(), []string{"a.csv"}).Fuzzy(tt.people, tt.opts)
+			var got []string
+			for _, r := range result.Records {
+				got = append(got, r.OthersMap[ClusterColumn])
+			}
+			if !reflect.DeepEqual(got, tt.clusters) {
+				t.Errorf("clusters = %q, want %q", got, tt.clusters)
+			}
+		})
+	}
+}
+
+func TestFuzzyAutoMerge(t *testing.T) {
+	list := []records.Record{
+		contact("a.csv", 1, "Jon Smith", map[string]string{"Email": "jon@acme.com"}),
+		contact("a.csv", 2, "Ann Lee", map[string]string{"Email": "ann@acme.com"}),
+		contact("a.csv", 3, "John Smith", map[string]string{"Email": "jsmith@acme.com", "OrgName": "Acme"}),
+	}
+	result := New(DefaultOptions(), []string{"a.csv"}).Fuzzy(list, FuzzyOptions{Enabled: true, AutoMerge: true})
+	if result.Clusters != 1 || len(result.Records) != 2 {
+		t.Fatalf("got %d clusters and %d records, want 1 and 2", result.Clusters, len(result.Records))
+	}
+	// The first record wins and its blank organization is filled
+	if got := result.Records[0]; got.Email != "jon@acme.com" || got.OrgName != "Acme" {
+		t.Errorf("merged record = %s <%s> of %q, want jon@acme.com of Acme", got.Name, got.Email, got.OrgName)
+	}
+	if len(result.Decisions) == 0 || result.Decisions[0].Decision != Merged {
+		t.Errorf("decisions = %v, want the pair merged", result.Decisions)
+	}
+}
+
+func TestFuzzyMaxBlock(t *testing.T) {
+	list := []records.Record{
+		contact("a.csv", 1, "Jon Smith", map[string]string{"Email": "jon@gmail.com", "OrgName": "Acme"}),
+		contact("a.csv", 2, "Jane Jones", map[string]string{"Email": "jane@gmail.com"}),
+		contact("a.csv", 3, "John Smith", map[string]string{"Email": "js@gmail.com", "OrgName": "Acme Ltd"}),
+		contact("a.csv", 4, "Ann Lee", map[string]string{"Email": "ann@gmail.com"}),
+	}
+	result := New(DefaultOptions(), []string{"a.csv"}).Fuzzy(list, FuzzyOptions{Enabled: true, MaxBlock: 2})
+
+	// The three names starting with j at gmail.com are too many, but the two at Acme are still compared
+	want := []FuzzyBlock{{Key: BlockDomain, Value: "gmail.com", Initial: "j", Records: 3}}
+	if !reflect.DeepEqual(result.Skipped, want) {
+		t.Errorf("skipped = %v, want %v", result.Skipped, want)
+	}
+	if result.Clusters != 1 || result.Records[0].OthersMap[ClusterColumn] != "1" || result.Records[2].OthersMap[ClusterColumn] != "1" {
+		t.Errorf("got %d clusters in %v, want the Acme pair", result.Clusters, result.Records)
+	}
+	if got := want[0].String(); got != "domain gmail.com, names starting with j" {
+		t.Errorf("String() = %q", got)
+	}
+
+	if result := New(DefaultOptions(), []string{"a.csv"}).Fuzzy(list, FuzzyOptions{Enabled: true}); len(result.Skipped) != 0 {
+		t.Errorf("default block size skipped %v", result.Skipped)
+	}
+	if err := (FuzzyOptions{Enabled: true, MaxBlock: -1}).Validate(); err == nil {
+		t.Errorf("Validate() = nil for a negative block size")
+	}
+}
@@ -0,0 +1,63 @@
+package dedupe
+
+import (
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"website-copier/cmd/records"
+)
+
+// Columns of the fuzzy duplicates report
+var reportColumns = []string{
+	"Cluster", "Decision", "Score", "Name Score", "Org Score",
+	"Email", "Name", "OrgName", "Source File", "Source Row",
+	"Matched Email", "Matched Name", "Matched OrgName", "Matched Source File", "Matched Source Row",
+}
+
+// ReportPath returns the fuzzy duplicates report next to an output file
+func ReportPath(outputFilePath string) string {
+	ext := filepath.Ext(outputFilePath)
+	return strings.TrimSuffix(outputFilePath, ext) + "_duplicates" + ext
+}
+
+// WriteReport writes every pair of records scored by fuzzy matching with the
+// decision taken
+func WriteReport(filename string, decisions []Decision, opts records.WriterOptions) error {
+	writer, err := records.CreateRecordWriter(filename, reportColumns, opts)
+	if err != nil {
+		return err
+	}
+	for _, d := range decisions {
+		row := map[string]string{
+			"Decision":            d.Decision,
+			"Score":               formatScore(d.Score),
+			"Name Score":          formatScore(d.NameScore),
+			"Email":               d.A.Email,
+			"Name":                d.A.Name,
+			"OrgName":             d.A.OrgName,
+			"Source File":         d.A.Source(),
+			"Source Row":          strconv.Itoa(d.A.Row),
+			"Matched Email":       d.B.Email,
+			"Matched Name":        d.B.Name,
+			"Matched OrgName":     d.B.OrgName,
+			"Matched Source File": d.B.Source(),
+			"Matched Source Row":  strconv.Itoa(d.B.Row),
+		}
+		if d.Cluster > 0 {
+			row["Cluster"] = strconv.Itoa(d.Cluster)
+		}
+		if d.OrgScore >= 0 {
+			row["Org Score"] = formatScore(d.OrgScore)
+		}
+		if err := writer.Write(records.Record{OthersMap: row}); err != nil {
+			writer.Close()
+			return err
+		}
+	}
+	return writer.Close()
+}
+
+func formatScore(score float64) string {
+	return strconv.FormatFloat(score, 'f', 3, 64)
+}
@@ -0,0 +1,187 @@
+package domains
+
+import (
+	"bufio"
+	"embed"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// Categories of email domains
+const (
+	Disposable = "disposable" // Temporary mailboxes such as mailinator.com
+	Free       = "free"       // Free webmail such as gmail.com
+	Education  = "education"  // Schools and universities such as .edu and .ac.uk
+	Corporate  = "corporate"  // Any other domain
+	Unknown    = "unknown"    // No domain to classify
+)
+
+// Categories lists every category in the order they are reported
+var Categories = []string{Disposable, Free, Education, Corporate, Unknown}
+
+// listed are the categories backed by a domain list, in the order they are checked
+var listed = []string{Disposable, Free, Education}
+
+// Column is the output column holding the category of each record
+const Column = "Domain Category"
+
+//go:embed lists/*.txt
+var bundled embed.FS
+
+// Classifier tells the category of email domains from local lists, without
+// any network lookups
+type Classifier struct {
+	lists map[string]map[string]bool // category -> domains
+}
+
+// ListsDir is the folder whose <category>.txt files extend the bundled lists
+// for every job, e.g. ~/.config/DataMerge/domains/disposable.txt on Linux
+func ListsDir() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "DataMerge", "domains")
+}
+
+// Load reads the bundled lists, then the lists of ListsDir and of dir when
+// given. A domain listed with a leading ! is taken off a bundled list.
+func Load(dir string) (*Classifier, error) {
+	c := &Classifier{lists: make(map[string]map[string]bool)}
+	for _, category := range listed {
+		c.lists[category] = make(map[string]bool)
+		file, err := bundled.Open("lists/" + category + ".txt")
+		if err != nil {
+			return nil, err
+		}
+		err = c.read(category, file)
+		file.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, d := range []string{ListsDir(), dir} {
+		if d == "" {
+			continue
+		}
+		if err := c.loadDir(d); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// loadDir reads the <category>.txt files found in a folder
+func (c *Classifier) loadDir(dir string) error {
+	for _, category := range listed {
+		path := filepath.Join(dir, category+".txt")
+		file, err := os.Open(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read domain list: %v", err)
+		}
+		err = c.read(category, file)
+		file.Close()
+		if err != nil {
+			return fmt.Errorf("failed to read domain list %s: %v", path, err)
+		}
+	}
+	return nil
+}
+
+func (c *Classifier) read(category string, r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "!") {
+			delete(c.lists[category], Canonical(line[1:]))
+			continue
+		}
+		c.lists[category][Canonical(line)] = true
+	}
+	return scanner.Err()
+}
+
+// Len returns the number of domains listed for a category
+func (c *Classifier) Len(category string) int {
+	return len(c.lists[category])
+}
+
+// Classify returns the category of the domain of an email address
+func (c *Classifier) Classify(email string) string {
+	domain := Domain(email)
+	if domain == "" {
+		return Unknown
+	}
+	return c.ClassifyDomain(domain)
+}
+
+// ClassifyDomain returns the category of a domain. A domain belongs to a list
+// when it or one of its parent domains is listed.
+func (c *Classifier) ClassifyDomain(domain string) string {
+	domain = Canonical(domain)
+	for _, category := range listed {
+		for d := domain; d != ""; {
+			if c.lists[category][d] {
+				return category
+			}
+			dot := strings.Index(d, ".")
+			if dot == -1 {
+				break
+			}
+			d = d[dot+1:]
+		}
+	}
+	if !strings.Contains(domain, ".") {
+		return Unknown
+	}
+	return Corporate
+}
+
+// ParseCategories checks a list of category names
+func ParseCategories(names []string) ([]string, error) {
+	var categories []string
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		known := false
+		for _, category := range Categories {
+			known = known || category == name
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown domain category: %s (expected one of %s)", name, strings.Join(Categories, ", "))
+		}
+		categories = append(categories, name)
+	}
+	return categories, nil
+}
+
+// Domain returns the canonical domain of an email address, or "" when it has none
+func Domain(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at == -1 {
+		return ""
+	}
+	return Canonical(strings.TrimRight(strings.TrimSpace(email[at+1:]), ">"))
+}
+
+// Canonical lower-cases a domain and converts it to its ASCII form
+func Canonical(domain string) string {
+	domain = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(domain)), ".")
+	if ascii, err := idna.Lookup.ToASCII(domain); err == nil {
+		return ascii
+	}
+	return domain
+}
@@ -0,0 +1,124 @@
+package domains
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// load reads the bundled lists, extended by the files given as name to content,
+// with the user config folder pointed away from the real one
+func load(t *testing.T, files map[string]string) *Classifier {
+	t.Helper()
+	config := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", config)
+	t.Setenv("HOME", config)
+
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestClassify(t *testing.T) {
+	c := load(t, nil)
+	tests := []struct {
+		email string
+		want  string
+	}{
+		{"ann@gmail.com", Free},
+		{"ann@mailinator.com", Disposable},
+		{"ann@cs.stanford.edu", Education},
+		{"ann@ox.ac.uk", Education},
+		{"ann@acme.com", Corporate},
+		{"ann@mail.gmail.com", Free},
+		{"Ann@GMAIL.COM.", Free},
+		{" Ann Lee <ann@gmail.com> ", Free},
+		{"ann@notgmail.com", Corporate},
+		{"ann@localhost", Unknown},
+		{"ann@", Unknown},
+		{"ann", Unknown},
+		{"", Unknown},
+	}
+	for _, tt := range tests {
+		if got := c.Classify(tt.email); got != tt.want {
+			t.Errorf("Classify(%q) = %s, want %s", tt.email, got, tt.want)
+		}
+	}
+}
+
+func TestClassifyDomain(t *testing.T) {
+	c := load(t, map[string]string{
+		// mailinator.com is also on the free list, but disposable is checked first
+		"free.txt":      "# Extra providers\nmailinator.com\n\nbücher.example\n",
+		"education.txt": "!ac.uk\nschool.example\n",
+	})
+	tests := []struct {
+		domain string
+		want   string
+	}{
+		{"mailinator.com", Disposable},
+		{"gmail.com", Free},
+		{"GMail.Com.", Free},
+		{"bücher.example", Free},
+		{"xn--bcher-kva.example", Free},
+		{"shop.bücher.example", Free},
+		{"ox.ac.uk", Corporate},
+		{"class.school.example", Education},
+		{"example", Unknown},
+		{"", Unknown},
+	}
+	for _, tt := range tests {
+		if got := c.ClassifyDomain(tt.domain); got != tt.want {
+			t.Errorf("ClassifyDomain(%q) = %s, want %s", tt.domain, got, tt.want)
+		}
+	}
+	if c.Len(Education) == 0 || c.Len(Corporate) != 0 {
+		t.Errorf("Len() = %d education and %d corporate domains, want some and none", c.Len(Education), c.Len(Corporate))
+	}
+}
+
+func TestParseCategories(t *testing.T) {
+	tests := []struct {
+		names []string
+		want  []string
+		ok    bool
+	}{
+		{[]string{"disposable", " Free ", ""}, []string{Disposable, Free}, true},
+		{[]string{"corporate", "unknown"}, []string{Corporate, Unknown}, true},
+		{nil, nil, true},
+		{[]string{"free", "spam"}, nil, false},
+	}
+	for _, tt := range tests {
+		got, err := ParseCategories(tt.names)
+		if (err == nil) != tt.ok || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseCategories(%q) = %q, %v, want %q and ok %v", tt.names, got, err, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestCanonical(t *testing.T) {
+	tests := []struct {
+		domain string
+		want   string
+	}{
+		{"Example.COM", "example.com"},
+		{" example.com. ", "example.com"},
+		{"Bücher.Example", "xn--bcher-kva.example"},
+		{"xn--bcher-kva.example", "xn--bcher-kva.example"},
+		{"under_score.example", "under_score.example"},
+	}
+	for _, tt := range tests {
+		if got := Canonical(tt.domain); got != tt.want {
+			t.Errorf("Canonical(%q) = %q, want %q", tt.domain, got, tt.want)
+		}
+	}
+}
@@ -2,7 +2,7 @@ package droparea
 
 import (
 	"image/color"
-	"website-copier/cmd/utils"
+	"website-copier/cmd/gui"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/canvas"
@@ -77,7 +77,7 @@ func (r *dropAreaRenderer) Objects() []fyne.CanvasObject {
 
 // Implement Tappable interface
 func (d *DropAreaWidget) Tapped(event *fyne.PointEvent) {
-	utils.ShowFileOpenDialog(d.FilePath, d.FileEntry, d.Window)
+	gui.ShowFileOpenDialog(d.FilePath, d.FileEntry, d.Window)
 }
 
 func (d *DropAreaWidget) TappedSecondary(event *fyne.PointEvent) {}
@@ -5,8 +5,8 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"website-copier/cmd/gui"
 	"website-copier/cmd/records"
-	"website-copier/cmd/utils"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/widget"
@@ -104,7 +104,7 @@ func ShowFolderSelectionDialog(
 			return nil
 		})
 		if err != nil {
-			utils.ShowError(fmt.Errorf("Error reading folder: %v", err), nil)
+			gui.ShowError(fmt.Errorf("Error reading folder: %v", err), nil)
 			return
 		}
 		inputPathEntry.SetText(strings.Join(*selectedInputFiles, "\n"))
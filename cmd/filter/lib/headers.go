@@ -3,8 +3,10 @@ package lib
 import (
 	"fmt"
 	"path/filepath"
+	"strconv"
 	"strings"
-	"website-copier/cmd/utils"
+	"website-copier/cmd/gui"
+	"website-copier/cmd/records"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
@@ -27,22 +29,52 @@ func UpdateHeadersDisplay(headersAccordion *widget.Accordion, fileHeaders map[st
 }
 
 func ShowHeaderSelectionModal(win fyne.Window, file string, headers []string, selectedHeaders map[string][]string, headerDisplay *widget.Entry) {
-	// Create checkboxes for each header
-	var checks []*widget.Check
-	headerChecks := make(map[string]*widget.Check)
+	// Previously selected headers come first, in their chosen order, followed by the rest
+	previous, hasPrevious := selectedHeaders[file]
+	var order []string
+	checked := make(map[string]bool)
+	for _, header := range previous {
+		order = append(order, header)
+		checked[header] = true
+	}
 	for _, header := range headers {
-		check := widget.NewCheck(header, nil)
-		check.SetChecked(true) // Default to selected
-		checks = append(checks, check)
-		headerChecks[header] = check
+		if _, ok := checked[header]; !ok {
+			order = append(order, header)
+			checked[header] = !hasPrevious // Default to selected
+		}
+	}
+
+	// Create one row per header with a checkbox and buttons to move it up or down
+	rows := container.NewVBox()
+	var renderRows func()
+	move := func(i, delta int) {
+		j := i + delta
+		if j < 0 || j >= len(order) {
+			return
+		}
+		order[i], order[j] = order[j], order[i]
+		renderRows()
 	}
+	renderRows = func() {
+		rows.Objects = nil
+		for i, header := range order {
+			i, header := i, header
+			check := widget.NewCheck(header, func(value bool) {
+				checked[header] = value
+			})
+			check.SetChecked(checked[header])
+			up := widget.NewButtonWithIcon("", theme.MoveUpIcon(), func() { move(i, -1) })
+			down := widget.NewButtonWithIcon("", theme.MoveDownIcon(), func() { move(i, 1) })
+			rows.Add(container.NewBorder(nil, nil, nil, container.NewHBox(up, down), check))
+		}
+		rows.Refresh()
+	}
+	renderRows()
 
 	// Create the content
 	content := container.NewVBox()
-	content.Add(widget.NewLabelWithStyle(fmt.Sprintf("Select headers to use from %s:", filepath.Base(file)), fyne.TextAlignLeading, fyne.TextStyle{Bold: true}))
-	for _, check := range checks {
-		content.Add(check)
-	}
+	content.Add(widget.NewLabelWithStyle(fmt.Sprintf("Select and order the headers to use from %s:", filepath.Base(file)), fyne.TextAlignLeading, fyne.TextStyle{Bold: true}))
+	content.Add(rows)
 
 	var modal *widget.PopUp // Declare modal here to access it in button handlers
 
@@ -50,10 +82,10 @@ func ShowHeaderSelectionModal(win fyne.Window, file string, headers []string, se
 	buttons := container.NewBorder(nil, nil, nil, nil,
 		container.NewHBox(
 			widget.NewButtonWithIcon("OK", theme.ConfirmIcon(), func() {
-				// Collect selected headers
+				// Collect selected headers in the chosen order
 				var selected []string
-				for header, check := range headerChecks {
-					if check.Checked {
+				for _, header := range order {
+					if checked[header] {
 						selected = append(selected, header)
 					}
 				}
@@ -65,7 +97,7 @@ func ShowHeaderSelectionModal(win fyne.Window, file string, headers []string, se
 				// Store the selected headers
 				selectedHeaders[file] = selected
 				// Update the header display
-				utils.DisplayHeadersInList(headerDisplay, selected)
+				gui.DisplayHeadersInList(headerDisplay, selected)
 				// Close the modal
 				win.Canvas().Overlays().Remove(modal)
 			}),
@@ -76,7 +108,6 @@ func ShowHeaderSelectionModal(win fyne.Window, file string, headers []string, se
 		),
 	)
 	buttons.Resize(fyne.NewSize(buttons.MinSize().Width, 50)) // Make buttons wider
-	buttons.Resize(fyne.NewSize(buttons.MinSize().Width, 50)) // Make buttons wider
 
 	// Create the modal content
 	scrollableContent := container.NewVScroll(content)
@@ -86,3 +117,126 @@ func ShowHeaderSelectionModal(win fyne.Window, file string, headers []string, se
 	modal = widget.NewModalPopUp(modalContent, win.Canvas())
 	modal.Show()
 }
+
+// ShowColumnMappingModal lets the user pin the Name, Email and OrgName columns of a
+// file and the row holding its headers. onHeaderRow is called when the row changes.
+func ShowColumnMappingModal(win fyne.Window, file string, headers []string, mapping *records.ColumnMapping, onHeaderRow func()) {
+	const auto = "(detect automatically)"
+	options := append([]string{auto}, headers...)
+	detected := mapping.ResolveColumns(file, headers)
+
+	form := widget.NewForm()
+	selects := make(map[string]*widget.Select)
+	for _, role := range []string{records.RoleName, records.RoleEmail, records.RoleOrgName} {
+		sel := widget.NewSelect(options, nil)
+		sel.SetSelected(auto)
+		if pinned, ok := mapping.Files[file][role]; ok {
+			sel.SetSelected(pinned)
+		}
+		label := role
+		if header := detected.Header(role); header != "" {
+			label = fmt.Sprintf("%s (detected: %s)", role, header)
+		}
+		form.Append(label, sel)
+		selects[role] = sel
+	}
+	headerRow := widget.NewEntry()
+	headerRow.SetPlaceHolder("detected")
+	current := mapping.FileHeaderRow(file)
+	if current > 0 {
+		headerRow.SetText(strconv.Itoa(current))
+	}
+	form.Append("Header row", headerRow)
+
+	content := container.NewVBox(
+		widget.NewLabelWithStyle(fmt.Sprintf("Map columns of %s:", filepath.Base(file)), fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		form,
+	)
+	for _, warning := range detected.Warnings {
+		content.Add(widget.NewLabel(warning))
+	}
+
+	dialog.ShowCustomConfirm("Column Mapping", "OK", "Cancel", content, func(ok bool) {
+		if !ok {
+			return
+		}
+		row := 0
+		if text := strings.TrimSpace(headerRow.Text); text != "" {
+			var err error
+			row, err = strconv.Atoi(text)
+			if err != nil || row < 1 {
+				gui.ShowError(fmt.Errorf("Invalid header row %q, expected a row number from 1", text), win)
+				return
+			}
+		}
+		delete(mapping.Files, file)
+		for role, sel := range selects {
+			if sel.Selected != auto && sel.Selected != "" {
+				mapping.SetOverride(file, role, sel.Selected)
+			}
+		}
+		if row != current {
+			mapping.SetHeaderRow(file, row)
+			if onHeaderRow != nil {
+				onHeaderRow()
+			}
+		}
+	}, win)
+}
+
+// ShowDatabaseColumnsModal lets the user pick the columns of a database file that
+// hold addresses or rules. The detected columns are checked until a choice is made.
+func ShowDatabaseColumnsModal(win fyne.Window, file string, columns *[]string, mapping records.ColumnMapping, onChange func()) {
+	// The headers are found as when the list is loaded, with the columns picked so far
+	sheets, err := records.GetSheetHeaders(file, records.HeaderOptions{Mapping: mapping, Database: true, Columns: *columns})
+	if err != nil {
+		gui.ShowError(fmt.Errorf("Failed to read headers: %v", err), win)
+		return
+	}
+
+	// Union of the headers of every sheet, in order
+	var headers, detected []string
+	seen := make(map[string]bool)
+	for _, sheet := range sheets {
+		emailColumns := make(map[int]bool)
+		for _, i := range (records.DatabaseOptions{Mapping: mapping}).DatabaseColumns(records.SheetKey(file, sheet.Sheet), sheet.Headers) {
+			emailColumns[i] = true
+		}
+		for i, header := range sheet.Headers {
+			if header == "" || seen[header] {
+				continue
+			}
+			seen[header] = true
+			headers = append(headers, header)
+			if emailColumns[i] {
+				detected = append(detected, header)
+			}
+		}
+	}
+
+	checks := widget.NewCheckGroup(headers, nil)
+	if len(*columns) > 0 {
+		checks.SetSelected(*columns)
+	} else {
+		checks.SetSelected(detected)
+	}
+
+	content := container.NewBorder(
+		widget.NewLabelWithStyle(fmt.Sprintf("Email columns of %s:", filepath.Base(file)), fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		nil, nil, nil,
+		container.NewVScroll(checks),
+	)
+	confirm := dialog.NewCustomConfirm("Database Columns", "OK", "Cancel", content, func(ok bool) {
+		if !ok {
+			return
+		}
+		if len(checks.Selected) == 0 {
+			gui.ShowError(fmt.Errorf("Please select at least one column"), win)
+			return
+		}
+		*columns = append([]string(nil), checks.Selected...)
+		onChange()
+	}, win)
+	confirm.Resize(fyne.NewSize(400, 400))
+	confirm.Show()
+}
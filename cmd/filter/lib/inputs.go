@@ -0,0 +1,80 @@
+package lib
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"website-copier/cmd/records"
+	"website-copier/cmd/utils"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+)
+
+// InputItem is a line of the input list: a file, or one sheet of a workbook
+type InputItem struct {
+	File  string
+	Sheet string // Empty for the file itself
+}
+
+// CreateInputList lists the input files, each workbook of several sheets followed
+// by its sheets. Unchecking a sheet leaves it out through the column mapping, and
+// onToggled is called with its file. The list items are returned to find the
+// selected one.
+func CreateInputList(files *[]string, mapping *records.ColumnMapping, onToggled func(file string)) (*widget.List, func() []InputItem) {
+	sheets := make(map[string][]string) // Sheets of the workbooks listed so far
+	items := func() []InputItem {
+		var list []InputItem
+		for _, file := range *files {
+			list = append(list, InputItem{File: file})
+			names, ok := sheets[file]
+			if !ok {
+				var err error
+				if names, err = records.SheetNames(file); err != nil {
+					utils.LogMessage(fmt.Sprintf("Error listing the sheets of %s: %v", file, err))
+				}
+				sheets[file] = names
+			}
+			if len(names) < 2 {
+				continue
+			}
+			for _, sheet := range names {
+				list = append(list, InputItem{File: file, Sheet: sheet})
+			}
+		}
+		return list
+	}
+
+	fileList := widget.NewList(
+		func() int { return len(items()) },
+		func() fyne.CanvasObject {
+			return container.NewHBox(widget.NewCheck("", nil), widget.NewLabel(""))
+		},
+		func(i widget.ListItemID, o fyne.CanvasObject) {
+			list := items()
+			if i >= len(list) {
+				return
+			}
+			item := list[i]
+			row := o.(*fyne.Container)
+			check, label := row.Objects[0].(*widget.Check), row.Objects[1].(*widget.Label)
+			check.OnChanged = nil
+			if item.Sheet == "" {
+				check.Hide()
+				label.SetText(filepath.Base(item.File))
+				return
+			}
+			check.Show()
+			check.SetChecked(mapping.SheetSelected(item.File, item.Sheet))
+			check.OnChanged = func(selected bool) {
+				mapping.SetSheetSelected(item.File, item.Sheet, selected)
+				if onToggled != nil {
+					onToggled(item.File)
+				}
+			}
+			label.SetText("    Sheet " + item.Sheet)
+		},
+	)
+	return fileList, items
+}
@@ -0,0 +1,242 @@
+package lib
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"website-copier/cmd/records"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+)
+
+// previewPageRows is the number of rows shown per page of a preview
+const previewPageRows = 20
+
+// PreviewPane shows the first rows of an input file the way they would be
+// read: the columns mapped to Name, Email and OrgName, the rows skipped and
+// why, and how full each column is
+type PreviewPane struct {
+	Container fyne.CanvasObject
+
+	summary *widget.Label
+	sheet   *widget.Select
+	page    *widget.Label
+	prev    *widget.Button
+	next    *widget.Button
+	table   *fyne.Container
+
+	sheets  []records.SheetPreview
+	current int // Index of the sheet shown
+	offset  int // First row of the page shown
+}
+
+// NewPreviewPane creates an empty preview pane
+func NewPreviewPane() *PreviewPane {
+	p := &PreviewPane{
+		summary: widget.NewLabel("Select a file to preview its first rows"),
+		page:    widget.NewLabel(""),
+		table:   container.NewStack(),
+	}
+	p.summary.Wrapping = fyne.TextWrapWord
+	p.sheet = widget.NewSelect(nil, func(string) {
+		p.current, p.offset = p.sheet.SelectedIndex(), 0
+		p.render()
+	})
+	p.sheet.Hide()
+	p.prev = widget.NewButton("Previous", func() {
+		p.offset -= previewPageRows
+		p.render()
+	})
+	p.next = widget.NewButton("Next", func() {
+		p.offset += previewPageRows
+		p.render()
+	})
+	p.prev.Disable()
+	p.next.Disable()
+
+	// Give the table room, it has no height of its own
+	space := canvas.NewRectangle(nil)
+	space.SetMinSize(fyne.NewSize(0, 250))
+	p.Container = container.NewBorder(
+		container.NewVBox(p.sheet, p.summary),
+		container.NewHBox(p.prev, p.next, p.page),
+		nil, nil,
+		container.NewStack(space, p.table),
+	)
+	return p
+}
+
+// Show previews a file with a mapping, starting on the given sheet
+func (p *PreviewPane) Show(file, sheet string, mapping records.ColumnMapping) {
+	sheets, err := records.Preview(file, records.ReaderOptions{Mapping: mapping}, records.PreviewRows)
+	if err != nil {
+		p.clear(fmt.Sprintf("Failed to preview %s: %v", filepath.Base(file), err))
+		return
+	}
+	if len(sheets) == 0 {
+		p.clear(fmt.Sprintf("%s has no rows", filepath.Base(file)))
+		return
+	}
+	p.sheets, p.current, p.offset = sheets, 0, 0
+
+	var names []string
+	for i, s := range sheets {
+		names = append(names, s.Sheet)
+		if s.Sheet == sheet {
+			p.current = i
+		}
+	}
+	if len(sheets) > 1 {
+		// Setting the options and selection renders the sheet
+		p.sheet.Options = names
+		p.sheet.Show()
+		p.sheet.SetSelectedIndex(p.current)
+		return
+	}
+	p.sheet.Hide()
+	p.render()
+}
+
+// clear empties the pane, leaving a message
+func (p *PreviewPane) clear(message string) {
+	p.sheets = nil
+	p.sheet.Hide()
+	p.summary.SetText(message)
+	p.page.SetText("")
+	p.prev.Disable()
+	p.next.Disable()
+	p.table.Objects = nil
+	p.table.Refresh()
+}
+
+// render shows a page of the current sheet
+func (p *PreviewPane) render() {
+	if p.current < 0 || p.current >= len(p.sheets) {
+		return
+	}
+	s := p.sheets[p.current]
+	p.summary.SetText(previewSummary(s))
+
+	if p.offset > len(s.Rows)-previewPageRows {
+		p.offset = len(s.Rows) - previewPageRows
+	}
+	if p.offset < 0 {
+		p.offset = 0
+	}
+	end := p.offset + previewPageRows
+	if end > len(s.Rows) {
+		end = len(s.Rows)
+	}
+	if len(s.Rows) == 0 {
+		p.page.SetText("No rows")
+	} else {
+		more := ""
+		if s.More {
+			more = fmt.Sprintf(", only the first %d rows below the headers are previewed", records.PreviewRows)
+		}
+		p.page.SetText(fmt.Sprintf("Rows %d-%d of %d%s", p.offset+1, end, len(s.Rows), more))
+	}
+	if p.offset > 0 {
+		p.prev.Enable()
+	} else {
+		p.prev.Disable()
+	}
+	if end < len(s.Rows) {
+		p.next.Enable()
+	} else {
+		p.next.Disable()
+	}
+
+	// Each header is marked with the role it was mapped to, with the fill rate in the first row
+	width := len(s.Columns.Headers)
+	for _, row := range s.Rows {
+		if len(row.Cells) > width {
+			width = len(row.Cells)
+		}
+	}
+	headers := make([]string, width+2)
+	headers[0], headers[1] = "Row", "Status"
+	fill := make([]string, width+2)
+	fill[1] = "Filled"
+	rates := s.FillRate()
+	for i, header := range s.Columns.Headers {
+		if role := previewRole(s.Columns, i); role != "" {
+			header += " (" + role + ")"
+		}
+		headers[i+2] = header
+		// Without loaded rows there is nothing to rate
+		if s.Loaded() > 0 {
+			fill[i+2] = fmt.Sprintf("%.0f%%", rates[i]*100)
+		}
+	}
+	data := [][]string{fill}
+	for _, row := range s.Rows[p.offset:end] {
+		line := make([]string, width+2)
+		line[0] = strconv.Itoa(row.Line)
+		line[1] = "Read"
+		if row.Skipped != "" {
+			line[1] = "Skipped: " + row.Skipped
+		}
+		copy(line[2:], row.Cells)
+		data = append(data, line)
+	}
+
+	table := CreateTable(headers, data)
+	for col := range headers {
+		longest := len(headers[col])
+		for _, line := range data {
+			if len(line[col]) > longest {
+				longest = len(line[col])
+			}
+		}
+		if longest > 30 {
+			longest = 30
+		}
+		table.SetColumnWidth(col, float32(longest)*8+20)
+	}
+	p.table.Objects = []fyne.CanvasObject{table}
+	p.table.Refresh()
+}
+
+// previewRole returns the role a column of a sheet was mapped to, if any
+func previewRole(columns records.Columns, i int) string {
+	switch i {
+	case columns.Name:
+		return records.RoleName
+	case columns.Email:
+		return records.RoleEmail
+	case columns.OrgName:
+		return records.RoleOrgName
+	}
+	return ""
+}
+
+// previewSummary describes how a sheet would be read
+func previewSummary(s records.SheetPreview) string {
+	var lines []string
+	if s.HeaderRow > 0 {
+		var mapped []string
+		for _, role := range []string{records.RoleName, records.RoleEmail, records.RoleOrgName} {
+			header := s.Columns.Header(role)
+			if header == "" {
+				header = "not found"
+			}
+			mapped = append(mapped, fmt.Sprintf("%s: %s", role, header))
+		}
+		lines = append(lines, fmt.Sprintf("Headers on row %d. %s", s.HeaderRow, strings.Join(mapped, ", ")))
+	}
+	for _, warning := range s.Columns.Warnings {
+		lines = append(lines, "Warning: "+warning)
+	}
+	if s.Skipped != "" {
+		lines = append(lines, "The whole sheet would be skipped: "+s.Skipped)
+	} else if skipped := len(s.Rows) - s.Loaded(); skipped > 0 {
+		lines = append(lines, fmt.Sprintf("%d of the %d rows previewed would be skipped", skipped, len(s.Rows)))
+	}
+	return strings.Join(lines, "\n")
+}
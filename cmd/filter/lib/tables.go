@@ -15,9 +15,10 @@ func CreateTable(headers []string, data [][]string) *widget.Table {
 		},
 		func(i widget.TableCellID, o fyne.CanvasObject) {
 			label := o.(*widget.Label)
+			// Cells are reused, so the style is set for the data rows too
+			label.TextStyle = fyne.TextStyle{Bold: i.Row == 0}
 			if i.Row == 0 {
 				label.SetText(headers[i.Col])
-				label.TextStyle = fyne.TextStyle{Bold: true}
 			} else {
 				label.SetText(data[i.Row-1][i.Col])
 			}
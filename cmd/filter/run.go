@@ -0,0 +1,553 @@
+package filter
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"website-copier/cmd/domains"
+	"website-copier/cmd/normalize"
+	"website-copier/cmd/provenance"
+	"website-copier/cmd/records"
+	"website-copier/cmd/suppress"
+	"website-copier/cmd/utils"
+	"website-copier/cmd/validate"
+)
+
+// Modes deciding which records end up in the output
+const (
+	ModeExclude = "exclude" // Keep the records not found in any list
+	ModeKeep    = "keep"    // Keep only the records found in a list
+	ModeSplit   = "split"   // Write records only in the input, records in both and list entries only in the lists to one file each
+)
+
+// Modes lists every mode for the CLI and GUI
+var Modes = []string{ModeExclude, ModeKeep, ModeSplit}
+
+// Config describes a filter job independently of the GUI
+type Config struct {
+	InputPaths     []string        // Files or folders holding the records to filter
+	Lists          []suppress.List // Labelled suppression lists with the emails to remove
+	OutputFilePath string          // Destination CSV or XLSX file; the split mode derives its files from it
+	Mode           string          // One of Modes; empty means ModeExclude
+	// RejectedFilePath optionally receives every record left out of the output with the
+	// reason it was left out, in the same format as the output file. Not used by the split mode.
+	RejectedFilePath string
+
+	Normalize normalize.Options     // Rules applied to both sides before comparing emails
+	Mapping   records.ColumnMapping // Header overrides for the input and list files
+	Validate  validate.Options      // Email validation; invalid rows go to a quarantine file next to the output
+
+	// Columns written for every input file, in output order. Empty means all of the file's headers.
+	Columns []string
+	// FileColumns overrides Columns for individual input files
+	FileColumns map[string][]string
+	// Output controls the sheet splitting of XLSX output
+	Output records.WriterOptions
+
+	// ExcludeCategories treats emails of these domain categories, e.g. disposable, as if they were listed
+	ExcludeCategories []string
+	DomainLists       string // Folder with extra domain lists; the bundled lists are always used
+
+	// Provenance adds columns telling where each record came from to the output files;
+	// the rejected and quarantine files always name the source file and row
+	Provenance provenance.Options
+}
+
+// CategoryList labels the matches of excluded domain categories
+const CategoryList = "Domain categories"
+
+// Columns added to the rejected records output
+const (
+	ColumnSourceFile   = "Source File"
+	ColumnSourceRow    = "Source Row"
+	ColumnMatchedList  = "Matched List"
+	ColumnMatchedEntry = "Matched Entry"
+	ColumnReason       = "Rejection Reason"
+)
+
+// rejectedColumns follow the output columns in the rejected records output
+var rejectedColumns = []string{ColumnSourceFile, ColumnSourceRow, ColumnMatchedList, ColumnMatchedEntry, ColumnReason}
+
+// Columns of the list entries written by the split mode
+var listEntryColumns = []string{"List", "Entry", "Rule"}
+
+// RejectedPath returns the default rejected records file next to an output file
+func RejectedPath(outputFilePath string) string {
+	return suffixPath(outputFilePath, "_rejected")
+}
+
+// SplitPaths returns the files the split mode writes next to the output file: the records
+// only in the input, the records also found in a list, and the list entries matching no record
+func SplitPaths(outputFilePath string) (onlyInput, inBoth, onlyLists string) {
+	return suffixPath(outputFilePath, "_only_input"), suffixPath(outputFilePath, "_in_both"), suffixPath(outputFilePath, "_only_lists")
+}
+
+func suffixPath(path, suffix string) string {
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + suffix + ext
+}
+
+// Result summarises a finished filter job
+type Result struct {
+	Mode        string
+	Records     int       // Input records read
+	Matched     int       // Records matched by at least one list
+	Unmatched   int       // Records not matched by any list
+	ListOnly    int       // List entries matching no record, counted by the split mode only
+	Quarantined int       // Records set aside by email validation
+	Hits        []ListHit // Records matched per list, in list order
+}
+
+// ListHit counts the records a suppression list matched
+type ListHit struct {
+	List    string
+	Records int
+}
+
+// Run validates the job and filters the input records against the suppression lists
+func Run(cfg Config) (Result, error) {
+	if len(cfg.InputPaths) == 0 {
+		return Result{}, fmt.Errorf("no input files or folders given")
+	}
+	if len(cfg.Lists) == 0 && len(cfg.ExcludeCategories) == 0 {
+		return Result{}, fmt.Errorf("no suppression list or domain category given")
+	}
+	categories, err := domains.ParseCategories(cfg.ExcludeCategories)
+	if err != nil {
+		return Result{}, err
+	}
+	cfg.ExcludeCategories = categories
+	for _, list := range cfg.Lists {
+		if list.Path == "" {
+			return Result{}, fmt.Errorf("no file given for list %s", list.Label)
+		}
+	}
+	if cfg.OutputFilePath == "" {
+		return Result{}, fmt.Errorf("no output file given")
+	}
+	if err := records.CheckOutputFile(cfg.OutputFilePath); err != nil {
+		return Result{}, err
+	}
+	switch cfg.Mode {
+	case "":
+		cfg.Mode = ModeExclude
+	case ModeExclude, ModeKeep:
+	case ModeSplit:
+		if cfg.RejectedFilePath != "" {
+			return Result{}, fmt.Errorf("the split mode writes every record, there is no rejected records file")
+		}
+	default:
+		return Result{}, fmt.Errorf("unknown filter mode: %s", cfg.Mode)
+	}
+	if cfg.Provenance.Time.IsZero() {
+		cfg.Provenance.Time = time.Now()
+	}
+	if cfg.RejectedFilePath != "" {
+		if err := records.CheckOutputFile(cfg.RejectedFilePath); err != nil {
+			return Result{}, err
+		}
+		if filepath.Clean(cfg.RejectedFilePath) == filepath.Clean(cfg.OutputFilePath) {
+			return Result{}, fmt.Errorf("the rejected records file must differ from the output file")
+		}
+	}
+	return filterEmails(cfg)
+}
+
+// outputs holds the files records are written to, by whether they matched a list.
+// A nil writer drops the records.
+type outputs struct {
+	matched, unmatched         records.RecordWriter
+	matchedPath, unmatchedPath string
+	annotateMatched            bool // Write matched records with the rejected columns
+	annotateUnmatched          bool // Write unmatched records with the rejected columns
+	provenance                 provenance.Options
+	paths                      []string
+	writers                    []records.RecordWriter
+	quarantine                 *validate.QuarantineWriter
+	quarantinePath             string
+}
+
+// openOutputs creates the output files of the mode
+func openOutputs(cfg Config, headers []string) (*outputs, error) {
+	o := &outputs{provenance: cfg.Provenance}
+	rejectedHeaders := append(append([]string(nil), headers...), rejectedColumns...)
+	outputHeaders := append(append([]string(nil), headers...), cfg.Provenance.Headers()...)
+	var err error
+	if cfg.Validate.Enabled {
+		o.quarantinePath = validate.QuarantinePath(cfg.OutputFilePath)
+		if o.quarantine, err = validate.CreateQuarantineWriter(o.quarantinePath, headers, cfg.Output); err != nil {
+			return nil, fmt.Errorf("failed to write quarantine file %s: %v", o.quarantinePath, err)
+		}
+	}
+	switch cfg.Mode {
+	case ModeExclude:
+		if o.unmatched, err = o.create(cfg.OutputFilePath, outputHeaders, cfg.Output); err == nil && cfg.RejectedFilePath != "" {
+			o.matched, err = o.create(cfg.RejectedFilePath, rejectedHeaders, cfg.Output)
+			o.annotateMatched = true
+		}
+		o.unmatchedPath, o.matchedPath = cfg.OutputFilePath, cfg.RejectedFilePath
+	case ModeKeep:
+		if o.matched, err = o.create(cfg.OutputFilePath, outputHeaders, cfg.Output); err == nil && cfg.RejectedFilePath != "" {
+			o.unmatched, err = o.create(cfg.RejectedFilePath, rejectedHeaders, cfg.Output)
+			o.annotateUnmatched = true
+		}
+		o.matchedPath, o.unmatchedPath = cfg.OutputFilePath, cfg.RejectedFilePath
+	case ModeSplit:
+		o.unmatchedPath, o.matchedPath, _ = SplitPaths(cfg.OutputFilePath)
+		if o.unmatched, err = o.create(o.unmatchedPath, outputHeaders, cfg.Output); err == nil {
+			o.matched, err = o.create(o.matchedPath, outputHeaders, cfg.Output)
+		}
+	}
+	if err != nil {
+		o.close()
+		return nil, err
+	}
+	return o, nil
+}
+
+func (o *outputs) create(path string, headers []string, opts records.WriterOptions) (records.RecordWriter, error) {
+	writer, err := records.CreateRecordWriter(path, headers, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write output file %s: %v", path, err)
+	}
+	o.paths = append(o.paths, path)
+	o.writers = append(o.writers, writer)
+	return writer, nil
+}
+
+// write files a record by its matches
+func (o *outputs) write(record records.Record, columns []string, matches []suppress.Match) error {
+	writer, path, annotate := o.unmatched, o.unmatchedPath, o.annotateUnmatched
+	if len(matches) > 0 {
+		writer, path, annotate = o.matched, o.matchedPath, o.annotateMatched
+	}
+	if writer == nil {
+		return nil
+	}
+	out := record.Select(columns)
+	if annotate {
+		out = rejectedRecord(record, columns, matches)
+	} else {
+		o.provenance.Stamp(out)
+	}
+	if err := writer.Write(out); err != nil {
+		return fmt.Errorf("failed to write output file %s: %v", path, err)
+	}
+	return nil
+}
+
+// close closes every file, returning the first error
+func (o *outputs) close() error {
+	var first error
+	if o.quarantine != nil {
+		if err := o.quarantine.Close(); err != nil {
+			first = fmt.Errorf("failed to write quarantine file %s: %v", o.quarantinePath, err)
+		}
+		o.quarantine = nil
+	}
+	for i, writer := range o.writers {
+		if err := writer.Close(); err != nil && first == nil {
+			first = fmt.Errorf("failed to write output file %s: %v", o.paths[i], err)
+		}
+	}
+	o.writers = nil
+	return first
+}
+
+// remove deletes every file written
+func (o *outputs) remove() {
+	for _, path := range o.paths {
+		os.Remove(path)
+	}
+	if o.quarantinePath != "" {
+		os.Remove(o.quarantinePath)
+	}
+}
+
+// filterEmails filters emails from input files based on the suppression lists and writes to the output files.
+// Input records are streamed straight to the output so large files never have to fit in memory.
+func filterEmails(cfg Config) (Result, error) {
+	result := Result{Mode: cfg.Mode}
+
+	// Load the lists, keyed the same way the input emails will be
+	lists, err := suppress.Load(cfg.Lists, cfg.Normalize, cfg.Mapping)
+	if err != nil {
+		return result, err
+	}
+	var classifier *domains.Classifier
+	excluded := make(map[string]bool)
+	if len(cfg.ExcludeCategories) > 0 {
+		if classifier, err = domains.Load(cfg.DomainLists); err != nil {
+			return result, err
+		}
+		for _, category := range cfg.ExcludeCategories {
+			excluded[category] = true
+		}
+	}
+	labels := lists.Labels()
+	if classifier != nil {
+		labels = append(labels, CategoryList)
+	}
+	hits := make(map[string]int)
+	ruleHits := make(map[suppress.Match]int)
+	var rules []suppress.Match
+
+	// Collect the input files from all selected files or folders
+	files, err := collectInputFiles(cfg.InputPaths)
+	if err != nil {
+		return result, err
+	}
+
+	fileColumns, headers := outputColumns(cfg, files)
+	out, err := openOutputs(cfg, headers)
+	if err != nil {
+		return result, err
+	}
+	var validator *validate.Validator
+	if cfg.Validate.Enabled {
+		validator = validate.New(cfg.Validate)
+	}
+
+	// Filter records
+	utils.LogMessage(fmt.Sprintf("Filtering records against lists: %s (mode: %s)", strings.Join(labels, ", "), cfg.Mode))
+	for _, file := range files {
+		utils.LogMessage(fmt.Sprintf("Loading records from file: %s", file))
+		reader, err := records.OpenRecordReader(file, records.ReaderOptions{Mapping: cfg.Mapping})
+		if err != nil {
+			// Log the error and continue
+			utils.LogMessage(fmt.Sprintf("Error opening file: %s - %v", file, err))
+			continue
+		}
+
+		count := 0
+		for reader.Next() {
+			count++
+			candidates := []records.Record{reader.Record()}
+			if validator != nil {
+				var quarantined []validate.Quarantined
+				candidates, quarantined = validator.Check(reader.Record())
+				for _, q := range quarantined {
+					q.Record = q.Record.Select(fileColumns[file])
+					if err := out.quarantine.Write(q); err != nil {
+						reader.Close()
+						out.close()
+						return result, fmt.Errorf("failed to write quarantine file: %v", err)
+					}
+					result.Quarantined++
+				}
+			}
+
+			for _, record := range candidates {
+				matches := lists.Match(record.Email)
+				if classifier != nil {
+					if category := classifier.Classify(record.Email); excluded[category] {
+						matches = append(matches, suppress.Match{List: CategoryList, Entry: category, Rule: suppress.RuleCategory})
+					}
+				}
+				if len(matches) > 0 {
+					result.Matched++
+					counted := make(map[string]bool)
+					for _, m := range matches {
+						if !counted[m.List] {
+							counted[m.List] = true
+							hits[m.List]++
+						}
+						// Keep track of the domain and pattern rules, which are few
+						if m.Pattern() {
+							if ruleHits[m] == 0 {
+								rules = append(rules, m)
+							}
+							ruleHits[m]++
+						}
+					}
+				} else {
+					result.Unmatched++
+				}
+				if err := out.write(record, fileColumns[file], matches); err != nil {
+					reader.Close()
+					out.close()
+					return result, err
+				}
+			}
+		}
+		if err := reader.Err(); err != nil {
+			// Log the error and continue
+			utils.LogMessage(fmt.Sprintf("Error reading file: %s - %v", file, err))
+		}
+		reader.Close()
+		result.Records += count
+		utils.LogMessage(fmt.Sprintf("Loaded %d records from file: %s", count, file))
+	}
+
+	// The split mode also reports the list entries no record matched
+	if cfg.Mode == ModeSplit {
+		_, _, onlyLists := SplitPaths(cfg.OutputFilePath)
+		// The split column belongs to the records, not to the list entries
+		writer, err := out.create(onlyLists, listEntryColumns, records.WriterOptions{SheetRows: cfg.Output.SheetRows})
+		if err != nil {
+			out.close()
+			return result, err
+		}
+		for _, m := range lists.Unmatched() {
+			entry := records.Record{OthersMap: map[string]string{"List": m.List, "Entry": m.Entry, "Rule": m.Rule}}
+			if err := writer.Write(entry); err != nil {
+				out.close()
+				return result, fmt.Errorf("failed to write output file %s: %v", onlyLists, err)
+			}
+			result.ListOnly++
+		}
+	}
+
+	if err := out.close(); err != nil {
+		return result, err
+	}
+	if result.Records == 0 {
+		out.remove()
+		return result, fmt.Errorf("no valid input records found")
+	}
+
+	// Run summary
+	for _, label := range labels {
+		result.Hits = append(result.Hits, ListHit{List: label, Records: hits[label]})
+		utils.LogMessage(fmt.Sprintf("List %s matched %d records", label, hits[label]))
+	}
+	for _, m := range rules {
+		utils.LogMessage(fmt.Sprintf("List %s, %s rule %s matched %d records", m.List, m.Rule, m.Entry, ruleHits[m]))
+	}
+	if validator != nil {
+		utils.LogMessage(validator.Summary())
+		utils.LogMessage(fmt.Sprintf("Quarantined %d records with unusable emails to %s", result.Quarantined, validate.QuarantinePath(cfg.OutputFilePath)))
+	}
+	utils.LogMessage(fmt.Sprintf("Matched %d of %d records", result.Matched, result.Records))
+	switch cfg.Mode {
+	case ModeExclude:
+		utils.LogMessage(fmt.Sprintf("Wrote %d records to output file: %s", result.Unmatched, cfg.OutputFilePath))
+		if cfg.RejectedFilePath != "" {
+			utils.LogMessage(fmt.Sprintf("Wrote %d rejected records to: %s", result.Matched, cfg.RejectedFilePath))
+		}
+	case ModeKeep:
+		utils.LogMessage(fmt.Sprintf("Wrote %d records to output file: %s", result.Matched, cfg.OutputFilePath))
+		if cfg.RejectedFilePath != "" {
+			utils.LogMessage(fmt.Sprintf("Wrote %d rejected records to: %s", result.Unmatched, cfg.RejectedFilePath))
+		}
+	case ModeSplit:
+		onlyInput, inBoth, onlyLists := SplitPaths(cfg.OutputFilePath)
+		utils.LogMessage(fmt.Sprintf("Wrote %d records only in the input to: %s", result.Unmatched, onlyInput))
+		utils.LogMessage(fmt.Sprintf("Wrote %d records found in both to: %s", result.Matched, inBoth))
+		utils.LogMessage(fmt.Sprintf("Wrote %d list entries matching no record to: %s", result.ListOnly, onlyLists))
+	}
+	utils.LogMessage("Email filtering completed successfully!")
+
+	return result, nil
+}
+
+// Summary describes the result for the user
+func (r Result) Summary() string {
+	var lines []string
+	switch r.Mode {
+	case ModeKeep:
+		lines = append(lines, fmt.Sprintf("Kept %d of %d records found in the lists, dropped %d.", r.Matched, r.Records, r.Unmatched))
+	case ModeSplit:
+		lines = append(lines, fmt.Sprintf("%d records only in the input, %d in both, %d list entries only in the lists.", r.Unmatched, r.Matched, r.ListOnly))
+	default:
+		lines = append(lines, fmt.Sprintf("Kept %d of %d records, excluded %d.", r.Unmatched, r.Records, r.Matched))
+	}
+	if r.Quarantined > 0 {
+		lines = append(lines, fmt.Sprintf("Quarantined %d records with unusable emails.", r.Quarantined))
+	}
+	for _, hit := range r.Hits {
+		lines = append(lines, fmt.Sprintf("%s: %d", hit.List, hit.Records))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// rejectedRecord lays out a record left out of the output with where it came from and why it was left out
+func rejectedRecord(record records.Record, columns []string, matches []suppress.Match) records.Record {
+	rejected := record.Select(columns)
+	var lists, entries, reasons []string
+	for _, m := range matches {
+		lists = append(lists, m.List)
+		entries = append(entries, m.Entry)
+		reasons = append(reasons, m.Reason())
+	}
+	if len(matches) == 0 {
+		reasons = append(reasons, "not listed in any list")
+	}
+	rejected.OthersMap[ColumnSourceFile] = record.Source()
+	rejected.OthersMap[ColumnSourceRow] = strconv.Itoa(record.Row)
+	rejected.OthersMap[ColumnMatchedList] = strings.Join(lists, "; ")
+	rejected.OthersMap[ColumnMatchedEntry] = strings.Join(entries, "; ")
+	rejected.OthersMap[ColumnReason] = strings.Join(reasons, "; ")
+	return rejected
+}
+
+// outputColumns works out the columns each file contributes and the output header,
+// which is the union of all selections in file order
+func outputColumns(cfg Config, files []string) (map[string][]string, []string) {
+	fileColumns := make(map[string][]string)
+	var headers []string
+	seen := make(map[string]bool)
+	for _, file := range files {
+		columns, ok := cfg.FileColumns[file]
+		if !ok && len(cfg.Columns) > 0 {
+			columns = cfg.Columns
+		} else if !ok {
+			fileHeaders, err := records.ReadHeaders(file, cfg.Mapping)
+			if err != nil {
+				utils.LogMessage(fmt.Sprintf("Error reading headers: %s - %v", file, err))
+			}
+			columns = fileHeaders
+		}
+		fileColumns[file] = columns
+		for _, column := range columns {
+			if column != "" && !seen[column] {
+				seen[column] = true
+				headers = append(headers, column)
+			}
+		}
+	}
+	return fileColumns, headers
+}
+
+// collectInputFiles expands the selected files and folders into the CSV/XLSX files to filter
+func collectInputFiles(inputPaths []string) ([]string, error) {
+	var files []string
+	for _, path := range inputPaths {
+		fileInfo, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to access input path: %v", err)
+		}
+		if !fileInfo.IsDir() {
+			// It's a file
+			if isSupportedFile(path) {
+				files = append(files, path)
+			}
+			continue
+		}
+
+		utils.LogMessage(fmt.Sprintf("Processing directory: %s", path))
+		// If it's a directory, walk through it and collect its files
+		err = filepath.Walk(path, func(p string, info os.FileInfo, err error) error {
+			if err != nil {
+				return nil // Skip this file and continue
+			}
+			if !info.IsDir() && isSupportedFile(p) {
+				files = append(files, p)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error walking the directory: %v", err)
+		}
+	}
+	return files, nil
+}
+
+func isSupportedFile(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".csv" || ext == ".xlsx"
+}
@@ -0,0 +1,282 @@
+package filter
+
+import (
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"website-copier/cmd/normalize"
+	"website-copier/cmd/provenance"
+	"website-copier/cmd/suppress"
+)
+
+// writeFile writes a file into a temporary folder
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// readCSV reads back the rows of a CSV file
+func readCSV(t *testing.T, path string) [][]string {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	rows, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rows
+}
+
+const contacts = "Name,Email\nAnn,ann@example.com\nBob,BOB@example.com\nCid,cid@example.com\nDee,dee@example.com\n"
+
+func TestRunLists(t *testing.T) {
+	input := writeFile(t, "contacts.csv", contacts)
+	output := filepath.Join(t.TempDir(), "kept.csv")
+	result, err := Run(Config{
+		InputPaths: []string{input},
+		Lists: []suppress.List{
+			{Label: "Unsubscribed", Path: writeFile(t, "unsubscribed.csv", "Email\nann@example.com\nbob@example.com\n")},
+			{Label: "Bounced", Path: writeFile(t, "bounced.csv", "Email,Reason\nbob@example.com,full\ncid@example.com,gone\n")},
+			{Label: "Complaints", Path: writeFile(t, "complaints.csv", "Email\nnobody@example.com\n")},
+		},
+		OutputFilePath: output,
+		Normalize:      normalize.DefaultOptions(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := Result{
+		Mode:      ModeExclude,
+		Records:   4,
+		Matched:   3,
+		Unmatched: 1,
+		Hits:      []ListHit{{"Unsubscribed", 2}, {"Bounced", 2}, {"Complaints", 0}},
+	}
+	if !reflect.DeepEqual(result, want) {
+		t.Errorf("Run() = %+v, want %+v", result, want)
+	}
+	wantSummary := "Kept 1 of 4 records, excluded 3.\nUnsubscribed: 2\nBounced: 2\nComplaints: 0"
+	if got := result.Summary(); got != wantSummary {
+		t.Errorf("Summary() = %q, want %q", got, wantSummary)
+	}
+	wantRows := [][]string{{"Name", "Email"}, {"Dee", "dee@example.com"}}
+	if rows := readCSV(t, output); !reflect.DeepEqual(rows, wantRows) {
+		t.Errorf("output = %q, want %q", rows, wantRows)
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	input := writeFile(t, "contacts.csv", contacts)
+	list := []suppress.List{{Label: "Unsubscribed", Path: writeFile(t, "unsubscribed.csv", "Email\nann@example.com\n")}}
+	output := filepath.Join(t.TempDir(), "kept.csv")
+
+	tests := []struct {
+		name string
+		cfg  Config
+	}{
+		{"no input", Config{Lists: list, OutputFilePath: output}},
+		{"no list", Config{InputPaths: []string{input}, OutputFilePath: output}},
+		{"list without a file", Config{InputPaths: []string{input}, Lists: []suppress.List{{Label: "Bounced"}}, OutputFilePath: output}},
+		{"no output", Config{InputPaths: []string{input}, Lists: list}},
+		{"unsupported output", Config{InputPaths: []string{input}, Lists: list, OutputFilePath: "kept.txt"}},
+		{"no records", Config{InputPaths: []string{writeFile(t, "empty.csv", "Name,Email\n")}, Lists: list, OutputFilePath: output}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Run(tt.cfg); err == nil {
+				t.Errorf("Run() = nil, want an error")
+			}
+		})
+	}
+	if _, err := os.Stat(output); !os.IsNotExist(err) {
+		t.Errorf("output of a failed run left behind: %v", err)
+	}
+}
+
+func TestRunRejected(t *testing.T) {
+	input := writeFile(t, "contacts.csv", contacts)
+	output := filepath.Join(t.TempDir(), "kept.csv")
+	rejected := RejectedPath(output)
+	result, err := Run(Config{
+		InputPaths: []string{input},
+		Lists: []suppress.List{
+			{Label: "Unsubscribed", Path: writeFile(t, "unsubscribed.csv", "Email\nbob@example.com\n")},
+			{Label: "Competitors", Path: writeFile(t, "competitors.csv", "Domain\n@example.com\n")},
+			{Label: "Bounced", Path: writeFile(t, "bounced.csv", "Email\nbob@example.com\n")},
+		},
+		OutputFilePath:   output,
+		RejectedFilePath: rejected,
+		Normalize:        normalize.DefaultOptions(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Unmatched != 0 || result.Matched != 4 {
+		t.Errorf("kept %d and excluded %d records, want 0 and 4", result.Unmatched, result.Matched)
+	}
+
+	rows := readCSV(t, rejected)
+	wantHeader := []string{"Name", "Email", ColumnSourceFile, ColumnSourceRow, ColumnMatchedList, ColumnMatchedEntry, ColumnReason}
+	if len(rows) != 5 || !reflect.DeepEqual(rows[0], wantHeader) {
+		t.Fatalf("rejected file = %q, want 4 records under %q", rows, wantHeader)
+	}
+	wantAnn := []string{"Ann", "ann@example.com", input, "2", "Competitors", "@example.com", "domain @example.com listed in Competitors"}
+	if !reflect.DeepEqual(rows[1], wantAnn) {
+		t.Errorf("rejected row = %q, want %q", rows[1], wantAnn)
+	}
+	// Bob is on every list; the matches are reported in list order
+	wantBob := []string{"Bob", "BOB@example.com", input, "3", "Unsubscribed; Competitors; Bounced", "bob@example.com; @example.com; bob@example.com",
+		"address listed in Unsubscribed; domain @example.com listed in Competitors; address listed in Bounced"}
+	if !reflect.DeepEqual(rows[2], wantBob) {
+		t.Errorf("rejected row = %q, want %q", rows[2], wantBob)
+	}
+}
+
+func TestRunRejectedSameAsOutput(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "kept.csv")
+	_, err := Run(Config{
+		InputPaths:       []string{writeFile(t, "contacts.csv", contacts)},
+		Lists:            []suppress.List{{Label: "Unsubscribed", Path: writeFile(t, "unsubscribed.csv", "Email\nann@example.com\n")}},
+		OutputFilePath:   output,
+		RejectedFilePath: output,
+	})
+	if err == nil {
+		t.Errorf("Run() = nil with the rejected file being the output, want an error")
+	}
+}
+
+// modeJob filters contacts and leads, whose columns differ in number and order, against lists matching
+// one record of each and holding an entry of each kind that matches nothing
+func modeJob(t *testing.T, mode string) Config {
+	return Config{
+		InputPaths: []string{
+			writeFile(t, "contacts.csv", contacts),
+			writeFile(t, "leads.csv", "Email,Name,Company\nzed@acme.com,Zed,Acme\nyan@other.org,,Other\n"),
+		},
+		Lists: []suppress.List{
+			{Label: "Unsubscribed", Path: writeFile(t, "unsubscribed.csv", "Email\nann@example.com\ncarl@nowhere.com\n")},
+			{Label: "Competitors", Path: writeFile(t, "competitors.csv", "Domain\n@acme.com\n@rival.com\n")},
+		},
+		OutputFilePath: filepath.Join(t.TempDir(), "out.csv"),
+		Mode:           mode,
+		Normalize:      normalize.DefaultOptions(),
+	}
+}
+
+func TestRunSplit(t *testing.T) {
+	cfg := modeJob(t, ModeSplit)
+	result, err := Run(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Unmatched != 4 || result.Matched != 2 || result.ListOnly != 2 {
+		t.Errorf("Run() = %+v, want 4 only in the input, 2 in both and 2 only in the lists", result)
+	}
+
+	// Every record file holds the union of the input columns, blank where a file has none
+	onlyInput, inBoth, onlyLists := SplitPaths(cfg.OutputFilePath)
+	want := map[string][][]string{
+		onlyInput: {
+			{"Name", "Email", "Company"},
+			{"Bob", "BOB@example.com", ""},
+			{"Cid", "cid@example.com", ""},
+			{"Dee", "dee@example.com", ""},
+			{"", "yan@other.org", "Other"},
+		},
+		inBoth: {
+			{"Name", "Email", "Company"},
+			{"Ann", "ann@example.com", ""},
+			{"Zed", "zed@acme.com", "Acme"},
+		},
+		onlyLists: {
+			{"List", "Entry", "Rule"},
+			{"Unsubscribed", "carl@nowhere.com", suppress.RuleEmail},
+			{"Competitors", "@rival.com", suppress.RuleDomain},
+		},
+	}
+	for path, rows := range want {
+		if got := readCSV(t, path); !reflect.DeepEqual(got, rows) {
+			t.Errorf("%s = %q, want %q", filepath.Base(path), got, rows)
+		}
+	}
+	if _, err := os.Stat(cfg.OutputFilePath); !os.IsNotExist(err) {
+		t.Errorf("split mode wrote the output file itself: %v", err)
+	}
+}
+
+func TestRunKeep(t *testing.T) {
+	cfg := modeJob(t, ModeKeep)
+	cfg.RejectedFilePath = RejectedPath(cfg.OutputFilePath)
+	result, err := Run(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantSummary := "Kept 2 of 6 records found in the lists, dropped 4.\nUnsubscribed: 1\nCompetitors: 1"
+	if got := result.Summary(); got != wantSummary {
+		t.Errorf("Summary() = %q, want %q", got, wantSummary)
+	}
+
+	wantKept := [][]string{{"Name", "Email", "Company"}, {"Ann", "ann@example.com", ""}, {"Zed", "zed@acme.com", "Acme"}}
+	if rows := readCSV(t, cfg.OutputFilePath); !reflect.DeepEqual(rows, wantKept) {
+		t.Errorf("output = %q, want %q", rows, wantKept)
+	}
+	rejected := readCSV(t, cfg.RejectedFilePath)
+	if len(rejected) != 5 {
+		t.Fatalf("rejected file = %q, want 4 records", rejected)
+	}
+	wantYan := []string{"", "yan@other.org", "Other", cfg.InputPaths[1], "3", "", "", "not listed in any list"}
+	if !reflect.DeepEqual(rejected[4], wantYan) {
+		t.Errorf("rejected row = %q, want %q", rejected[4], wantYan)
+	}
+}
+
+func TestRunModeErrors(t *testing.T) {
+	cfg := modeJob(t, "invert")
+	if _, err := Run(cfg); err == nil {
+		t.Errorf("Run() = nil for an unknown mode, want an error")
+	}
+	cfg = modeJob(t, ModeSplit)
+	cfg.RejectedFilePath = RejectedPath(cfg.OutputFilePath)
+	if _, err := Run(cfg); err == nil {
+		t.Errorf("Run() = nil for a split with a rejected file, want an error")
+	}
+}
+
+func TestRunProvenance(t *testing.T) {
+	cfg := modeJob(t, ModeExclude)
+	cfg.Provenance = provenance.Options{Columns: provenance.RecordNames}
+	if _, err := Run(cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	rows := readCSV(t, cfg.OutputFilePath)
+	wantHeader := []string{"Name", "Email", "Company", "Source File", "Source Sheet", "Source Row", "Imported At"}
+	if len(rows) != 5 || !reflect.DeepEqual(rows[0], wantHeader) {
+		t.Fatalf("output = %q, want 4 records under %q", rows, wantHeader)
+	}
+	wantYan := []string{"", "yan@other.org", "Other", cfg.InputPaths[1], "", "3"}
+	if !reflect.DeepEqual(rows[4][:6], wantYan) {
+		t.Errorf("output row = %q, want it to start with %q", rows[4], wantYan)
+	}
+	// The import time is taken once when the job starts, not per record or file
+	for _, row := range rows[1:] {
+		if row[6] != rows[1][6] {
+			t.Errorf("import times differ: %q and %q", rows[1][6], row[6])
+		}
+	}
+	if _, err := time.ParseInLocation(provenance.TimeLayout, rows[1][6], time.Local); err != nil {
+		t.Errorf("import time %q: %v", rows[1][6], err)
+	}
+}
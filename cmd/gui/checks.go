@@ -0,0 +1,92 @@
+package gui
+
+import (
+	"website-copier/cmd/normalize"
+	"website-copier/cmd/provenance"
+	"website-copier/cmd/typos"
+	"website-copier/cmd/validate"
+
+	"fyne.io/fyne/v2/widget"
+)
+
+// CreateOptionChecks creates a row of check boxes, one per label, with the
+// ticked labels checked. apply gets every label's state on each change.
+func CreateOptionChecks(labels, ticked []string, apply func(checked map[string]bool)) *widget.CheckGroup {
+	checks := widget.NewCheckGroup(labels, nil)
+	checks.Horizontal = true
+	checks.SetSelected(ticked)
+	checks.OnChanged = func(selected []string) {
+		checked := make(map[string]bool)
+		for _, label := range selected {
+			checked[label] = true
+		}
+		apply(checked)
+	}
+	return checks
+}
+
+// CreateNormalizeChecks creates one check box per email normalization rule, bound to opts
+func CreateNormalizeChecks(opts *normalize.Options) *widget.CheckGroup {
+	var labels, ticked []string
+	enabled := make(map[string]bool)
+	for _, name := range opts.Names() {
+		enabled[name] = true
+	}
+	for _, rule := range normalize.Rules {
+		labels = append(labels, rule.Label)
+		if enabled[rule.Name] {
+			ticked = append(ticked, rule.Label)
+		}
+	}
+	return CreateOptionChecks(labels, ticked, func(checked map[string]bool) {
+		*opts = normalize.Options{}
+		for _, rule := range normalize.Rules {
+			if checked[rule.Label] {
+				opts.Set(rule.Name, true)
+			}
+		}
+	})
+}
+
+// Labels of the validation and typo correction check boxes
+const (
+	labelValidate        = "Validate emails (unusable rows go to a _quarantine file)"
+	labelSplitMultiple   = "Split cells with several addresses"
+	labelQuarantineRoles = "Quarantine role accounts (info@, admin@...)"
+	labelTypos           = "Suggest fixes for misspelt domains such as gmial.com (listed in a _corrections file)"
+	labelAutoCorrect     = "Correct them automatically"
+)
+
+// CreateValidateChecks creates the email validation check boxes, bound to opts
+func CreateValidateChecks(opts *validate.Options) *widget.CheckGroup {
+	return CreateOptionChecks([]string{labelValidate, labelSplitMultiple, labelQuarantineRoles}, nil, func(checked map[string]bool) {
+		opts.Enabled = checked[labelValidate]
+		opts.SplitMultiple = checked[labelSplitMultiple]
+		opts.QuarantineRoles = checked[labelQuarantineRoles]
+	})
+}
+
+// CreateTypoChecks creates the typo correction check boxes, bound to opts
+func CreateTypoChecks(opts *typos.Options) *widget.CheckGroup {
+	return CreateOptionChecks([]string{labelTypos, labelAutoCorrect}, nil, func(checked map[string]bool) {
+		opts.Enabled = checked[labelTypos]
+		opts.AutoCorrect = checked[labelAutoCorrect]
+	})
+}
+
+// CreateProvenanceChecks creates a check box per provenance column, bound to
+// opts. The columns are offered in the order given.
+func CreateProvenanceChecks(opts *provenance.Options, names []string) *widget.CheckGroup {
+	var headers []string
+	for _, name := range names {
+		headers = append(headers, provenance.Header(name))
+	}
+	return CreateOptionChecks(headers, nil, func(checked map[string]bool) {
+		opts.Columns = nil
+		for _, column := range provenance.Columns {
+			if checked[column.Header] {
+				opts.Columns = append(opts.Columns, column.Name)
+			}
+		}
+	})
+}
@@ -0,0 +1,529 @@
+package combine
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"website-copier/cmd/combine"
+	"website-copier/cmd/dedupe"
+	"website-copier/cmd/domains"
+	"website-copier/cmd/filter/lib"
+	"website-copier/cmd/gui"
+	"website-copier/cmd/normalize"
+	"website-copier/cmd/provenance"
+	"website-copier/cmd/records"
+	"website-copier/cmd/typos"
+	"website-copier/cmd/utils"
+	"website-copier/cmd/validate"
+
+	"github.com/sqweek/dialog"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+)
+
+func CreateCombineScreen(myWindow fyne.Window) fyne.CanvasObject {
+	// Variable to store selected files
+	var selectedFiles []string
+	normalizeOpts := normalize.DefaultOptions()
+	var validateOpts validate.Options
+	var typoOpts typos.Options
+	var provenanceOpts provenance.Options
+	var columnMapping records.ColumnMapping
+
+	// Create Input Selection Widgets
+	inputPathEntry := createInputPathEntry()
+	headerRowEntry := createHeaderRowEntry()
+	preview := lib.NewPreviewPane()
+	fileList, refreshFileList := createFileList(inputPathEntry, &selectedFiles, &columnMapping, headerRowEntry, preview)
+	selectFolderBtn, selectFileBtn, clearFilesBtn := createInputButtons(inputPathEntry, &selectedFiles, refreshFileList)
+
+	// Create Output Selection Widgets
+	outputPathEntry, _, outputFileNameEntry, outputFileEntry, outputOptionRadio, outputOptionsContainer := createOutputWidgets()
+
+	// Create Output Column Widgets
+	columnOrderEntry, sortColumnsCheck := createColumnOrderWidgets()
+
+	sheetRowsEntry, splitColumnEntry := gui.CreateSheetEntries()
+	domainCategoryCheck := widget.NewCheck("Add a \""+domains.Column+"\" column ("+strings.Join(domains.Categories, ", ")+")", nil)
+
+	// Create Duplicate Merging Widgets
+	merge := createMergeWidgets()
+
+	// Create Start Button
+	startBtn := createStartButton(
+		inputPathEntry,
+		outputPathEntry,
+		outputFileNameEntry,
+		outputFileEntry,
+		outputOptionRadio,
+		&selectedFiles,
+		&columnMapping,
+		headerRowEntry,
+		&normalizeOpts,
+		&validateOpts,
+		&typoOpts,
+		&provenanceOpts,
+		domainCategoryCheck,
+		columnOrderEntry,
+		sortColumnsCheck,
+		merge,
+		sheetRowsEntry,
+		splitColumnEntry,
+		myWindow,
+	)
+
+	// Create Log Viewer
+	logContent := createLogViewer()
+
+	// Adjusted Layout
+	split := container.NewVSplit(
+		// The options scroll so the window fits on small screens
+		container.NewVScroll(container.NewVBox(
+			widget.NewLabelWithStyle("Input Selection", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+			inputPathEntry,
+			container.NewHBox(selectFolderBtn, selectFileBtn, clearFilesBtn),
+			fileList,
+			headerRowEntry,
+			widget.NewLabelWithStyle("Preview", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+			preview.Container,
+			widget.NewLabelWithStyle("Output Selection", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+			outputOptionRadio,
+			outputOptionsContainer,
+			container.NewGridWithColumns(2, sheetRowsEntry, splitColumnEntry),
+			widget.NewLabelWithStyle("Output Columns", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+			columnOrderEntry,
+			sortColumnsCheck,
+			widget.NewLabelWithStyle("Duplicates", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+			merge.container(),
+			widget.NewLabelWithStyle("Email Matching", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+			gui.CreateNormalizeChecks(&normalizeOpts),
+			widget.NewLabelWithStyle("Email Validation", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+			gui.CreateValidateChecks(&validateOpts),
+			widget.NewLabelWithStyle("Domain Typos", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+			gui.CreateTypoChecks(&typoOpts),
+			widget.NewLabelWithStyle("Domain Categories", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+			domainCategoryCheck,
+			widget.NewLabelWithStyle("Provenance Columns", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+			gui.CreateProvenanceChecks(&provenanceOpts, provenance.Names),
+			startBtn,
+		)),
+		container.NewVScroll(logContent),
+	)
+
+	split.Offset = 0.75
+	return split
+}
+
+// createInputPathEntry creates the input path entry widget
+func createInputPathEntry() *widget.Entry {
+	inputPathEntry := widget.NewMultiLineEntry()
+	inputPathEntry.SetPlaceHolder("No folder selected or files added")
+	inputPathEntry.Disable() // Make it read-only
+	return inputPathEntry
+}
+
+// createHeaderRowEntry creates the entry overriding the detected header row of the inputs
+func createHeaderRowEntry() *widget.Entry {
+	headerRowEntry := widget.NewEntry()
+	headerRowEntry.SetPlaceHolder("Header row (optional, found among the first rows when empty)")
+	return headerRowEntry
+}
+
+// parseHeaderRow reads the header row entry, 0 meaning it is detected
+func parseHeaderRow(headerRowEntry *widget.Entry) (int, error) {
+	text := strings.TrimSpace(headerRowEntry.Text)
+	if text == "" {
+		return 0, nil
+	}
+	row, err := strconv.Atoi(text)
+	if err != nil || row < 1 {
+		return 0, fmt.Errorf("Header row must be a positive number")
+	}
+	return row, nil
+}
+
+// createFileList lists the files found in the inputs with the sheets of each
+// workbook, which can be unchecked to leave them out. Clicking a file or sheet
+// previews it. The returned function refreshes the list after the inputs change.
+func createFileList(inputPathEntry *widget.Entry, selectedFiles *[]string, columnMapping *records.ColumnMapping, headerRowEntry *widget.Entry, preview *lib.PreviewPane) (fyne.CanvasObject, func()) {
+	var files []string
+	fileList, items := lib.CreateInputList(&files, columnMapping, nil)
+	fileList.OnSelected = func(id widget.ListItemID) {
+		item := items()[id]
+		mapping := *columnMapping
+		if headerRow, err := parseHeaderRow(headerRowEntry); err == nil {
+			mapping.HeaderRow = headerRow
+		}
+		preview.Show(item.File, item.Sheet, mapping)
+	}
+	refresh := func() {
+		inputs := *selectedFiles
+		if len(inputs) == 0 && inputPathEntry.Text != "" {
+			inputs = []string{inputPathEntry.Text}
+		}
+		files = nil
+		if len(inputs) > 0 {
+			var err error
+			if files, err = combine.CollectFiles(inputs); err != nil {
+				utils.LogMessage(err.Error())
+			}
+		}
+		fileList.Refresh()
+	}
+	scroll := container.NewVScroll(fileList)
+	scroll.SetMinSize(fyne.NewSize(0, 120))
+	return scroll, refresh
+}
+
+// createInputButtons creates the buttons for selecting folders and files, and clearing the selection
+func createInputButtons(inputPathEntry *widget.Entry, selectedFiles *[]string, onChange func()) (*widget.Button, *widget.Button, *widget.Button) {
+	selectFolderBtn := widget.NewButton("Select Folder", func() {
+		folderPath, err := dialog.Directory().Title("Select Input Folder").Browse()
+		if err != nil {
+			return // User cancelled or an error occurred
+		}
+		inputPathEntry.SetText(folderPath)
+		onChange()
+	})
+
+	selectFileBtn := widget.NewButton("Add File", func() {
+		for {
+			file, err := dialog.File().Title("Select Files").Filter("CSV and XLSX Files", "csv", "xlsx").Load()
+			if err != nil {
+				break // User cancelled or an error occurred
+			}
+			*selectedFiles = append(*selectedFiles, file)
+		}
+		inputPathEntry.SetText(strings.Join(*selectedFiles, "\n"))
+		onChange()
+	})
+
+	clearFilesBtn := widget.NewButton("Clear Files", func() {
+		*selectedFiles = []string{}
+		inputPathEntry.SetText("")
+		onChange()
+	})
+
+	return selectFolderBtn, selectFileBtn, clearFilesBtn
+}
+
+// createOutputWidgets creates the output selection widgets with a toggle between existing output file and folder path with filename
+func createOutputWidgets() (*widget.Entry, *widget.Button, *widget.Entry, *widget.Entry, *widget.RadioGroup, *fyne.Container) {
+	// Output Option RadioGroup
+	outputOptions := []string{"Select Existing Output File", "Specify Output Folder and Filename"}
+	outputOptionRadio := widget.NewRadioGroup(outputOptions, nil)
+	outputOptionRadio.SetSelected("Specify Output Folder and Filename") // Default selection
+
+	// Widgets for "Select Existing Output File" option
+	outputFileEntry := widget.NewEntry()
+	outputFileEntry.SetPlaceHolder("No output file selected")
+	selectOutputFileBtn := widget.NewButton("Select Output File", func() {
+		filePath, err := dialog.File().Title("Select Output File").Filter("CSV and XLSX Files", "csv", "xlsx").Load()
+		if err != nil {
+			return // User cancelled or an error occurred
+		}
+		outputFileEntry.SetText(filePath)
+	})
+
+	// Widgets for "Specify Output Folder and Filename" option
+	outputPathEntry := widget.NewEntry()
+	outputPathEntry.SetPlaceHolder("No output folder selected")
+	selectOutputFolderBtn := widget.NewButton("Select Output Folder", func() {
+		folderPath, err := dialog.Directory().Title("Select Output Folder").Browse()
+		if err != nil {
+			return // User cancelled or an error occurred
+		}
+		outputPathEntry.SetText(folderPath)
+	})
+	outputFileNameEntry := widget.NewEntry()
+	outputFileNameEntry.SetPlaceHolder("Enter output file name (e.g., combined_output.csv or combined_output.xlsx)")
+
+	// Container to hold the widgets that will change based on selection
+	outputOptionsContainer := container.NewVBox()
+
+	// Function to update the output options container
+	updateOutputOptions := func(selected string) {
+		outputOptionsContainer.Objects = nil
+		if selected == "Select Existing Output File" {
+			outputOptionsContainer.Add(outputFileEntry)
+			outputOptionsContainer.Add(selectOutputFileBtn)
+		} else if selected == "Specify Output Folder and Filename" {
+			outputOptionsContainer.Add(outputPathEntry)
+			outputOptionsContainer.Add(selectOutputFolderBtn)
+			outputOptionsContainer.Add(outputFileNameEntry)
+		}
+		outputOptionsContainer.Refresh()
+	}
+
+	// Set the initial state
+	updateOutputOptions(outputOptionRadio.Selected)
+
+	// Set the handler for when the selection changes
+	outputOptionRadio.OnChanged = updateOutputOptions
+
+	return outputPathEntry, selectOutputFolderBtn, outputFileNameEntry, outputFileEntry, outputOptionRadio, outputOptionsContainer
+}
+
+// createColumnOrderWidgets creates the widgets controlling the order of the output columns
+func createColumnOrderWidgets() (*widget.Entry, *widget.Check) {
+	columnOrderEntry := widget.NewEntry()
+	columnOrderEntry.SetPlaceHolder("Columns to place first, comma separated (e.g., Email, Name, Phone)")
+	sortColumnsCheck := widget.NewCheck("Sort the other columns alphabetically", nil)
+	return columnOrderEntry, sortColumnsCheck
+}
+
+// mergeWidgets holds the widgets choosing how duplicate emails are merged
+type mergeWidgets struct {
+	strategy   *widget.Select
+	priority   *widget.Entry
+	dateColumn *widget.Entry
+	fillBlanks *widget.Check
+	fuzzy      *widget.Check
+	fuzzyMerge *widget.Check
+	threshold  *widget.Slider
+	// thresholdLabel shows the value of the threshold slider
+	thresholdLabel *widget.Label
+}
+
+// createMergeWidgets creates the duplicate merging widgets, showing the extra
+// entry only for the strategies that need one
+func createMergeWidgets() *mergeWidgets {
+	defaults := dedupe.DefaultOptions()
+	m := &mergeWidgets{
+		priority:   widget.NewEntry(),
+		dateColumn: widget.NewEntry(),
+		fillBlanks: widget.NewCheck("Fill blank fields from the other duplicates", nil),
+		fuzzyMerge: widget.NewCheck("Merge them automatically", nil),
+		threshold:  widget.NewSlider(0.8, 1),
+	}
+	m.priority.SetPlaceHolder("Source files, highest priority first, comma separated (e.g., crm.xlsx, *.csv)")
+	m.dateColumn.SetPlaceHolder("Date column to compare (e.g., Last Updated)")
+	m.fillBlanks.SetChecked(defaults.FillBlanks)
+
+	m.strategy = widget.NewSelect(dedupe.Strategies, func(selected string) {
+		m.priority.Hide()
+		m.dateColumn.Hide()
+		switch selected {
+		case dedupe.Priority:
+			m.priority.Show()
+		case dedupe.Newest:
+			m.dateColumn.Show()
+		}
+	})
+	m.strategy.SetSelected(defaults.Strategy)
+
+	m.threshold.Step = 0.01
+	m.threshold.SetValue(dedupe.DefaultThreshold)
+	thresholdLabel := widget.NewLabel("")
+	m.threshold.OnChanged = func(value float64) {
+		thresholdLabel.SetText(fmt.Sprintf("Similarity: %.2f", value))
+	}
+	m.threshold.OnChanged(m.threshold.Value)
+	m.fuzzy = widget.NewCheck("Find similar names at the same domain or organization (listed in a _duplicates file)", func(checked bool) {
+		if checked {
+			m.fuzzyMerge.Enable()
+			m.threshold.Enable()
+		} else {
+			m.fuzzyMerge.Disable()
+			m.threshold.Disable()
+		}
+	})
+	m.fuzzy.OnChanged(false)
+	m.thresholdLabel = thresholdLabel
+	return m
+}
+
+func (m *mergeWidgets) container() *fyne.Container {
+	return container.NewVBox(
+		container.NewHBox(widget.NewLabel("Keep:"), m.strategy, m.fillBlanks),
+		m.priority,
+		m.dateColumn,
+		container.NewHBox(m.fuzzy, m.fuzzyMerge),
+		container.NewBorder(nil, nil, m.thresholdLabel, nil, m.threshold),
+	)
+}
+
+// options returns the merge options chosen in the widgets
+func (m *mergeWidgets) options() dedupe.Options {
+	var priority []string
+	for _, item := range strings.Split(m.priority.Text, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			priority = append(priority, item)
+		}
+	}
+	return dedupe.Options{
+		Strategy:   m.strategy.Selected,
+		FillBlanks: m.fillBlanks.Checked,
+		Priority:   priority,
+		DateColumn: strings.TrimSpace(m.dateColumn.Text),
+		Fuzzy: dedupe.FuzzyOptions{
+			Enabled:   m.fuzzy.Checked,
+			Threshold: m.threshold.Value,
+			AutoMerge: m.fuzzyMerge.Checked,
+		},
+	}
+}
+
+// createStartButton creates the start button to begin processing
+func createStartButton(
+	inputPathEntry *widget.Entry,
+	outputPathEntry *widget.Entry,
+	outputFileNameEntry *widget.Entry,
+	outputFileEntry *widget.Entry,
+	outputOptionRadio *widget.RadioGroup,
+	selectedFiles *[]string,
+	columnMapping *records.ColumnMapping,
+	headerRowEntry *widget.Entry,
+	normalizeOpts *normalize.Options,
+	validateOpts *validate.Options,
+	typoOpts *typos.Options,
+	provenanceOpts *provenance.Options,
+	domainCategoryCheck *widget.Check,
+	columnOrderEntry *widget.Entry,
+	sortColumnsCheck *widget.Check,
+	merge *mergeWidgets,
+	sheetRowsEntry *widget.Entry,
+	splitColumnEntry *widget.Entry,
+	myWindow fyne.Window,
+) *widget.Button {
+	return widget.NewButton("Start Processing", func() {
+		go func() {
+			inputPath := inputPathEntry.Text
+			outputPath := outputPathEntry.Text
+			outputFileName := outputFileNameEntry.Text
+			outputFile := outputFileEntry.Text
+			outputOption := outputOptionRadio.Selected
+
+			if inputPath == "" && len(*selectedFiles) == 0 {
+				gui.ShowError(fmt.Errorf("Please select an input folder or add files"), myWindow)
+				return
+			}
+
+			// Output validation
+			var outputFilePath string
+			if outputOption == "Select Existing Output File" {
+				if outputFile == "" {
+					gui.ShowError(fmt.Errorf("Please select an output file"), myWindow)
+					return
+				}
+				outputFilePath = outputFile
+			} else if outputOption == "Specify Output Folder and Filename" {
+				if outputPath == "" {
+					gui.ShowError(fmt.Errorf("Please select an output folder"), myWindow)
+					return
+				}
+				if outputFileName == "" {
+					gui.ShowError(fmt.Errorf("Please enter an output file name"), myWindow)
+					return
+				}
+				// Ensure the output file has a .csv or .xlsx extension
+				if err := records.CheckOutputFile(outputFileName); err != nil {
+					gui.ShowError(fmt.Errorf("Output file name must have a .csv or .xlsx extension"), myWindow)
+					return
+				}
+				outputFilePath = filepath.Join(outputPath, outputFileName)
+			} else {
+				gui.ShowError(fmt.Errorf("Invalid output option selected"), myWindow)
+				return
+			}
+
+			sheetRows, splitColumn, err := gui.ParseSheetEntries(sheetRowsEntry, splitColumnEntry)
+			if err != nil {
+				gui.ShowError(err, myWindow)
+				return
+			}
+			headerRow, err := parseHeaderRow(headerRowEntry)
+			if err != nil {
+				gui.ShowError(err, myWindow)
+				return
+			}
+			mapping := *columnMapping
+			mapping.HeaderRow = headerRow
+
+			// Open the log file for writing
+			logFilePath := filepath.Join(filepath.Dir(outputFilePath), "process_log.txt")
+			logFile, err := os.OpenFile(logFilePath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0666)
+			if err != nil {
+				gui.ShowError(fmt.Errorf("Failed to open log file: %v", err), myWindow)
+				return
+			}
+			defer logFile.Close()
+
+			// Set up the logger to write to the log file
+			utils.Logger = log.New(logFile, "", log.Ldate|log.Ltime)
+
+			inputs := *selectedFiles
+			if len(inputs) == 0 {
+				inputs = []string{inputPath}
+			}
+
+			result, err := combine.Run(combine.Config{
+				Inputs:     inputs,
+				OutputPath: outputFilePath,
+				Mapping:    mapping,
+				Normalize:  *normalizeOpts,
+				Schema: records.SchemaOptions{
+					Order: strings.Split(columnOrderEntry.Text, ","),
+					Sort:  sortColumnsCheck.Checked,
+				},
+				Dedupe:   merge.options(),
+				Validate: *validateOpts,
+				Typos:    *typoOpts,
+				Output:   records.WriterOptions{SheetRows: sheetRows, SplitColumn: splitColumn},
+
+				Provenance: *provenanceOpts,
+
+				DomainCategory: domainCategoryCheck.Checked,
+			})
+			if err != nil {
+				utils.LogMessage(err.Error())
+				gui.ShowError(err, myWindow)
+				return
+			}
+
+			message := "Processing completed successfully!"
+			if result.Appended {
+				message = "Records appended successfully!"
+			}
+			if result.Quarantined > 0 {
+				message += fmt.Sprintf("\n%d records with unusable emails were quarantined.", result.Quarantined)
+			}
+			if result.Clusters > 0 {
+				message += fmt.Sprintf("\n%d clusters of similar records were found, see the duplicates report.", result.Clusters)
+			}
+			if result.Skipped > 0 {
+				message += fmt.Sprintf("\n%d groups of records were too large to look for similar names, see the log.", result.Skipped)
+			}
+			if result.Corrections > 0 {
+				message += fmt.Sprintf("\n%d records had a misspelt domain, see the corrections report.", result.Corrections)
+			}
+			gui.ShowInfo(message, myWindow)
+		}()
+	})
+}
+
+// createLogViewer creates the log viewer for displaying log messages
+func createLogViewer() *widget.Entry {
+	logContent := widget.NewMultiLineEntry()
+	logContent.Wrapping = fyne.TextWrapWord
+
+	// Periodically update the log viewer
+	go func() {
+		for {
+			utils.LogMutex.Lock()
+			logText := strings.Join(utils.LogMessages, "\n")
+			logContent.SetText(logText)
+			utils.LogMutex.Unlock()
+			time.Sleep(500 * time.Millisecond)
+		}
+	}()
+
+	return logContent
+}
@@ -0,0 +1,168 @@
+package compare
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"website-copier/cmd/compare"
+	"website-copier/cmd/gui"
+	"website-copier/cmd/normalize"
+	"website-copier/cmd/records"
+	"website-copier/cmd/utils"
+
+	"github.com/sqweek/dialog"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+)
+
+func CreateCompareScreen(myWindow fyne.Window) fyne.CanvasObject {
+	normalizeOpts := normalize.DefaultOptions()
+
+	// Input Elements
+	oldFileEntry, selectOldBtn := createFileSelection("Old export (e.g. last week)", "Select Old File")
+	newFileEntry, selectNewBtn := createFileSelection("New export (e.g. this week)", "Select New File")
+
+	// Output Elements
+	outputPathEntry, selectOutputBtn, outputFileNameEntry := createOutputWidgets()
+	sheetRowsEntry, splitColumnEntry := gui.CreateSheetEntries()
+
+	startBtn := createStartButton(oldFileEntry, newFileEntry, outputPathEntry, outputFileNameEntry, &normalizeOpts, sheetRowsEntry, splitColumnEntry, myWindow)
+
+	// Log Viewer
+	logViewer := createLogViewer()
+
+	// Layout
+	content := container.NewVSplit(
+		container.NewVBox(
+			widget.NewLabelWithStyle("Files to Compare", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+			container.NewBorder(nil, nil, nil, selectOldBtn, oldFileEntry),
+			container.NewBorder(nil, nil, nil, selectNewBtn, newFileEntry),
+			widget.NewLabelWithStyle("Output Selection", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+			container.NewBorder(nil, nil, nil, selectOutputBtn, outputPathEntry),
+			outputFileNameEntry,
+			container.NewGridWithColumns(2, sheetRowsEntry, splitColumnEntry),
+			widget.NewLabelWithStyle("Email Matching", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+			gui.CreateNormalizeChecks(&normalizeOpts),
+			startBtn,
+		),
+		container.NewVScroll(logViewer),
+	)
+
+	content.Offset = 0.75
+	return content
+}
+
+// createFileSelection creates a read-only path entry with a button to pick an export
+func createFileSelection(placeholder, title string) (*widget.Entry, *widget.Button) {
+	fileEntry := widget.NewEntry()
+	fileEntry.SetPlaceHolder(placeholder)
+	fileEntry.Disable() // Make it read-only
+	selectBtn := widget.NewButton(title, func() {
+		file, err := dialog.File().Title(title).Filter("CSV, TSV and XLSX Files", "csv", "tsv", "xlsx").Load()
+		if err != nil {
+			return // User cancelled or an error occurred
+		}
+		fileEntry.SetText(file)
+	})
+	return fileEntry, selectBtn
+}
+
+// createOutputWidgets creates the output folder and file name widgets
+func createOutputWidgets() (*widget.Entry, *widget.Button, *widget.Entry) {
+	outputPathEntry := widget.NewEntry()
+	outputPathEntry.SetPlaceHolder("Output folder")
+	outputPathEntry.Disable() // Make it read-only
+	selectOutputBtn := widget.NewButton("Select Output Folder", func() {
+		folderPath, err := dialog.Directory().Title("Select Output Folder").Browse()
+		if err != nil {
+			return // User cancelled or an error occurred
+		}
+		outputPathEntry.SetText(folderPath)
+	})
+
+	outputFileNameEntry := widget.NewEntry()
+	outputFileNameEntry.SetPlaceHolder("Output file name (e.g. changes.csv, changes.xlsx or changes.json)")
+	return outputPathEntry, selectOutputBtn, outputFileNameEntry
+}
+
+func createStartButton(oldFileEntry, newFileEntry, outputPathEntry, outputFileNameEntry *widget.Entry, normalizeOpts *normalize.Options, sheetRowsEntry, splitColumnEntry *widget.Entry, myWindow fyne.Window) *widget.Button {
+	return widget.NewButton("Start Comparison", func() {
+		go func() {
+			// Input validation
+			if oldFileEntry.Text == "" || newFileEntry.Text == "" {
+				gui.ShowError(fmt.Errorf("Please select the old and the new file"), myWindow)
+				return
+			}
+			if outputPathEntry.Text == "" {
+				gui.ShowError(fmt.Errorf("Please select an output folder"), myWindow)
+				return
+			}
+			if outputFileNameEntry.Text == "" {
+				gui.ShowError(fmt.Errorf("Please enter an output file name"), myWindow)
+				return
+			}
+			if err := compare.CheckOutputFile(outputFileNameEntry.Text); err != nil {
+				gui.ShowError(fmt.Errorf("Output file name must have a .csv, .xlsx or .json extension"), myWindow)
+				return
+			}
+			outputFilePath := filepath.Join(outputPathEntry.Text, outputFileNameEntry.Text)
+
+			sheetRows, splitColumn, err := gui.ParseSheetEntries(sheetRowsEntry, splitColumnEntry)
+			if err != nil {
+				gui.ShowError(err, myWindow)
+				return
+			}
+
+			// Open the log file for writing
+			logFilePath := filepath.Join(filepath.Dir(outputFilePath), "process_log.txt")
+			logFile, err := os.OpenFile(logFilePath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0666)
+			if err != nil {
+				gui.ShowError(fmt.Errorf("Failed to open log file: %v", err), myWindow)
+				return
+			}
+			defer logFile.Close()
+
+			// Set up the logger to write to the log file
+			utils.Logger = log.New(logFile, "", log.Ldate|log.Ltime)
+
+			result, err := compare.Run(compare.Config{
+				OldPath:        oldFileEntry.Text,
+				NewPath:        newFileEntry.Text,
+				OutputFilePath: outputFilePath,
+				Normalize:      *normalizeOpts,
+				Output:         records.WriterOptions{SheetRows: sheetRows, SplitColumn: splitColumn},
+			})
+			if err != nil {
+				gui.ShowError(fmt.Errorf("Error during comparison: %v", err), myWindow)
+				return
+			}
+
+			gui.ShowInfo("Comparison completed successfully!\n"+result.Summary(), myWindow)
+		}()
+	})
+}
+
+// createLogViewer creates the log viewer for displaying log messages
+func createLogViewer() *widget.Entry {
+	logContent := widget.NewMultiLineEntry()
+	logContent.Wrapping = fyne.TextWrapWord
+
+	// Periodically update the log viewer
+	go func() {
+		for {
+			utils.LogMutex.Lock()
+			logText := strings.Join(utils.LogMessages, "\n")
+			logContent.SetText(logText)
+			utils.LogMutex.Unlock()
+			time.Sleep(500 * time.Millisecond)
+		}
+	}()
+
+	return logContent
+}
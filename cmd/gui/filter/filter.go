@@ -0,0 +1,537 @@
+package filter
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"website-copier/cmd/domains"
+	"website-copier/cmd/filter"
+	"website-copier/cmd/filter/lib"
+	"website-copier/cmd/gui"
+	"website-copier/cmd/normalize"
+	"website-copier/cmd/provenance"
+	"website-copier/cmd/records"
+	"website-copier/cmd/suppress"
+	"website-copier/cmd/utils"
+	"website-copier/cmd/validate"
+
+	"github.com/sqweek/dialog"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	fynedialog "fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+func CreateFilterScreen(myWindow fyne.Window) fyne.CanvasObject {
+	// Variables to store selected files
+	var selectedInputFiles []string
+	var suppressionLists []suppress.List
+	fileHeaders := make(map[string][]string)
+	selectedHeaders := make(map[string][]string)
+	normalizeOpts := normalize.DefaultOptions()
+	var validateOpts validate.Options
+	var columnMapping records.ColumnMapping
+	var provenanceOpts provenance.Options
+
+	// Input Elements
+	inputPathEntry, selectFolderBtn, selectFilesBtn, clearInputSelectionBtn, fileListContainer := createInputElements(&selectedInputFiles, fileHeaders, selectedHeaders, &columnMapping, myWindow)
+	suppressionList, addListBtn, removeListBtn, listColumnsBtn := createSuppressionElements(&suppressionLists, &columnMapping, myWindow)
+
+	// Output Elements
+	outputOptionRadio, outputOptionsContainer := createOutputSelectionElements(selectedInputFiles)
+	sheetRowsEntry, splitColumnEntry := gui.CreateSheetEntries()
+	rejectedCheck := widget.NewCheck("Also write the rejected records with the reason for each", nil)
+	modeRadio := createModeRadio(rejectedCheck)
+	categoryChecks := widget.NewCheckGroup(domains.Categories, nil)
+	categoryChecks.Horizontal = true
+	startBtn := createStartButton(&selectedInputFiles, &suppressionLists, selectedHeaders, &normalizeOpts, &validateOpts, &columnMapping, outputOptionRadio, outputOptionsContainer, sheetRowsEntry, splitColumnEntry, modeRadio, rejectedCheck, categoryChecks, &provenanceOpts, myWindow)
+
+	// Log Viewer
+	logViewer := createLogViewer()
+
+	// Layout
+	content := container.NewVSplit(
+		// The options scroll so the window fits on small screens
+		container.NewVScroll(container.NewVBox(
+			widget.NewLabelWithStyle("Input Selection", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+			inputPathEntry,
+			widget.NewLabelWithStyle("Selected Files", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+			container.NewHBox(selectFolderBtn, selectFilesBtn, clearInputSelectionBtn),
+			widget.NewLabelWithStyle("File Headers", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+			fileListContainer,
+			widget.NewLabelWithStyle("Suppression Lists", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+			suppressionList,
+			container.NewHBox(addListBtn, removeListBtn, listColumnsBtn),
+			widget.NewLabelWithStyle("Domain Categories (matched like a suppression list)", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+			categoryChecks,
+			widget.NewLabelWithStyle("Filter Mode", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+			modeRadio,
+			widget.NewLabelWithStyle("Output Selection", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+			outputOptionRadio,
+			outputOptionsContainer,
+			container.NewGridWithColumns(2, sheetRowsEntry, splitColumnEntry),
+			rejectedCheck,
+			widget.NewLabelWithStyle("Provenance Columns", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+			gui.CreateProvenanceChecks(&provenanceOpts, provenance.RecordNames),
+			widget.NewLabelWithStyle("Email Matching", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+			gui.CreateNormalizeChecks(&normalizeOpts),
+			widget.NewLabelWithStyle("Email Validation", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+			gui.CreateValidateChecks(&validateOpts),
+			startBtn,
+		)),
+		container.NewVScroll(logViewer),
+	)
+
+	content.Offset = 0.75
+	return content
+}
+
+// createInputElements initializes the input selection elements
+func createInputElements(selectedInputFiles *[]string,
+	fileHeaders map[string][]string,
+	selectedHeaders map[string][]string, columnMapping *records.ColumnMapping, myWindow fyne.Window) (*widget.Entry, *widget.Button, *widget.Button, *widget.Button, fyne.CanvasObject) {
+	inputPathEntry := widget.NewMultiLineEntry()
+	inputPathEntry.SetPlaceHolder("No input files or folders selected")
+	inputPathEntry.Disable() // Make it read-only
+
+	headerDisplay := widget.NewMultiLineEntry()
+	headerDisplay.SetPlaceHolder("Select a file to view its headers")
+	headerDisplay.Disable() // Read-only
+
+	// File list with the sheets of each workbook, and header display. The headers
+	// of a workbook are those of the sheets left checked.
+	fileList, items := lib.CreateInputList(selectedInputFiles, columnMapping, func(file string) {
+		if headers, err := records.ReadHeaders(file, *columnMapping); err == nil {
+			fileHeaders[file] = headers
+		}
+	})
+
+	preview := lib.NewPreviewPane()
+	var selectedFile, selectedSheet string
+	fileList.OnSelected = func(id widget.ListItemID) {
+		item := items()[id]
+		file := item.File
+		selectedFile, selectedSheet = file, item.Sheet
+		preview.Show(file, item.Sheet, *columnMapping)
+		if item.Sheet != "" {
+			// Sheets only show their own headers; columns are selected for the whole file
+			headers, err := sheetHeaders(file, item.Sheet, *columnMapping)
+			if err != nil {
+				headerDisplay.SetText(err.Error())
+				return
+			}
+			headerDisplay.SetText(fmt.Sprintf("Headers for %s (sheet %s):\n%s", filepath.Base(file), item.Sheet, strings.Join(headers, ", ")))
+			return
+		}
+		headers := fileHeaders[file]
+		if len(headers) > 5 {
+			// Show modal to select headers
+			lib.ShowHeaderSelectionModal(myWindow, file, headers, selectedHeaders, headerDisplay)
+		} else {
+			// Store the selected headers, keeping an order chosen earlier
+			if _, ok := selectedHeaders[file]; !ok {
+				selectedHeaders[file] = headers
+			}
+			// Display headers in the headerDisplay area
+			headerText := fmt.Sprintf("Headers for %s:\n%s", filepath.Base(file), strings.Join(selectedHeaders[file], ", "))
+			headerDisplay.SetText(headerText)
+		}
+
+	}
+
+	// Show the folder selection dialog
+	selectFolderBtn := lib.ShowFolderSelectionDialog(selectedInputFiles, fileHeaders, inputPathEntry, headerDisplay, fileList)
+
+	//Show the file selection dialog
+	selectFilesBtn := lib.ShowFileSelectionDialog(selectedInputFiles, fileHeaders, inputPathEntry, fileList, myWindow)
+
+	//Clear selection button
+	clearInputSelectionBtn := lib.ClearSelectionButton(selectedInputFiles, fileHeaders, inputPathEntry, headerDisplay, fileList)
+
+	// Let the user override the detected Name/Email/OrgName columns of the selected file or sheet
+	mapColumnsBtn := widget.NewButton("Map Columns", func() {
+		if selectedFile == "" {
+			gui.ShowError(fmt.Errorf("Please select a file first"), myWindow)
+			return
+		}
+		file, headers := selectedFile, fileHeaders[selectedFile]
+		if selectedSheet != "" {
+			var err error
+			if headers, err = sheetHeaders(file, selectedSheet, *columnMapping); err != nil {
+				gui.ShowError(err, myWindow)
+				return
+			}
+		}
+		lib.ShowColumnMappingModal(myWindow, records.SheetKey(file, selectedSheet), headers, columnMapping, func() {
+			// The headers move with the header row, so the columns picked before no longer apply
+			headers, err := records.ReadHeaders(file, *columnMapping)
+			if err != nil {
+				gui.ShowError(fmt.Errorf("Failed to read headers: %v", err), myWindow)
+				return
+			}
+			fileHeaders[file] = headers
+			selectedHeaders[file] = headers
+			headerDisplay.SetText(fmt.Sprintf("Headers for %s:\n%s", filepath.Base(file), strings.Join(headers, ", ")))
+			preview.Show(file, selectedSheet, *columnMapping)
+		})
+	})
+
+	// Let the user pick and order the columns of the selected file that go to the output
+	selectColumnsBtn := widget.NewButton("Select Columns", func() {
+		if selectedFile == "" {
+			gui.ShowError(fmt.Errorf("Please select a file first"), myWindow)
+			return
+		}
+		lib.ShowHeaderSelectionModal(myWindow, selectedFile, fileHeaders[selectedFile], selectedHeaders, headerDisplay)
+	})
+
+	// Container for file list and header display, with the preview of the selected file below
+	fileSplit := container.NewHSplit(
+		container.NewVScroll(fileList),
+		container.NewBorder(nil, container.NewHBox(selectColumnsBtn, mapColumnsBtn), nil, nil, headerDisplay),
+	)
+	fileSplit.Offset = 0.3 // Adjust the split ratio as needed
+	fileListContainer := container.NewVBox(
+		fileSplit,
+		widget.NewLabelWithStyle("Preview", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		preview.Container,
+	)
+
+	return inputPathEntry, selectFolderBtn, selectFilesBtn, clearInputSelectionBtn, fileListContainer
+}
+
+// sheetHeaders reads the headers of one sheet of a workbook
+func sheetHeaders(file, sheet string, mapping records.ColumnMapping) ([]string, error) {
+	if !mapping.SheetSelected(file, sheet) {
+		return nil, fmt.Errorf("Sheet %s of %s is unchecked, check it to read its headers", sheet, filepath.Base(file))
+	}
+	sheets, err := records.GetSheetHeaders(file, records.HeaderOptions{Mapping: mapping})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read headers: %v", err)
+	}
+	for _, s := range sheets {
+		if s.Sheet == sheet {
+			return s.Headers, nil
+		}
+	}
+	return nil, fmt.Errorf("No headers found in sheet %s of %s", sheet, filepath.Base(file))
+}
+
+// createSuppressionElements initializes the suppression list elements. Each list
+// has a label that is reported for the records it excludes.
+func createSuppressionElements(lists *[]suppress.List, columnMapping *records.ColumnMapping, myWindow fyne.Window) (fyne.CanvasObject, *widget.Button, *widget.Button, *widget.Button) {
+	selected := -1
+	listView := widget.NewList(
+		func() int { return len(*lists) },
+		func() fyne.CanvasObject { return widget.NewLabel("") },
+		func(i widget.ListItemID, o fyne.CanvasObject) {
+			list := (*lists)[i]
+			text := fmt.Sprintf("%s: %s", list.Label, filepath.Base(list.Path))
+			if list.Hash != suppress.HashAuto {
+				text += fmt.Sprintf(" [%s]", list.Hash)
+			}
+			if len(list.Columns) > 0 {
+				text += fmt.Sprintf(" (columns: %s)", strings.Join(list.Columns, ", "))
+			}
+			o.(*widget.Label).SetText(text)
+		},
+	)
+	listView.OnSelected = func(id widget.ListItemID) {
+		selected = id
+	}
+	listView.OnUnselected = func(id widget.ListItemID) {
+		selected = -1
+	}
+
+	addListBtn := widget.NewButton("Add List", func() {
+		filePath, err := dialog.File().Title("Select Suppression List").Filter("CSV, TSV and XLSX Files", "csv", "tsv", "xlsx").Load()
+		if err != nil {
+			return // User cancelled or an error occurred
+		}
+		labelEntry := widget.NewEntry()
+		labelEntry.SetText(suppress.DefaultLabel(filePath))
+		hashOptions := []string{"Detect hashed emails", "Plain text", "MD5", "SHA-1", "SHA-256"}
+		hashSelect := widget.NewSelect(hashOptions, nil)
+		hashSelect.SetSelected(hashOptions[0])
+		items := []*widget.FormItem{
+			widget.NewFormItem("Label", labelEntry),
+			widget.NewFormItem("Entries", hashSelect),
+		}
+		fynedialog.ShowForm("Suppression List", "Add", "Cancel", items, func(ok bool) {
+			if !ok {
+				return
+			}
+			label := strings.TrimSpace(labelEntry.Text)
+			if label == "" {
+				label = suppress.DefaultLabel(filePath)
+			}
+			// The options are in the order of suppress.Hashes
+			hash := suppress.Hashes[hashSelect.SelectedIndex()]
+			*lists = append(*lists, suppress.List{Label: label, Path: filePath, Hash: hash})
+			listView.Refresh()
+		}, myWindow)
+	})
+
+	removeListBtn := widget.NewButton("Remove List", func() {
+		if selected < 0 || selected >= len(*lists) {
+			gui.ShowError(fmt.Errorf("Please select a list first"), myWindow)
+			return
+		}
+		*lists = append((*lists)[:selected], (*lists)[selected+1:]...)
+		listView.UnselectAll()
+		listView.Refresh()
+	})
+
+	// Let the user choose the columns holding addresses; every email-like column is used otherwise
+	listColumnsBtn := widget.NewButton("Email Columns", func() {
+		if selected < 0 || selected >= len(*lists) {
+			gui.ShowError(fmt.Errorf("Please select a list first"), myWindow)
+			return
+		}
+		list := &(*lists)[selected]
+		lib.ShowDatabaseColumnsModal(myWindow, list.Path, &list.Columns, *columnMapping, listView.Refresh)
+	})
+
+	listContainer := container.NewVScroll(listView)
+	listContainer.SetMinSize(fyne.NewSize(0, 100))
+	return listContainer, addListBtn, removeListBtn, listColumnsBtn
+}
+
+// createOutputSelectionElements initializes the output selection elements
+func createOutputSelectionElements(selectedInputFiles []string) (*widget.RadioGroup, *fyne.Container) {
+	outputOptionRadio := widget.NewRadioGroup([]string{"Select Existing Output File", "Specify Output Folder and Filename", "Generate Output Filename"}, nil)
+	outputOptionRadio.SetSelected("Specify Output Folder and Filename") // Default option
+
+	outputOptionsContainer := container.NewVBox()
+	updateOutputOptions(selectedInputFiles, outputOptionRadio.Selected, outputOptionsContainer)
+
+	outputOptionRadio.OnChanged = func(selected string) {
+		updateOutputOptions(selectedInputFiles, selected, outputOptionsContainer)
+	}
+
+	return outputOptionRadio, outputOptionsContainer
+}
+
+// updateOutputOptions updates the output options container based on selected option
+func updateOutputOptions(selectedInputFiles []string, selected string, container *fyne.Container) {
+	container.Objects = nil
+	if selected == "Select Existing Output File" {
+		outputFileEntry := widget.NewEntry()
+		outputFileEntry.SetPlaceHolder("No output file selected")
+		outputFileEntry.Disable()
+		selectOutputFileBtn := widget.NewButton("Select Output File", func() {
+			filePath, err := dialog.File().Title("Select Output File").Filter("CSV and XLSX Files", "csv", "xlsx").Load()
+			if err != nil {
+				return // User cancelled or an error occurred
+			}
+			outputFileEntry.SetText(filePath)
+		})
+
+		container.Add(outputFileEntry)
+		container.Add(selectOutputFileBtn)
+	} else if selected == "Specify Output Folder and Filename" {
+		outputPathEntry := widget.NewEntry()
+		outputPathEntry.SetPlaceHolder("No output folder selected")
+		selectOutputFolderBtn := widget.NewButton("Select Output Folder", func() {
+			folderPath, err := dialog.Directory().Title("Select Output Folder").Browse()
+			if err != nil {
+				return // User cancelled or an error occurred
+			}
+			outputPathEntry.SetText(folderPath)
+		})
+
+		outputFileNameEntry := widget.NewEntry()
+		outputFileNameEntry.SetPlaceHolder("Enter output file name (e.g., filtered_output.csv or filtered_output.xlsx)")
+
+		container.Add(outputPathEntry)
+		container.Add(selectOutputFolderBtn)
+		container.Add(outputFileNameEntry)
+
+	} else if selected == "Generate Output Filename" {
+		outputPathEntry := widget.NewEntry()
+		outputPathEntry.SetPlaceHolder("No output folder selected")
+		selectOutputFolderBtn := widget.NewButton("Select Output Folder", func() {
+			folderPath, err := dialog.Directory().Title("Select Output Folder").Browse()
+			if err != nil {
+				return // User cancelled or an error occurred
+			}
+			outputPathEntry.SetText(folderPath)
+			if len(selectedInputFiles) > 0 {
+				// Automatically generate output filename from the first input file
+				inputFile := selectedInputFiles[0]
+				fileName := strings.TrimSuffix(filepath.Base(inputFile), filepath.Ext(inputFile))
+				outputFileName := fmt.Sprintf("%s_filtered_output.csv", fileName)
+				outputPathEntry.SetText(filepath.Join(folderPath, outputFileName))
+			}
+		})
+		formatSelect := widget.NewSelect([]string{"csv", "xlsx"}, nil)
+		formatSelect.SetSelected("csv")
+
+		container.Add(outputPathEntry)
+		container.Add(selectOutputFolderBtn)
+		container.Add(formatSelect)
+	}
+
+	container.Refresh()
+}
+
+// createStartButton initializes the start button for filtering
+// modeLabels describe the filter modes in the order of Modes
+var modeLabels = []string{
+	"Remove records found in the lists",
+	"Keep only records found in the lists",
+	"Split into records only in the input, records in both and list entries only in the lists",
+}
+
+var modeOptions = map[string]string{
+	modeLabels[0]: filter.ModeExclude,
+	modeLabels[1]: filter.ModeKeep,
+	modeLabels[2]: filter.ModeSplit,
+}
+
+// createModeRadio picks the filter mode; the split mode writes every record, so it has no rejected records
+func createModeRadio(rejectedCheck *widget.Check) *widget.RadioGroup {
+	modeRadio := widget.NewRadioGroup(modeLabels, func(selected string) {
+		if modeOptions[selected] == filter.ModeSplit {
+			rejectedCheck.Disable()
+		} else {
+			rejectedCheck.Enable()
+		}
+	})
+	modeRadio.Required = true
+	modeRadio.SetSelected(modeLabels[0])
+	return modeRadio
+}
+
+func createStartButton(selectedInputFiles *[]string, suppressionLists *[]suppress.List, selectedHeaders map[string][]string, normalizeOpts *normalize.Options, validateOpts *validate.Options, columnMapping *records.ColumnMapping, outputOptionRadio *widget.RadioGroup, outputOptionsContainer *fyne.Container, sheetRowsEntry, splitColumnEntry *widget.Entry, modeRadio *widget.RadioGroup, rejectedCheck *widget.Check, categoryChecks *widget.CheckGroup, provenanceOpts *provenance.Options, myWindow fyne.Window) *widget.Button {
+	return widget.NewButton("Start Filtering", func() {
+		go func() {
+			// Input validation
+			if len(*selectedInputFiles) == 0 {
+				gui.ShowError(fmt.Errorf("Please select input files or folders"), myWindow)
+				return
+			}
+
+			if len(*suppressionLists) == 0 && len(categoryChecks.Selected) == 0 {
+				gui.ShowError(fmt.Errorf("Please add at least one suppression list or domain category"), myWindow)
+				return
+			}
+
+			var outputFilePath string
+			outputOption := outputOptionRadio.Selected
+			if outputOption == "Select Existing Output File" {
+				outputFileEntry := outputOptionsContainer.Objects[0].(*widget.Entry)
+				outputFilePath = outputFileEntry.Text
+				if outputFilePath == "" {
+					gui.ShowError(fmt.Errorf("Please select an output file"), myWindow)
+					return
+				}
+			} else if outputOption == "Specify Output Folder and Filename" {
+				outputPathEntry := outputOptionsContainer.Objects[0].(*widget.Entry)
+				outputFileNameEntry := outputOptionsContainer.Objects[2].(*widget.Entry)
+				outputFolder := outputPathEntry.Text
+				outputFileName := outputFileNameEntry.Text
+
+				if outputFolder == "" {
+					gui.ShowError(fmt.Errorf("Please select an output folder"), myWindow)
+					return
+				}
+				if outputFileName == "" {
+					gui.ShowError(fmt.Errorf("Please enter an output file name"), myWindow)
+					return
+				}
+				// Ensure the output file has a .csv or .xlsx extension
+				if err := records.CheckOutputFile(outputFileName); err != nil {
+					gui.ShowError(fmt.Errorf("Output file name must have a .csv or .xlsx extension"), myWindow)
+					return
+				}
+				outputFilePath = filepath.Join(outputFolder, outputFileName)
+			} else if outputOption == "Generate Output Filename" {
+				outputPathEntry := outputOptionsContainer.Objects[0].(*widget.Entry)
+				outputFolder := outputPathEntry.Text
+
+				if outputFolder == "" {
+					gui.ShowError(fmt.Errorf("Please select an output folder"), myWindow)
+					return
+				}
+
+				if len(*selectedInputFiles) > 0 {
+					// Automatically generate output filename from the first input file
+					format := outputOptionsContainer.Objects[2].(*widget.Select).Selected
+					inputFile := (*selectedInputFiles)[0]
+					fileName := strings.TrimSuffix(filepath.Base(inputFile), filepath.Ext(inputFile))
+					outputFilePath = filepath.Join(outputFolder, fmt.Sprintf("%s_filtered_output.%s", fileName, format))
+				}
+			}
+			sheetRows, splitColumn, err := gui.ParseSheetEntries(sheetRowsEntry, splitColumnEntry)
+			if err != nil {
+				gui.ShowError(err, myWindow)
+				return
+			}
+			// The rejected records go next to the output file
+			mode := modeOptions[modeRadio.Selected]
+			var rejectedFilePath string
+			if rejectedCheck.Checked && mode != filter.ModeSplit {
+				rejectedFilePath = filter.RejectedPath(outputFilePath)
+			}
+
+			// Open the log file for writing
+			logFilePath := filepath.Join(filepath.Dir(outputFilePath), "process_log.txt")
+			logFile, err := os.OpenFile(logFilePath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0666)
+			if err != nil {
+				gui.ShowError(fmt.Errorf("Failed to open log file: %v", err), myWindow)
+				return
+			}
+			defer logFile.Close()
+
+			// Set up the logger to write to the log file
+			utils.Logger = log.New(logFile, "", log.Ldate|log.Ltime)
+
+			// Perform filtering
+			result, err := filter.Run(filter.Config{
+				InputPaths:       *selectedInputFiles,
+				Lists:            append([]suppress.List(nil), *suppressionLists...),
+				OutputFilePath:   outputFilePath,
+				Mode:             mode,
+				RejectedFilePath: rejectedFilePath,
+				Normalize:        *normalizeOpts,
+				Mapping:          *columnMapping,
+				Validate:         *validateOpts,
+				FileColumns:      selectedHeaders,
+				Output:           records.WriterOptions{SheetRows: sheetRows, SplitColumn: splitColumn},
+
+				ExcludeCategories: append([]string(nil), categoryChecks.Selected...),
+				Provenance:        *provenanceOpts,
+			})
+			if err != nil {
+				gui.ShowError(fmt.Errorf("Error during filtering: %v", err), myWindow)
+				return
+			}
+
+			gui.ShowInfo("Email filtering completed successfully!\n"+result.Summary(), myWindow)
+		}()
+	})
+}
+
+func createLogViewer() *widget.Entry {
+	logContent := widget.NewMultiLineEntry()
+	logContent.Wrapping = fyne.TextWrapWord
+
+	// Periodically update the log viewer
+	go func() {
+		for {
+			utils.LogMutex.Lock()
+			logText := strings.Join(utils.LogMessages, "\n")
+			logContent.SetText(logText)
+			utils.LogMutex.Unlock()
+			time.Sleep(500 * time.Millisecond)
+		}
+	}()
+
+	return logContent
+}
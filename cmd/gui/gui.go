@@ -0,0 +1,112 @@
+package gui
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/widget"
+)
+
+// Global variables to store the last used directories
+var LastFileDirectory fyne.ListableURI
+var LastFolderDirectory fyne.ListableURI
+
+// Helper functions
+func ShowError(err error, win fyne.Window) {
+	dialog.ShowError(err, win)
+}
+
+func ShowInfo(message string, win fyne.Window) {
+	dialog.ShowInformation("Info", message, win)
+}
+
+func ShowFolderSelectionDialog(pathEntry *widget.Entry, win fyne.Window) {
+	folderDialog := dialog.NewFolderOpen(func(uri fyne.ListableURI, err error) {
+		if err != nil {
+			ShowError(err, win)
+			return
+		}
+		if uri != nil {
+			pathEntry.SetText(uri.Path())
+		}
+	}, win)
+	folderDialog.SetFilter(storage.NewExtensionFileFilter([]string{}))
+
+	// Reset the location to force refresh
+	folderDialog.SetLocation(nil)
+
+	folderDialog.Show()
+}
+
+func ShowFileOpenDialog(filePath *string, fileEntry *widget.Entry, win fyne.Window) {
+	fileDialog := dialog.NewFileOpen(func(reader fyne.URIReadCloser, err error) {
+		if err != nil {
+			ShowError(err, win)
+			return
+		}
+		if reader != nil {
+			ext := strings.ToLower(reader.URI().Extension())
+			if ext == ".csv" || ext == ".xlsx" {
+				*filePath = reader.URI().Path()
+				fileEntry.SetText(*filePath)
+				reader.Close()
+			} else {
+				ShowError(errors.New("Unsupported file type selected"), win)
+			}
+		}
+	}, win)
+	fileDialog.SetFilter(storage.NewExtensionFileFilter([]string{".csv", ".xlsx"}))
+
+	// Reset the location to force refresh
+	fileDialog.SetLocation(nil)
+
+	fileDialog.Show()
+}
+
+func ShowFileSelectionDialog(selectedFiles *[]string, inputPathEntry *widget.Entry, win fyne.Window) {
+	fileDialog := dialog.NewFileOpen(func(reader fyne.URIReadCloser, err error) {
+		if err != nil {
+			ShowError(err, win)
+			return
+		}
+		if reader != nil {
+			*selectedFiles = append(*selectedFiles, reader.URI().Path())
+			inputPathEntry.SetText(strings.Join(*selectedFiles, "\n"))
+			reader.Close()
+		}
+	}, win)
+	fileDialog.Show()
+}
+
+func DisplayHeadersInList(headerDisplay *widget.Entry, headers []string) {
+	headerText := strings.Join(headers, ", ")
+	headerText = strings.ToTitle(headerText)
+	headerDisplay.SetText(headerText)
+}
+
+// CreateSheetEntries creates the entries splitting XLSX output into several sheets
+func CreateSheetEntries() (*widget.Entry, *widget.Entry) {
+	sheetRowsEntry := widget.NewEntry()
+	sheetRowsEntry.SetPlaceHolder("Rows per sheet (optional)")
+	splitColumnEntry := widget.NewEntry()
+	splitColumnEntry.SetPlaceHolder("One sheet per value of column (optional)")
+	return sheetRowsEntry, splitColumnEntry
+}
+
+// ParseSheetEntries reads the rows per sheet and split column from the sheet entries
+func ParseSheetEntries(sheetRowsEntry, splitColumnEntry *widget.Entry) (int, string, error) {
+	rows := 0
+	if text := strings.TrimSpace(sheetRowsEntry.Text); text != "" {
+		n, err := strconv.Atoi(text)
+		if err != nil || n < 1 {
+			return 0, "", fmt.Errorf("Rows per sheet must be a positive number")
+		}
+		rows = n
+	}
+	return rows, strings.TrimSpace(splitColumnEntry.Text), nil
+}
@@ -0,0 +1,254 @@
+package normalize
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/idna"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Rule rewrites an email address into a more canonical form
+type Rule func(email string) string
+
+// Options switches the individual normalization rules on or off for a job
+type Options struct {
+	Trim             bool // Trim whitespace, surrounding quotes and mailto: prefixes
+	StripDisplayName bool // `"John" <john@x.com>` becomes john@x.com
+	Unicode          bool // Unicode NFC composition
+	FoldCase         bool // Lower-case the whole address
+	IDN              bool // Convert internationalized domains to their ASCII (punycode) form
+	GmailDots        bool // Ignore dots in the local part of Gmail addresses
+	PlusTags         bool // Drop +tags for providers that support sub-addressing
+}
+
+// RuleInfo names a rule for the CLI and labels it for the GUI
+type RuleInfo struct {
+	Name  string
+	Label string
+}
+
+// Rules lists every rule in the order it is applied
+var Rules = []RuleInfo{
+	{"trim", "Trim spaces and quotes"},
+	{"display-name", "Strip display names"},
+	{"unicode", "Unicode NFC"},
+	{"case", "Ignore case"},
+	{"idn", "Normalize international domains"},
+	{"gmail-dots", "Ignore dots in Gmail addresses"},
+	{"plus-tags", "Ignore +tags (Gmail, Outlook, iCloud...)"},
+}
+
+// DefaultOptions enables every generic rule; provider specific rules are opt-in
+func DefaultOptions() Options {
+	return Options{
+		Trim:             true,
+		StripDisplayName: true,
+		Unicode:          true,
+		FoldCase:         true,
+		IDN:              true,
+	}
+}
+
+// FromNames builds options from rule names, as used by the CLI
+func FromNames(names []string) (Options, error) {
+	var opts Options
+	for _, name := range names {
+		name = strings.TrimSpace(strings.ToLower(name))
+		if name == "" {
+			continue
+		}
+		field := opts.field(name)
+		if field == nil {
+			return opts, fmt.Errorf("unknown normalization rule: %s", name)
+		}
+		*field = true
+	}
+	return opts, nil
+}
+
+// Names returns the names of the enabled rules
+func (o Options) Names() []string {
+	var names []string
+	for _, rule := range Rules {
+		if *o.field(rule.Name) {
+			names = append(names, rule.Name)
+		}
+	}
+	return names
+}
+
+// Set switches a rule on or off by name, as used by the GUI check boxes
+func (o *Options) Set(name string, enabled bool) {
+	if field := o.field(name); field != nil {
+		*field = enabled
+	}
+}
+
+func (o *Options) field(name string) *bool {
+	switch name {
+	case "trim":
+		return &o.Trim
+	case "display-name":
+		return &o.StripDisplayName
+	case "unicode":
+		return &o.Unicode
+	case "case":
+		return &o.FoldCase
+	case "idn":
+		return &o.IDN
+	case "gmail-dots":
+		return &o.GmailDots
+	case "plus-tags":
+		return &o.PlusTags
+	}
+	return nil
+}
+
+// Normalizer turns raw email cells into keys used for deduplication and matching
+type Normalizer struct {
+	rules []Rule
+}
+
+// New creates a normalizer applying the enabled rules in a fixed order
+func New(opts Options) *Normalizer {
+	n := &Normalizer{}
+	if opts.Trim {
+		n.Add(Trim)
+	}
+	if opts.StripDisplayName {
+		n.Add(StripDisplayName)
+	}
+	if opts.Unicode {
+		n.Add(NFC)
+	}
+	if opts.FoldCase {
+		n.Add(FoldCase)
+	}
+	if opts.IDN {
+		n.Add(IDN)
+	}
+	if opts.GmailDots {
+		n.Add(GmailDots)
+	}
+	if opts.PlusTags {
+		n.Add(PlusTags)
+	}
+	return n
+}
+
+// Add appends a custom rule to the pipeline
+func (n *Normalizer) Add(rule Rule) {
+	n.rules = append(n.rules, rule)
+}
+
+// Normalize runs the address through every rule
+func (n *Normalizer) Normalize(email string) string {
+	for _, rule := range n.rules {
+		email = rule(email)
+	}
+	return email
+}
+
+// Trim removes whitespace, surrounding quotes and a mailto: prefix
+func Trim(email string) string {
+	email = strings.TrimSpace(email)
+	for len(email) >= 2 && (email[0] == '"' && email[len(email)-1] == '"' || email[0] == '\'' && email[len(email)-1] == '\'') {
+		email = strings.TrimSpace(email[1 : len(email)-1])
+	}
+	if len(email) > 7 && strings.EqualFold(email[:7], "mailto:") {
+		email = email[7:]
+	}
+	return email
+}
+
+// StripDisplayName keeps only the address of `Name <address>` forms
+func StripDisplayName(email string) string {
+	end := strings.LastIndex(email, ">")
+	if end == -1 {
+		return email
+	}
+	start := strings.LastIndex(email[:end], "<")
+	if start == -1 {
+		return email
+	}
+	return strings.TrimSpace(email[start+1 : end])
+}
+
+// NFC composes the address into Unicode normalization form C
+func NFC(email string) string {
+	return norm.NFC.String(email)
+}
+
+// FoldCase lower-cases the whole address
+func FoldCase(email string) string {
+	return strings.ToLower(email)
+}
+
+// IDN converts the domain to its ASCII form so that münchen.de and
+// xn--mnchen-3ya.de compare equal. Invalid domains are left untouched.
+func IDN(email string) string {
+	local, domain, ok := split(email)
+	if !ok {
+		return email
+	}
+	ascii, err := idna.Lookup.ToASCII(domain)
+	if err != nil {
+		return email
+	}
+	return local + "@" + ascii
+}
+
+// gmailDomains all deliver to the same Gmail mailbox
+var gmailDomains = map[string]bool{
+	"gmail.com":      true,
+	"googlemail.com": true,
+}
+
+// plusTagDomains are providers where local+tag@domain reaches local@domain
+var plusTagDomains = map[string]bool{
+	"gmail.com":      true,
+	"googlemail.com": true,
+	"outlook.com":    true,
+	"hotmail.com":    true,
+	"live.com":       true,
+	"msn.com":        true,
+	"icloud.com":     true,
+	"me.com":         true,
+	"mac.com":        true,
+	"fastmail.com":   true,
+	"fastmail.fm":    true,
+	"protonmail.com": true,
+	"proton.me":      true,
+	"pm.me":          true,
+}
+
+// GmailDots removes dots from Gmail local parts and folds googlemail.com into gmail.com
+func GmailDots(email string) string {
+	local, domain, ok := split(email)
+	if !ok || !gmailDomains[strings.ToLower(domain)] {
+		return email
+	}
+	return strings.ReplaceAll(local, ".", "") + "@gmail.com"
+}
+
+// PlusTags drops the +tag suffix of the local part for providers that support it
+func PlusTags(email string) string {
+	local, domain, ok := split(email)
+	if !ok || !plusTagDomains[strings.ToLower(domain)] {
+		return email
+	}
+	if i := strings.Index(local, "+"); i > 0 {
+		local = local[:i]
+	}
+	return local + "@" + domain
+}
+
+// split separates the local part and domain at the last @
+func split(email string) (string, string, bool) {
+	at := strings.LastIndex(email, "@")
+	if at <= 0 || at == len(email)-1 {
+		return "", "", false
+	}
+	return email[:at], email[at+1:], true
+}
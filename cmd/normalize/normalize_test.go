@@ -0,0 +1,56 @@
+package normalize
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	all := DefaultOptions()
+	all.GmailDots, all.PlusTags = true, true
+
+	tests := []struct {
+		name  string
+		opts  Options
+		email string
+		want  string
+	}{
+		{"defaults", DefaultOptions(), "  Alice@Example.COM ", "alice@example.com"},
+		{"quotes", DefaultOptions(), `"'bob@example.com'"`, "bob@example.com"},
+		{"mailto", DefaultOptions(), "MAILTO:bob@example.com", "bob@example.com"},
+		{"display name", DefaultOptions(), `"Bob, Jr" <Bob@Example.com>`, "bob@example.com"},
+		{"nfc", DefaultOptions(), "jose\u0301@example.com", "jos\u00e9@example.com"},
+		{"idn", DefaultOptions(), "info@München.de", "info@xn--mnchen-3ya.de"},
+		{"idn punycode", DefaultOptions(), "info@xn--mnchen-3ya.de", "info@xn--mnchen-3ya.de"},
+		{"invalid domain kept", DefaultOptions(), "a@b..com", "a@b..com"},
+		{"no domain", DefaultOptions(), "not an email", "not an email"},
+		{"gmail dots off", DefaultOptions(), "j.doe+news@gmail.com", "j.doe+news@gmail.com"},
+		{"gmail dots", all, "J.Doe@googlemail.com", "jdoe@gmail.com"},
+		{"plus tags", all, "j.doe+news@gmail.com", "jdoe@gmail.com"},
+		{"plus tags outlook", all, "sam+shop@outlook.com", "sam@outlook.com"},
+		{"plus tags other provider", all, "sam+shop@example.com", "sam+shop@example.com"},
+		{"dots other provider", all, "s.am@example.com", "s.am@example.com"},
+		{"leading plus kept", all, "+sam@gmail.com", "+sam@gmail.com"},
+		{"no rules", Options{}, " Alice@Example.COM ", " Alice@Example.COM "},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := New(tt.opts).Normalize(tt.email); got != tt.want {
+				t.Errorf("Normalize(%q) = %q, want %q", tt.email, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFromNames(t *testing.T) {
+	opts, err := FromNames([]string{"Trim", " case ", "", "plus-tags"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"trim", "case", "plus-tags"}; !reflect.DeepEqual(opts.Names(), want) {
+		t.Errorf("Names() = %q, want %q", opts.Names(), want)
+	}
+	if _, err := FromNames([]string{"soundex"}); err == nil {
+		t.Error("an unknown rule should be an error")
+	}
+}
@@ -0,0 +1,216 @@
+package pipeline
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+
+	"website-copier/cmd/dedupe"
+	"website-copier/cmd/domains"
+	"website-copier/cmd/normalize"
+	"website-copier/cmd/records"
+	"website-copier/cmd/typos"
+	"website-copier/cmd/utils"
+	"website-copier/cmd/validate"
+)
+
+// bufferSize is the capacity of the channels between stages
+const bufferSize = 1024
+
+// Config describes one run of the pipeline
+type Config struct {
+	Files    []string // Input files, in the order that decides ties and output order
+	Workers  int      // Files read at the same time; 0 means one per CPU
+	Reader   records.ReaderOptions
+	Validate validate.Options
+	// Quarantine is called with each record set aside by validation as it
+	// comes, from a single goroutine; nothing is kept when it is nil
+	Quarantine func(validate.Quarantined)
+	Typos      *typos.Corrector    // Corrects or flags misspelt domains when set
+	Domains    *domains.Classifier // Adds the domain category column when set
+	Normalize  normalize.Options
+	Dedupe     dedupe.Options
+}
+
+// Item is a record travelling through the pipeline with its dedup key
+type Item struct {
+	Key    string
+	Record records.Record
+}
+
+// Output is what reaches the writer once every record is in
+type Output struct {
+	Records []records.Record   // Merged records in file order
+	Sources []*records.Columns // Header layouts of the input sheets in file order
+	Read    int                // Records read before merging duplicates
+	// Validation holds the counts of the email statuses; nil when validation is off
+	Validation *validate.Validator
+	// Fuzzy holds the fuzzy duplicate decisions; nil when fuzzy matching is off
+	Fuzzy *dedupe.FuzzyResult
+}
+
+// Run streams the files through the readers, the validator, the typo
+// corrector, the domain classifier, the normalizer and the deduper, waits for every stage to finish
+// and hands the result to write.
+// The output does not depend on the order the files finish in.
+func Run(cfg Config, write func(Output) error) error {
+	read := Read(cfg.Files, cfg.Workers, cfg.Reader)
+	var validator *validate.Validator
+	if cfg.Validate.Enabled {
+		validator = validate.New(cfg.Validate)
+		read = Validate(read, validator, cfg.Quarantine)
+	}
+	if cfg.Typos != nil {
+		read = Correct(read, cfg.Typos)
+	}
+	if cfg.Domains != nil {
+		read = Categorize(read, cfg.Domains)
+	}
+	keyed := Normalize(read, normalize.New(cfg.Normalize))
+	output := <-Dedupe(keyed, cfg.Files, cfg.Dedupe)
+	// The validator is done once the deduper has drained its output
+	output.Validation = validator
+	return write(output)
+}
+
+// Read streams the records of the files, reading at most workers files at
+// once. The channel is closed once every file has been read.
+func Read(files []string, workers int, opts records.ReaderOptions) <-chan records.Record {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	out := make(chan records.Record, bufferSize)
+	slots := make(chan struct{}, workers)
+
+	go func() {
+		var wg sync.WaitGroup
+		for _, file := range files {
+			wg.Add(1)
+			slots <- struct{}{}
+			go func(file string) {
+				defer func() {
+					<-slots
+					wg.Done()
+				}()
+				readFile(file, opts, out)
+			}(file)
+		}
+		// Wait for all file processing to complete
+		wg.Wait()
+		close(out)
+	}()
+	return out
+}
+
+// openReader opens the records of a file; the tests wrap it to watch the readers
+var openReader = records.OpenRecordReader
+
+func readFile(file string, opts records.ReaderOptions, out chan<- records.Record) {
+	utils.LogMessage(fmt.Sprintf("Processing file: %s", file))
+	reader, err := openReader(file, opts)
+	if err != nil {
+		utils.LogMessage(fmt.Sprintf("Error opening file: %s - %v", file, err))
+		return
+	}
+	defer reader.Close()
+
+	for reader.Next() {
+		out <- reader.Record()
+	}
+	if err := reader.Err(); err != nil {
+		utils.LogMessage(fmt.Sprintf("Error reading file: %s - %v, skipping...", file, err))
+	}
+}
+
+// Validate passes on the records with a usable email and leaves the others
+// handed to quarantine, when set
+func Validate(in <-chan records.Record, validator *validate.Validator, quarantine func(validate.Quarantined)) <-chan records.Record {
+	out := make(chan records.Record, bufferSize)
+	go func() {
+		defer close(out)
+		for record := range in {
+			keep, quarantined := validator.Check(record)
+			if quarantine != nil {
+				for _, q := range quarantined {
+					quarantine(q)
+				}
+			}
+			for _, record := range keep {
+				out <- record
+			}
+		}
+	}()
+	return out
+}
+
+// Correct fixes or flags the records whose domain looks like a typo of a known
+// domain, before the domain is classified or used as a dedup key
+func Correct(in <-chan records.Record, corrector *typos.Corrector) <-chan records.Record {
+	out := make(chan records.Record, bufferSize)
+	go func() {
+		defer close(out)
+		for record := range in {
+			out <- corrector.Correct(record)
+		}
+	}()
+	return out
+}
+
+// Categorize sets the domain category column of every record
+func Categorize(in <-chan records.Record, classifier *domains.Classifier) <-chan records.Record {
+	out := make(chan records.Record, bufferSize)
+	go func() {
+		defer close(out)
+		for record := range in {
+			record.OthersMap[domains.Column] = classifier.Classify(record.Email)
+			out <- record
+		}
+	}()
+	return out
+}
+
+// Normalize keys every record by its normalized email
+func Normalize(in <-chan records.Record, normalizer *normalize.Normalizer) <-chan Item {
+	out := make(chan Item, bufferSize)
+	go func() {
+		defer close(out)
+		for record := range in {
+			out <- Item{Key: normalizer.Normalize(record.Email), Record: record}
+		}
+	}()
+	return out
+}
+
+// Dedupe collects every item and merges the duplicates once the input is
+// closed, then looks for fuzzy duplicates when asked to. The single Output is sent only after the whole input was drained.
+func Dedupe(in <-chan Item, files []string, opts dedupe.Options) <-chan Output {
+	out := make(chan Output, 1)
+	go func() {
+		defer close(out)
+		set := dedupe.New(opts, files)
+		// Header layouts seen in each file, used to build the unified output schema
+		sources := make(map[string][]*records.Columns)
+		seen := make(map[*records.Columns]bool)
+		read := 0
+		for item := range in {
+			read++
+			if cols := item.Record.Columns; cols != nil && !seen[cols] {
+				seen[cols] = true
+				sources[item.Record.FilePath] = append(sources[item.Record.FilePath], cols)
+			}
+			set.Add(item.Key, item.Record)
+		}
+
+		output := Output{Records: set.Resolve(), Read: read}
+		if opts.Fuzzy.Enabled {
+			fuzzy := set.Fuzzy(output.Records, opts.Fuzzy)
+			output.Records = fuzzy.Records
+			output.Fuzzy = &fuzzy
+		}
+		for _, file := range files {
+			output.Sources = append(output.Sources, sources[file]...)
+		}
+		out <- output
+	}()
+	return out
+}
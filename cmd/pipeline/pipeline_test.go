@@ -0,0 +1,162 @@
+package pipeline
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"website-copier/cmd/dedupe"
+	"website-copier/cmd/normalize"
+	"website-copier/cmd/records"
+)
+
+// watchReaders wraps openReader to count the files open at the same time and
+// returns the most seen at once
+func watchReaders(t *testing.T) func() int {
+	t.Helper()
+	var mu sync.Mutex
+	open, most := 0, 0
+	opener := openReader
+	openReader = func(file string, opts records.ReaderOptions) (records.RecordReader, error) {
+		reader, err := opener(file, opts)
+		if err != nil {
+			return nil, err
+		}
+		mu.Lock()
+		open++
+		if open > most {
+			most = open
+		}
+		mu.Unlock()
+		return &watchedReader{RecordReader: reader, close: func() {
+			mu.Lock()
+			open--
+			mu.Unlock()
+		}}, nil
+	}
+	t.Cleanup(func() { openReader = opener })
+	return func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return most
+	}
+}
+
+type watchedReader struct {
+	records.RecordReader
+	close func()
+}
+
+// Next is slowed down so the workers overlap
+func (r *watchedReader) Next() bool {
+	time.Sleep(100 * time.Microsecond)
+	return r.RecordReader.Next()
+}
+
+func (r *watchedReader) Close() error {
+	r.close()
+	return r.RecordReader.Close()
+}
+
+// writeInputs writes files that each hold their own addresses and one address
+// shared by all of them, in a different case each time
+func writeInputs(t *testing.T, count, rows int) []string {
+	t.Helper()
+	dir := t.TempDir()
+	var files []string
+	for f := 0; f < count; f++ {
+		var b strings.Builder
+		b.WriteString("Name,Email,Phone\n")
+		fmt.Fprintf(&b, "Shared %d,Shared@Example.COM,\n", f)
+		for r := 0; r < rows; r++ {
+			fmt.Fprintf(&b, "User %d-%d,user%d@file%d.com,555-%04d\n", f, r, r, f, r)
+		}
+		path := filepath.Join(dir, fmt.Sprintf("input%d.csv", f))
+		if err := os.WriteFile(path, []byte(b.String()), 0644); err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, path)
+	}
+	return files
+}
+
+func run(t *testing.T, files []string, workers int) Output {
+	t.Helper()
+	cfg := Config{
+		Files:     files,
+		Workers:   workers,
+		Normalize: normalize.DefaultOptions(),
+		Dedupe:    dedupe.DefaultOptions(),
+	}
+	var output Output
+	if err := Run(cfg, func(out Output) error {
+		output = out
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return output
+}
+
+func TestRunBoundedWorkers(t *testing.T) {
+	files := writeInputs(t, 8, 20)
+	for _, workers := range []int{1, 2, 3} {
+		t.Run(fmt.Sprintf("%d workers", workers), func(t *testing.T) {
+			most := watchReaders(t)
+			run(t, files, workers)
+			if got := most(); got > workers {
+				t.Errorf("%d files were open at once, want at most %d", got, workers)
+			}
+		})
+	}
+}
+
+func TestRunOutput(t *testing.T) {
+	const count, rows = 6, 30
+	files := writeInputs(t, count, rows)
+
+	want := run(t, files, 1)
+	if want.Read != count*(rows+1) {
+		t.Errorf("Read = %d, want %d", want.Read, count*(rows+1))
+	}
+	// The shared address is merged into one record, the first file's
+	if len(want.Records) != count*rows+1 {
+		t.Fatalf("got %d records, want %d", len(want.Records), count*rows+1)
+	}
+	if first := want.Records[0]; first.Name != "Shared 0" || first.FilePath != files[0] {
+		t.Errorf("first record = %q from %s, want Shared 0 from %s", first.Name, first.FilePath, files[0])
+	}
+	// Records follow the file order, then the row order
+	for i := 1; i < len(want.Records); i++ {
+		f := (i - 1) / rows
+		if got, wantName := want.Records[i].Name, fmt.Sprintf("User %d-%d", f, (i-1)%rows); got != wantName {
+			t.Fatalf("record %d = %q, want %q", i, got, wantName)
+		}
+	}
+	if len(want.Sources) != count {
+		t.Errorf("got %d source layouts, want %d", len(want.Sources), count)
+	}
+
+	// The files finish in any order with more workers, the output does not change
+	for _, workers := range []int{2, 4, 8} {
+		for i := 0; i < 3; i++ {
+			got := run(t, files, workers)
+			if got.Read != want.Read || !reflect.DeepEqual(names(got.Records), names(want.Records)) {
+				t.Fatalf("%d workers: output differs from one worker", workers)
+			}
+		}
+	}
+}
+
+func names(list []records.Record) []string {
+	var out []string
+	for _, r := range list {
+		out = append(out, r.FilePath+": "+r.Name+" <"+r.Email+">")
+	}
+	return out
+}
@@ -0,0 +1,129 @@
+package provenance
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"website-copier/cmd/records"
+)
+
+// Names of the provenance columns for the CLI
+const (
+	File     = "file"     // File the record was read from
+	Sheet    = "sheet"    // Sheet of the workbook, blank for CSV files
+	Row      = "row"      // Row number in the file or sheet
+	Imported = "imported" // When the job ran
+	Sources  = "sources"  // Every file and sheet merged into the record
+)
+
+// Column names a provenance column for the CLI and heads it in the output
+type Column struct {
+	Name   string
+	Header string
+}
+
+// Columns lists every provenance column in output order
+var Columns = []Column{
+	{File, "Source File"},
+	{Sheet, "Source Sheet"},
+	{Row, "Source Row"},
+	{Imported, "Imported At"},
+	{Sources, "All Source Files"},
+}
+
+// Names lists the names of every provenance column
+var Names = []string{File, Sheet, Row, Imported, Sources}
+
+// RecordNames lists the columns describing a single record, for jobs that
+// don't merge duplicates
+var RecordNames = []string{File, Sheet, Row, Imported}
+
+// TimeLayout formats the import timestamp
+const TimeLayout = "2006-01-02 15:04:05"
+
+// Options picks the provenance columns added to the output of a job
+type Options struct {
+	Columns []string  // Names of the columns; empty adds none
+	Time    time.Time // Import timestamp, set when the job starts if zero
+}
+
+// Parse checks a list of column names against the allowed ones; "all" stands
+// for every allowed column
+func Parse(names, allowed []string) ([]string, error) {
+	var columns []string
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if name == "all" {
+			return allowed, nil
+		}
+		known := false
+		for _, a := range allowed {
+			known = known || a == name
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown provenance column: %s (expected all or any of %s)", name, strings.Join(allowed, ", "))
+		}
+		columns = append(columns, name)
+	}
+	return columns, nil
+}
+
+// Header returns the output header of a provenance column
+func Header(name string) string {
+	for _, column := range Columns {
+		if column.Name == name {
+			return column.Header
+		}
+	}
+	return ""
+}
+
+// Enabled reports whether any column is added
+func (o Options) Enabled() bool {
+	return len(o.Columns) > 0
+}
+
+// Headers returns the headers of the columns added, in output order
+func (o Options) Headers() []string {
+	var headers []string
+	for _, column := range Columns {
+		for _, name := range o.Columns {
+			if name == column.Name {
+				headers = append(headers, column.Header)
+				break
+			}
+		}
+	}
+	return headers
+}
+
+// Stamp fills the provenance columns of a record
+func (o Options) Stamp(r records.Record) {
+	for _, name := range o.Columns {
+		var value string
+		switch name {
+		case File:
+			value = r.FilePath
+		case Sheet:
+			value = r.Sheet
+		case Row:
+			if r.Row > 0 {
+				value = strconv.Itoa(r.Row)
+			}
+		case Imported:
+			value = o.Time.Format(TimeLayout)
+		case Sources:
+			sources := r.Sources
+			if len(sources) == 0 {
+				sources = []string{r.Source()}
+			}
+			value = strings.Join(sources, "; ")
+		}
+		r.OthersMap[Header(name)] = value
+	}
+}
@@ -0,0 +1,89 @@
+package provenance
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"website-copier/cmd/records"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		names   []string
+		allowed []string
+		want    []string
+		ok      bool
+	}{
+		{[]string{" File ", "row", ""}, Names, []string{File, Row}, true},
+		{[]string{"all"}, RecordNames, RecordNames, true},
+		{nil, Names, nil, true},
+		{[]string{"sources"}, RecordNames, nil, false},
+		{[]string{"file", "origin"}, Names, nil, false},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.names, tt.allowed)
+		if (err == nil) != tt.ok || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Parse(%q) = %q, %v, want %q and ok %v", tt.names, got, err, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestStamp(t *testing.T) {
+	imported := time.Date(2026, 3, 4, 5, 6, 7, 0, time.Local)
+	// Columns are written in output order whatever order they are picked in
+	opts := Options{Columns: []string{Sources, Row, File, Imported, Sheet}, Time: imported}
+	wantHeaders := []string{"Source File", "Source Sheet", "Source Row", "Imported At", "All Source Files"}
+	if got := opts.Headers(); !reflect.DeepEqual(got, wantHeaders) {
+		t.Errorf("Headers() = %q, want %q", got, wantHeaders)
+	}
+
+	tests := []struct {
+		name   string
+		record records.Record
+		want   map[string]string
+	}{
+		{
+			name:   "csv row",
+			record: records.Record{FilePath: "/in/crm.csv", Row: 7},
+			want: map[string]string{
+				"Source File": "/in/crm.csv", "Source Sheet": "", "Source Row": "7",
+				"Imported At": "2026-03-04 05:06:07", "All Source Files": "/in/crm.csv",
+			},
+		},
+		{
+			name:   "workbook sheet",
+			record: records.Record{FilePath: "/in/events.xlsx", Sheet: "Leads", Row: 2},
+			want: map[string]string{
+				"Source File": "/in/events.xlsx", "Source Sheet": "Leads", "Source Row": "2",
+				"Imported At": "2026-03-04 05:06:07", "All Source Files": "/in/events.xlsx:Leads",
+			},
+		},
+		{
+			name: "merged duplicates",
+			record: records.Record{FilePath: "/in/web.csv", Row: 3,
+				Sources: []string{"/in/crm.csv", "/in/events.xlsx:Leads", "/in/web.csv"}},
+			want: map[string]string{
+				"Source File": "/in/web.csv", "Source Sheet": "", "Source Row": "3",
+				"Imported At": "2026-03-04 05:06:07", "All Source Files": "/in/crm.csv; /in/events.xlsx:Leads; /in/web.csv",
+			},
+		},
+		{
+			name:   "no row",
+			record: records.Record{FilePath: "/in/crm.csv"},
+			want: map[string]string{
+				"Source File": "/in/crm.csv", "Source Sheet": "", "Source Row": "",
+				"Imported At": "2026-03-04 05:06:07", "All Source Files": "/in/crm.csv",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.record.OthersMap = make(map[string]string)
+			opts.Stamp(tt.record)
+			if !reflect.DeepEqual(tt.record.OthersMap, tt.want) {
+				t.Errorf("Stamp() = %q, want %q", tt.record.OthersMap, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,366 @@
+package records
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Column roles a header can be mapped to
+const (
+	RoleName    = "Name"
+	RoleEmail   = "Email"
+	RoleOrgName = "OrgName"
+)
+
+// roles in the order they are reported
+var roles = []string{RoleName, RoleEmail, RoleOrgName}
+
+// DefaultAliases lists the header names recognised for each role. Matching
+// ignores case, surrounding spaces and the separators _ - .
+var DefaultAliases = map[string][]string{
+	RoleEmail: {
+		"email", "e mail", "email address", "e mail address", "mail", "emailaddress",
+		"work email", "business email", "primary email", "contact email", "personal email",
+	},
+	RoleName: {
+		"name", "full name", "fullname", "contact name", "contact", "person", "person name",
+		"customer name", "display name",
+	},
+	RoleOrgName: {
+		"organization", "organisation", "org", "orgname", "org name", "organization name",
+		"organisation name", "company", "company name", "companyname", "employer",
+		"account name", "business name",
+	},
+}
+
+// excludedWords disqualify a header from a role when it only matches partially,
+// e.g. "Email Opt-in" is not an address and "Username" is not a person's name
+var excludedWords = map[string][]string{
+	RoleEmail:   {"opt", "optin", "opted", "consent", "subscribed", "unsubscribed", "status", "verified", "valid", "bounce", "bounced", "type", "format", "date", "count", "sent", "open", "opens", "domain"},
+	RoleName:    {"user", "username", "file", "filename", "domain", "host", "company", "org", "organization", "organisation", "account", "business", "sheet", "product", "campaign", "email", "mail", "phone", "address", "id", "number"},
+	RoleOrgName: {"id", "type", "size", "code"},
+}
+
+// Scores for the different kinds of matches, higher wins
+const (
+	scorePrimary   = 100 // The first, canonical alias of a role
+	scoreExact     = 90
+	scoreTokens    = 60
+	scoreSubstring = 20
+)
+
+// ColumnMapping tells the readers which header holds which field. Headers
+// that are not overridden are picked by scoring them against the aliases.
+type ColumnMapping struct {
+	Columns map[string]string            `json:"columns,omitempty"` // role -> header, for every file
+	Files   map[string]map[string]string `json:"files,omitempty"`   // file path or base name, or file:sheet -> role -> header
+	Aliases map[string][]string          `json:"aliases,omitempty"` // extra aliases per role
+
+	// CSV overrides the detected delimiter, quote and encoding of every CSV file
+	CSV      Dialect            `json:"csv,omitempty"`
+	CSVFiles map[string]Dialect `json:"csv_files,omitempty"` // file path or base name -> CSV overrides
+
+	// HeaderRow is the 1-based row holding the headers of every sheet; 0 detects it
+	HeaderRow      int            `json:"header_row,omitempty"`
+	HeaderRowFiles map[string]int `json:"header_row_files,omitempty"` // file path or base name, or file:sheet -> header row
+
+	// Sheets limits workbooks to some of their sheets and SkipSheets leaves sheets
+	// out, each entry a sheet name for every workbook or file:sheet for one
+	Sheets     []string `json:"sheets,omitempty"`
+	SkipSheets []string `json:"skip_sheets,omitempty"`
+}
+
+// Columns is the result of mapping a header row
+type Columns struct {
+	Headers  []string
+	Name     int // -1 when missing
+	Email    int // -1 when missing
+	OrgName  int // -1 when missing
+	Warnings []string
+	Score    int // How well the headers matched, used to find the header row
+}
+
+// Index returns the column index of a role, or -1
+func (c *Columns) Index(role string) int {
+	switch role {
+	case RoleName:
+		return c.Name
+	case RoleEmail:
+		return c.Email
+	case RoleOrgName:
+		return c.OrgName
+	}
+	return -1
+}
+
+// Header returns the header mapped to a role, or an empty string
+func (c *Columns) Header(role string) string {
+	if i := c.Index(role); i >= 0 && i < len(c.Headers) {
+		return c.Headers[i]
+	}
+	return ""
+}
+
+func (c *Columns) set(role string, index int) {
+	switch role {
+	case RoleName:
+		c.Name = index
+	case RoleEmail:
+		c.Email = index
+	case RoleOrgName:
+		c.OrgName = index
+	}
+}
+
+// ParseRole accepts a role name in any case, plus a few common spellings
+func ParseRole(s string) (string, bool) {
+	switch canonicalHeader(s) {
+	case "name":
+		return RoleName, true
+	case "email", "e mail":
+		return RoleEmail, true
+	case "orgname", "org name", "org", "organization", "organisation", "company":
+		return RoleOrgName, true
+	}
+	return "", false
+}
+
+// LoadColumnMapping reads a column mapping from a JSON file
+func LoadColumnMapping(filename string) (ColumnMapping, error) {
+	var mapping ColumnMapping
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return mapping, err
+	}
+	if err := json.Unmarshal(data, &mapping); err != nil {
+		return mapping, fmt.Errorf("invalid column mapping file %s: %v", filename, err)
+	}
+	return mapping, nil
+}
+
+// SetOverride pins a role to a header for one file or sheet key, or for every file when file is empty
+func (m *ColumnMapping) SetOverride(file, role, header string) {
+	if file == "" {
+		if m.Columns == nil {
+			m.Columns = make(map[string]string)
+		}
+		m.Columns[role] = header
+		return
+	}
+	if m.Files == nil {
+		m.Files = make(map[string]map[string]string)
+	}
+	if m.Files[file] == nil {
+		m.Files[file] = make(map[string]string)
+	}
+	m.Files[file][role] = header
+}
+
+// overrides returns the pinned headers for a file or sheet key, per-sheet entries
+// winning over per-file ones, which win over global ones
+func (m ColumnMapping) overrides(key string) map[string]string {
+	result := make(map[string]string)
+	add := func(pinned map[string]string) {
+		for role, header := range pinned {
+			if r, ok := ParseRole(role); ok {
+				result[r] = header
+			}
+		}
+	}
+	file, sheet := SplitSheet(key)
+	add(m.Columns)
+	add(m.Files[filepath.Base(file)])
+	add(m.Files[file])
+	if sheet != "" {
+		add(m.Files[SheetKey(filepath.Base(file), sheet)])
+		add(m.Files[key])
+	}
+	return result
+}
+
+// Dialect returns the CSV overrides for a file, per-file entries winning over global ones
+func (m ColumnMapping) Dialect(file string) Dialect {
+	return m.CSV.Merge(m.CSVFiles[filepath.Base(file)]).Merge(m.CSVFiles[file])
+}
+
+// SetHeaderRow sets the header row of a file, 0 going back to detecting it
+func (m *ColumnMapping) SetHeaderRow(file string, row int) {
+	if row == 0 {
+		delete(m.HeaderRowFiles, file)
+		return
+	}
+	if m.HeaderRowFiles == nil {
+		m.HeaderRowFiles = make(map[string]int)
+	}
+	m.HeaderRowFiles[file] = row
+}
+
+// FileHeaderRow returns the header row of a file or sheet key, or 0 to detect it
+func (m ColumnMapping) FileHeaderRow(key string) int {
+	file, sheet := SplitSheet(key)
+	keys := []string{file, filepath.Base(file)}
+	if sheet != "" {
+		keys = append([]string{key, SheetKey(filepath.Base(file), sheet)}, keys...)
+	}
+	for _, k := range keys {
+		if row, ok := m.HeaderRowFiles[k]; ok {
+			return row
+		}
+	}
+	return m.HeaderRow
+}
+
+// ResolveColumns maps the headers of a file to the Name, Email and OrgName roles.
+// Exact alias matches beat whole-word matches, which beat substring matches;
+// each header is used for at most one role. Ties are reported as warnings.
+func (m ColumnMapping) ResolveColumns(file string, headers []string) Columns {
+	cols := Columns{Headers: headers, Name: -1, Email: -1, OrgName: -1}
+	used := make(map[int]bool)
+
+	// Explicit overrides first
+	pinned := m.overrides(file)
+	for _, role := range roles {
+		header, ok := pinned[role]
+		if !ok {
+			continue
+		}
+		index := -1
+		for i, h := range headers {
+			if strings.EqualFold(strings.TrimSpace(h), strings.TrimSpace(header)) {
+				index = i
+				break
+			}
+		}
+		if index == -1 {
+			cols.Warnings = append(cols.Warnings, fmt.Sprintf("mapped %s column %q not found", role, header))
+			continue
+		}
+		cols.set(role, index)
+		cols.Score += scorePrimary
+		used[index] = true
+	}
+
+	// Score every remaining role/header pair
+	type candidate struct {
+		role   string
+		index  int
+		score  int
+		header string
+	}
+	var candidates []candidate
+	for _, role := range roles {
+		if cols.Index(role) != -1 {
+			continue
+		}
+		aliases := append([]string(nil), DefaultAliases[role]...)
+		for r, extra := range m.Aliases {
+			if parsed, ok := ParseRole(r); ok && parsed == role {
+				aliases = append(aliases, extra...)
+			}
+		}
+		for i, header := range headers {
+			if used[i] {
+				continue
+			}
+			if score := scoreHeader(role, header, aliases); score > 0 {
+				candidates = append(candidates, candidate{role, i, score, header})
+			}
+		}
+	}
+	sort.SliceStable(candidates, func(a, b int) bool {
+		return candidates[a].score > candidates[b].score
+	})
+
+	// Assign greedily, best score first
+	for n, c := range candidates {
+		if cols.Index(c.role) != -1 || used[c.index] {
+			continue
+		}
+		cols.set(c.role, c.index)
+		cols.Score += c.score
+		used[c.index] = true
+
+		// Report other free headers that scored just as well for the same role
+		var rivals []string
+		for _, other := range candidates[n+1:] {
+			if other.role == c.role && other.score == c.score && !used[other.index] {
+				rivals = append(rivals, fmt.Sprintf("%q", other.header))
+			}
+		}
+		if len(rivals) > 0 {
+			cols.Warnings = append(cols.Warnings, fmt.Sprintf("ambiguous %s column: %q chosen over %s", c.role, c.header, strings.Join(rivals, ", ")))
+		}
+	}
+	return cols
+}
+
+// scoreHeader rates how well a header matches one of the aliases of a role
+func scoreHeader(role, header string, aliases []string) int {
+	h := canonicalHeader(header)
+	if h == "" {
+		return 0
+	}
+	for i, alias := range aliases {
+		if h == canonicalHeader(alias) {
+			if i == 0 {
+				return scorePrimary
+			}
+			return scoreExact
+		}
+	}
+
+	words := strings.Fields(h)
+	for _, word := range words {
+		for _, excluded := range excludedWords[role] {
+			if word == excluded {
+				return 0
+			}
+		}
+	}
+
+	best := 0
+	for _, alias := range aliases {
+		a := canonicalHeader(alias)
+		if containsWords(words, strings.Fields(a)) {
+			// Fewer extra words means a closer match
+			score := scoreTokens - 5*(len(words)-len(strings.Fields(a)))
+			if score < scoreSubstring+1 {
+				score = scoreSubstring + 1
+			}
+			if score > best {
+				best = score
+			}
+		} else if best < scoreSubstring && !strings.Contains(a, " ") && strings.Contains(strings.ReplaceAll(h, " ", ""), a) {
+			best = scoreSubstring
+		}
+	}
+	return best
+}
+
+// canonicalHeader lower-cases a header and turns separators into single spaces
+func canonicalHeader(header string) string {
+	header = strings.ToLower(strings.TrimSpace(strings.Trim(header, `"`)))
+	header = strings.NewReplacer("_", " ", "-", " ", ".", " ", "/", " ", ":", " ", "(", " ", ")", " ").Replace(header)
+	return strings.Join(strings.Fields(header), " ")
+}
+
+// containsWords reports whether the alias words appear consecutively in the header words
+func containsWords(words, alias []string) bool {
+	for i := 0; i+len(alias) <= len(words); i++ {
+		match := true
+		for j := range alias {
+			if words[i+j] != alias[j] {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
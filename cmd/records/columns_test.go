@@ -0,0 +1,66 @@
+package records
+
+import "testing"
+
+func TestScoreHeader(t *testing.T) {
+	tests := []struct {
+		role   string
+		header string
+		want   int
+	}{
+		{RoleEmail, "Email", scorePrimary},
+		{RoleEmail, " E-Mail ", scoreExact},
+		{RoleEmail, "email_address", scoreExact},
+		{RoleEmail, "Work Email", scoreExact},
+		{RoleEmail, "Secondary Email", scoreTokens - 5},
+		{RoleEmail, "Email Opt-in", 0},
+		{RoleEmail, "Email Status", 0},
+		{RoleEmail, "Domain", 0},
+		{RoleEmail, "EmailAddr", scoreSubstring},
+		{RoleName, "Name", scorePrimary},
+		{RoleName, "Full Name", scoreExact},
+		{RoleName, "Username", 0},
+		{RoleName, "File Name", 0},
+		{RoleName, "Company Name", 0},
+		{RoleOrgName, "Company", scoreExact},
+		{RoleOrgName, "Company ID", 0},
+		{RoleOrgName, "", 0},
+	}
+	for _, tt := range tests {
+		if got := scoreHeader(tt.role, tt.header, DefaultAliases[tt.role]); got != tt.want {
+			t.Errorf("scoreHeader(%s, %q) = %d, want %d", tt.role, tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestResolveColumns(t *testing.T) {
+	tests := []struct {
+		name     string
+		mapping  ColumnMapping
+		headers  []string
+		want     [3]int // Name, Email and OrgName columns
+		warnings int
+	}{
+		{"exact", ColumnMapping{}, []string{"Name", "Email", "Company"}, [3]int{0, 1, 2}, 0},
+		{"any order", ColumnMapping{}, []string{"Company Name", "E-mail Address", "Contact Name"}, [3]int{2, 1, 0}, 0},
+		{"opt-in is not the address", ColumnMapping{}, []string{"Email Opt-in", "Full Name", "Email"}, [3]int{1, 2, -1}, 0},
+		{"exact beats partial", ColumnMapping{}, []string{"Secondary Email", "Email Address"}, [3]int{-1, 1, -1}, 0},
+		{"ambiguous", ColumnMapping{}, []string{"Work Email", "Personal Email", "Name"}, [3]int{2, 0, -1}, 1},
+		{"missing", ColumnMapping{}, []string{"Phone", "City"}, [3]int{-1, -1, -1}, 0},
+		{"override", ColumnMapping{Columns: map[string]string{RoleEmail: "Personal Email"}}, []string{"Work Email", "personal email"}, [3]int{-1, 1, -1}, 0},
+		{"override not found", ColumnMapping{Columns: map[string]string{RoleEmail: "Mail 2"}}, []string{"Email"}, [3]int{-1, 0, -1}, 1},
+		{"file override", ColumnMapping{Files: map[string]map[string]string{"leads.csv": {"name": "Lead"}}}, []string{"Lead", "Email"}, [3]int{0, 1, -1}, 0},
+		{"extra alias", ColumnMapping{Aliases: map[string][]string{"org": {"Firma"}}}, []string{"Firma", "Email"}, [3]int{-1, 1, 0}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cols := tt.mapping.ResolveColumns("/data/leads.csv", tt.headers)
+			if got := [3]int{cols.Name, cols.Email, cols.OrgName}; got != tt.want {
+				t.Errorf("Name, Email, OrgName = %v, want %v", got, tt.want)
+			}
+			if len(cols.Warnings) != tt.warnings {
+				t.Errorf("warnings = %q, want %d", cols.Warnings, tt.warnings)
+			}
+		})
+	}
+}
@@ -0,0 +1,226 @@
+package records
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"website-copier/cmd/utils"
+)
+
+// DatabaseOptions picks the columns a database file's entries are read from
+type DatabaseOptions struct {
+	// Columns lists the headers to read on every sheet; empty means every email-like
+	// column, or the columns of suppression rules on sheets without one
+	Columns []string
+	// Mapping pins the Email column or adds aliases for it
+	Mapping ColumnMapping
+}
+
+// RuleHeaders are the headers of columns holding suppression rules such as
+// domains, patterns or hashed addresses rather than addresses, compared like aliases
+var RuleHeaders = []string{
+	"domain", "domains", "email domain", "email domains", "rule", "rules",
+	"pattern", "patterns", "regex", "suppression", "suppressions", "entry", "entries",
+	"hash", "hashes", "hashed email", "email hash", "md5", "sha1", "sha 1", "sha256", "sha 256",
+}
+
+// SheetHeaders is the header row of one sheet of a file
+type SheetHeaders struct {
+	Sheet   string
+	Headers []string
+}
+
+// HeaderOptions tells GetSheetHeaders how a file is read
+type HeaderOptions struct {
+	// Mapping holds the CSV dialect and the header row and column overrides
+	Mapping ColumnMapping
+	// Database finds the header row the way LoadDatabaseEntries does, above the
+	// address or rule columns (or the Columns given), instead of above the Name
+	// and Email columns records are read from
+	Database bool
+	Columns  []string
+}
+
+// GetSheetHeaders reads the header row of every sheet of a CSV, TSV or XLSX file,
+// found as when the file is read. CSV and TSV files have a single sheet with an empty name.
+func GetSheetHeaders(filename string, opts HeaderOptions) ([]SheetHeaders, error) {
+	source, err := openRows(filename, opts.Mapping.Dialect(filename))
+	if err != nil {
+		return nil, err
+	}
+	source = selectSheets(source, filename, opts.Mapping, true)
+	score := opts.Mapping.headerScore(filename)
+	if opts.Database {
+		score = DatabaseOptions{Columns: opts.Columns, Mapping: opts.Mapping}.headerScore(filename)
+	}
+	rows := &headerRows{rows: source, filename: filename, row: opts.Mapping.headerRow(filename), score: score, quiet: true}
+	defer rows.Close()
+
+	var sheets []SheetHeaders
+	for {
+		row, err := rows.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(sheets) > 0 && sheets[len(sheets)-1].Sheet == row.sheet {
+			continue
+		}
+		sheets = append(sheets, SheetHeaders{Sheet: row.sheet, Headers: sanitizeHeaders(row.cells)})
+		if row.sheet == "" {
+			// A CSV file has no more sheets
+			break
+		}
+		rows.skipSheet()
+	}
+	if len(sheets) == 0 {
+		return nil, fmt.Errorf("no headers found in file: %s", filename)
+	}
+	return sheets, nil
+}
+
+// EmailColumns returns the indexes of the email-like columns of a header row.
+// A pinned Email column is used on its own.
+func (m ColumnMapping) EmailColumns(file string, headers []string) []int {
+	if _, ok := m.overrides(file)[RoleEmail]; ok {
+		if cols := m.ResolveColumns(file, headers); cols.Email != -1 {
+			return []int{cols.Email}
+		}
+		return nil
+	}
+
+	aliases := append([]string(nil), DefaultAliases[RoleEmail]...)
+	for r, extra := range m.Aliases {
+		if parsed, ok := ParseRole(r); ok && parsed == RoleEmail {
+			aliases = append(aliases, extra...)
+		}
+	}
+	var indexes []int
+	for i, header := range headers {
+		if scoreHeader(RoleEmail, header, aliases) > 0 {
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes
+}
+
+// DatabaseColumns returns the indexes of the columns to read from a sheet
+func (opts DatabaseOptions) DatabaseColumns(file string, headers []string) []int {
+	var indexes []int
+	if len(opts.Columns) == 0 {
+		// Next to an email column, a Domain column describes the addresses rather
+		// than suppressing whole domains, so rules are only read from sheets without one
+		if indexes = opts.Mapping.EmailColumns(file, headers); len(indexes) > 0 {
+			return indexes
+		}
+		for i, header := range headers {
+			if isRuleHeader(header) {
+				indexes = append(indexes, i)
+			}
+		}
+		return indexes
+	}
+	for i, header := range headers {
+		for _, column := range opts.Columns {
+			if strings.EqualFold(strings.TrimSpace(header), strings.TrimSpace(column)) {
+				indexes = append(indexes, i)
+				break
+			}
+		}
+	}
+	return indexes
+}
+
+// headerScore rates a row as the header row of a database sheet
+func (opts DatabaseOptions) headerScore(file string) func(string, []string) int {
+	return func(sheet string, cells []string) int {
+		headers := sanitizeHeaders(append([]string(nil), cells...))
+		score := 0
+		for _, i := range opts.DatabaseColumns(SheetKey(file, sheet), headers) {
+			score += scoreHeader(RoleEmail, headers[i], DefaultAliases[RoleEmail])
+			if isRuleHeader(headers[i]) || len(opts.Columns) > 0 {
+				score += scoreExact
+			}
+		}
+		return score
+	}
+}
+
+func isRuleHeader(header string) bool {
+	h := canonicalHeader(header)
+	for _, alias := range RuleHeaders {
+		if h == alias {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadDatabaseEntries collects the entries (addresses, domains or patterns) of a
+// CSV, TSV or XLSX database file, reading the chosen columns of every sheet
+func LoadDatabaseEntries(filename string, opts DatabaseOptions) (map[string]bool, error) {
+	rows, err := openRows(filename, opts.Mapping.Dialect(filename))
+	if err != nil {
+		return nil, err
+	}
+	rows = withHeaderRow(selectSheets(rows, filename, opts.Mapping, false), filename, opts.Mapping.headerRow(filename), opts.headerScore(filename))
+	defer rows.Close()
+
+	entries := make(map[string]bool)
+	var sheet string
+	var columns []int
+	started, found := false, false
+	for {
+		row, err := rows.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		// The first row of every sheet holds its headers
+		if !started || row.sheet != sheet {
+			started = true
+			sheet = row.sheet
+			headers := sanitizeHeaders(row.cells)
+			columns = opts.DatabaseColumns(SheetKey(filename, sheet), headers)
+			if len(columns) == 0 {
+				if sheet != "" {
+					utils.LogMessage(fmt.Sprintf("No email columns found in sheet %s of %s, skipping...", sheet, filename))
+				}
+				continue
+			}
+			found = true
+			var names []string
+			for _, i := range columns {
+				names = append(names, headers[i])
+			}
+			utils.LogMessage(fmt.Sprintf("Reading entries of %s from columns: %s", sheetLabel(filename, sheet), strings.Join(names, ", ")))
+			continue
+		}
+
+		for _, i := range columns {
+			if i < len(row.cells) {
+				if entry := strings.TrimSpace(row.cells[i]); entry != "" {
+					entries[entry] = true
+				}
+			}
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("no email columns found in file")
+	}
+	return entries, nil
+}
+
+// sheetLabel names a sheet of a file in log messages
+func sheetLabel(filename, sheet string) string {
+	if sheet == "" {
+		return filename
+	}
+	return fmt.Sprintf("%s (sheet %s)", filename, sheet)
+}
@@ -0,0 +1,82 @@
+package records
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDatabaseColumns(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers []string
+		columns []string
+		want    []int
+	}{
+		{"email columns", []string{"Email", "Secondary Email", "Name"}, nil, []int{0, 1}},
+		{"domain beside email", []string{"Email", "Domain", "Unsubscribed At"}, nil, []int{0}},
+		{"email domain beside email", []string{"Email", "Email Domain"}, nil, []int{0}},
+		{"rule columns alone", []string{"Domain", "Pattern", "Added"}, nil, []int{0, 1}},
+		{"hash column alone", []string{"SHA256"}, nil, []int{0}},
+		{"picked columns", []string{"Email", "Domain"}, []string{"domain"}, []int{1}},
+		{"nothing to read", []string{"Name", "Phone"}, nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DatabaseOptions{Columns: tt.columns}.DatabaseColumns("list.csv", tt.headers)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DatabaseColumns(%q) = %v, want %v", tt.headers, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetSheetHeaders(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		opts    HeaderOptions
+		want    []string
+	}{
+		{
+			name:    "title row",
+			content: "Unsubscribes\n\nEmail,Unsubscribed At\na@b.com,2026-01-02\n",
+			opts:    HeaderOptions{Database: true},
+			want:    []string{"Email", "Unsubscribed At"},
+		},
+		{
+			name:    "rule columns",
+			content: "Blocked domains\nDomain,Added\n@example.com,2026-01-02\n",
+			opts:    HeaderOptions{Database: true},
+			want:    []string{"Domain", "Added"},
+		},
+		{
+			name:    "records need a name",
+			content: "Email,Source\nName,Email,Source\nAnn,a@b.com,web\n",
+			want:    []string{"Name", "Email", "Source"},
+		},
+		{
+			name:    "delimiter override",
+			content: "Suppression export\nEmail|Reason\na@b.com|moved; left\n",
+			opts:    HeaderOptions{Mapping: ColumnMapping{CSV: Dialect{Delimiter: "|"}}, Database: true},
+			want:    []string{"Email", "Reason"},
+		},
+		{
+			name:    "header row override",
+			content: "Email,Notes\nOwner,Sales\nAddress,Source\na@b.com,web\n",
+			opts:    HeaderOptions{Mapping: ColumnMapping{HeaderRow: 3}, Database: true},
+			want:    []string{"Address", "Source"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeFile(t, "list.csv", tt.content)
+			sheets, err := GetSheetHeaders(path, tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(sheets) != 1 || !reflect.DeepEqual(sheets[0].Headers, tt.want) {
+				t.Errorf("GetSheetHeaders() = %v, want one sheet with %q", sheets, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package records
+
+import (
+	"fmt"
+	"io"
+
+	"website-copier/cmd/utils"
+)
+
+// headerScan is the number of rows at the top of a sheet searched for its headers
+const headerScan = 20
+
+// headerRows drops the title, date and blank rows found above the header row of
+// every sheet, so the headers are the first row each sheet yields. The header
+// row is the best scoring of the first rows, or the row given.
+type headerRows struct {
+	rows     rowSource
+	filename string
+	row      func(sheet string) int                 // 1-based header row of a sheet; 0 or a nil func detects it
+	score    func(sheet string, cells []string) int // Rates a row as the header row of a sheet
+	quiet    bool                                   // Don't log the rows skipped, e.g. when only peeking at the headers
+	above    func(row rawRow)                       // Optionally called with every row dropped above a header row
+
+	pending []rawRow // Rows of the current sheet, from its header on
+	held    *rawRow  // First row of the next sheet, read while scanning
+	sheet   string
+	started bool
+}
+
+// withHeaderRow wraps a row source so each sheet starts at its header row
+func withHeaderRow(rows rowSource, filename string, row func(string) int, score func(string, []string) int) rowSource {
+	return &headerRows{rows: rows, filename: filename, row: row, score: score}
+}
+
+func (h *headerRows) next() (rawRow, error) {
+	if len(h.pending) > 0 {
+		row := h.pending[0]
+		h.pending = h.pending[1:]
+		return row, nil
+	}
+	row, err := h.read()
+	if err != nil {
+		return rawRow{}, err
+	}
+	if h.started && row.sheet == h.sheet {
+		return row, nil
+	}
+
+	// A new sheet: look for its headers among its first rows
+	h.started, h.sheet = true, row.sheet
+	headerRow := h.headerRow()
+	scanned := []rawRow{row}
+	for headerRow > 0 && scanned[len(scanned)-1].line < headerRow || headerRow == 0 && len(scanned) < headerScan {
+		next, err := h.read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return rawRow{}, err
+		}
+		if next.sheet != h.sheet {
+			h.held = &next
+			break
+		}
+		scanned = append(scanned, next)
+	}
+
+	header := h.headerIndex(scanned, headerRow)
+	if line := scanned[header].line; line > 1 && !h.quiet {
+		utils.LogMessage(fmt.Sprintf("Found the headers of %s on row %d, skipping the rows above them", sheetLabel(h.filename, h.sheet), line))
+	}
+	if h.above != nil {
+		for _, row := range scanned[:header] {
+			h.above(row)
+		}
+	}
+	h.pending = scanned[header+1:]
+	return scanned[header], nil
+}
+
+// headerRow returns the header row given for the current sheet, or 0
+func (h *headerRows) headerRow() int {
+	if h.row == nil {
+		return 0
+	}
+	return h.row(h.sheet)
+}
+
+// headerIndex picks the header row among the scanned rows
+func (h *headerRows) headerIndex(scanned []rawRow, headerRow int) int {
+	if headerRow > 0 {
+		for i, row := range scanned {
+			if row.line >= headerRow {
+				return i
+			}
+		}
+		return len(scanned) - 1
+	}
+	best, bestScore := 0, 0
+	for i, row := range scanned {
+		// Ties go to the earlier row
+		if score := h.score(h.sheet, row.cells); score > bestScore {
+			best, bestScore = i, score
+		}
+	}
+	return best
+}
+
+// read returns the row held back from the next sheet, or the next row
+func (h *headerRows) read() (rawRow, error) {
+	if h.held != nil {
+		row := *h.held
+		h.held = nil
+		return row, nil
+	}
+	return h.rows.next()
+}
+
+// skipSheet moves on to the next sheet of a workbook
+func (h *headerRows) skipSheet() {
+	h.pending = nil
+	if h.held != nil {
+		// The source is already on the next sheet
+		return
+	}
+	if s, ok := h.rows.(interface{ skipSheet() }); ok {
+		s.skipSheet()
+	}
+}
+
+func (h *headerRows) Close() error {
+	return h.rows.Close()
+}
+
+// headerScore rates a row as the header row of records mapped by the mapping
+func (m ColumnMapping) headerScore(file string) func(string, []string) int {
+	return func(sheet string, cells []string) int {
+		return m.ResolveColumns(SheetKey(file, sheet), sanitizeHeaders(append([]string(nil), cells...))).Score
+	}
+}
+
+// headerRow returns the header row given for each sheet of a file
+func (m ColumnMapping) headerRow(file string) func(string) int {
+	return func(sheet string) int {
+		return m.FileHeaderRow(SheetKey(file, sheet))
+	}
+}
@@ -0,0 +1,141 @@
+package records
+
+import (
+	"io"
+	"reflect"
+	"testing"
+)
+
+// sliceRows is a row source over rows held in memory, numbered per sheet
+type sliceRows struct {
+	rows []rawRow
+}
+
+func newSliceRows(sheets map[string][][]string, order ...string) *sliceRows {
+	s := &sliceRows{}
+	for i, sheet := range order {
+		for line, cells := range sheets[sheet] {
+			s.rows = append(s.rows, rawRow{cells: cells, sheet: sheet, sheetIndex: i, line: line + 1})
+		}
+	}
+	return s
+}
+
+func (s *sliceRows) next() (rawRow, error) {
+	if len(s.rows) == 0 {
+		return rawRow{}, io.EOF
+	}
+	row := s.rows[0]
+	s.rows = s.rows[1:]
+	return row, nil
+}
+
+func (s *sliceRows) Close() error { return nil }
+
+func TestHeaderRows(t *testing.T) {
+	title := []string{"Vendor export"}
+	date := []string{"Generated 2026-01-02", ""}
+	blank := []string{"", "", ""}
+	headers := []string{"Full Name", "Email", "Company"}
+	row := []string{"Ann", "ann@example.com", "Acme"}
+
+	tests := []struct {
+		name    string
+		sheets  map[string][][]string
+		order   []string
+		mapping ColumnMapping
+		want    map[string]int // Header row found in each sheet
+		rows    int            // Rows yielded, headers included
+	}{
+		{
+			name:   "headers first",
+			sheets: map[string][][]string{"": {headers, row, row}},
+			order:  []string{""},
+			want:   map[string]int{"": 1},
+			rows:   3,
+		},
+		{
+			name:   "title, date and blank rows",
+			sheets: map[string][][]string{"": {title, date, blank, headers, row}},
+			order:  []string{""},
+			want:   map[string]int{"": 4},
+			rows:   2,
+		},
+		{
+			name:    "header row override",
+			sheets:  map[string][][]string{"": {title, headers, {"Name", "Email"}, row}},
+			order:   []string{""},
+			mapping: ColumnMapping{HeaderRow: 3},
+			want:    map[string]int{"": 3},
+			rows:    2,
+		},
+		{
+			name: "each sheet",
+			sheets: map[string][][]string{
+				"Leads":  {headers, row},
+				"Events": {title, blank, headers, row, row},
+			},
+			order: []string{"Leads", "Events"},
+			want:  map[string]int{"Leads": 1, "Events": 3},
+			rows:  5,
+		},
+		{
+			name: "sheet override",
+			sheets: map[string][][]string{
+				"Leads":  {title, headers, row},
+				"Events": {title, headers, row},
+			},
+			order:   []string{"Leads", "Events"},
+			mapping: ColumnMapping{HeaderRowFiles: map[string]int{"leads.xlsx:Events": 1}},
+			want:    map[string]int{"Leads": 2, "Events": 1},
+			rows:    5,
+		},
+		{
+			name:   "no headers",
+			sheets: map[string][][]string{"": {row, row}},
+			order:  []string{""},
+			want:   map[string]int{"": 1},
+			rows:   2,
+		},
+		{
+			name:   "title only",
+			sheets: map[string][][]string{"": {title}},
+			order:  []string{""},
+			want:   map[string]int{"": 1},
+			rows:   1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			const file = "/data/leads.xlsx"
+			rows := &headerRows{
+				rows:     newSliceRows(tt.sheets, tt.order...),
+				filename: file,
+				row:      tt.mapping.headerRow(file),
+				score:    tt.mapping.headerScore(file),
+				quiet:    true,
+			}
+			got := make(map[string]int)
+			count := 0
+			for {
+				r, err := rows.next()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				if _, ok := got[r.sheet]; !ok {
+					got[r.sheet] = r.line
+				}
+				count++
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("header rows = %v, want %v", got, tt.want)
+			}
+			if count != tt.rows {
+				t.Errorf("got %d rows, want %d", count, tt.rows)
+			}
+		})
+	}
+}
@@ -0,0 +1,159 @@
+package records
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// PreviewRows is the number of rows read from each sheet for a preview
+const PreviewRows = 100
+
+// Reasons rows or sheets are skipped when loading a file
+const (
+	SkipAboveHeader = "above the header row"
+	SkipNotSelected = "sheet not selected"
+	SkipEmptySheet  = "empty sheet"
+)
+
+// PreviewRow is a row of a preview
+type PreviewRow struct {
+	Line    int
+	Cells   []string
+	Skipped string // Why the row would not be loaded, empty when it would
+}
+
+// SheetPreview is the start of a sheet as it would be loaded
+type SheetPreview struct {
+	Sheet     string
+	HeaderRow int     // Line of the header row, 0 for sheets that were not read
+	Columns   Columns // The headers and the columns mapped to Name, Email and OrgName
+	Skipped   string  // Why the whole sheet would be skipped, if it would
+	Rows      []PreviewRow
+	More      bool // The sheet has more rows than were read
+}
+
+// Loaded returns the number of rows that would be loaded as records
+func (s SheetPreview) Loaded() int {
+	n := 0
+	for _, row := range s.Rows {
+		if row.Skipped == "" {
+			n++
+		}
+	}
+	return n
+}
+
+// FillRate returns the share of the loaded rows with a value in each column,
+// between 0 and 1
+func (s SheetPreview) FillRate() []float64 {
+	rates := make([]float64, len(s.Columns.Headers))
+	loaded := s.Loaded()
+	if loaded == 0 {
+		return rates
+	}
+	for _, row := range s.Rows {
+		if row.Skipped != "" {
+			continue
+		}
+		for i := range rates {
+			if i < len(row.Cells) && strings.TrimSpace(row.Cells[i]) != "" {
+				rates[i]++
+			}
+		}
+	}
+	for i := range rates {
+		rates[i] /= float64(loaded)
+	}
+	return rates
+}
+
+// Preview reads the first rows of every sheet of a file the way they would be
+// loaded, keeping the rows that would be skipped along with the reason. At
+// most limit rows are read below each header row.
+func Preview(filename string, opts ReaderOptions, limit int) ([]SheetPreview, error) {
+	rows, err := openRows(filename, opts.Mapping.Dialect(filename))
+	if err != nil {
+		return nil, err
+	}
+	var above []rawRow
+	headers := &headerRows{
+		rows:     selectSheets(rows, filename, opts.Mapping, true),
+		filename: filename,
+		row:      opts.Mapping.headerRow(filename),
+		score:    opts.Mapping.headerScore(filename),
+		quiet:    true,
+		above:    func(row rawRow) { above = append(above, row) },
+	}
+	defer headers.Close()
+
+	var previews []SheetPreview
+	var current *SheetPreview
+	read := 0
+	for {
+		row, err := headers.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		// The first row of every sheet holds its headers, once the rows above them are dropped
+		if current == nil || row.sheet != current.Sheet {
+			previews = append(previews, SheetPreview{Sheet: row.sheet, HeaderRow: row.line})
+			current = &previews[len(previews)-1]
+			for _, r := range above {
+				current.Rows = append(current.Rows, PreviewRow{Line: r.line, Cells: r.cells, Skipped: SkipAboveHeader})
+			}
+			above, read = nil, 0
+			current.Columns = opts.Mapping.ResolveColumns(SheetKey(filename, row.sheet), sanitizeHeaders(append([]string(nil), row.cells...)))
+			if current.Columns.Email == -1 || current.Columns.Name == -1 && !opts.EmailOnly {
+				current.Skipped = fmt.Sprintf("required columns (%s) not found", requiredColumns(opts.EmailOnly))
+			}
+			continue
+		}
+
+		if read == limit {
+			current.More = true
+			if row.sheet == "" {
+				break
+			}
+			headers.skipSheet()
+			continue
+		}
+		read++
+		skipped := current.Skipped
+		if skipped == "" {
+			// Rows too short to reach a required column
+			if len(row.cells) <= current.Columns.Email {
+				skipped = "no " + RoleEmail + " value"
+			} else if len(row.cells) <= current.Columns.Name {
+				skipped = "no " + RoleName + " value"
+			}
+		}
+		current.Rows = append(current.Rows, PreviewRow{Line: row.line, Cells: row.cells, Skipped: skipped})
+	}
+
+	// List the sheets that were left out or had no rows, in workbook order
+	names, err := SheetNames(filename)
+	if err != nil || len(names) == 0 {
+		return previews, err
+	}
+	bySheet := make(map[string]SheetPreview)
+	for _, preview := range previews {
+		bySheet[preview.Sheet] = preview
+	}
+	all := make([]SheetPreview, 0, len(names))
+	for _, name := range names {
+		preview, ok := bySheet[name]
+		if !ok {
+			preview = SheetPreview{Sheet: name, Skipped: SkipEmptySheet}
+			if !opts.Mapping.SheetSelected(filename, name) {
+				preview.Skipped = SkipNotSelected
+			}
+		}
+		all = append(all, preview)
+	}
+	return all, nil
+}
@@ -0,0 +1,145 @@
+package records
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPreviewCSV(t *testing.T) {
+	// A title and a blank row sit above the headers; the empty line is dropped by the CSV reader
+	path := writeFile(t, "contacts.csv", "Contacts export\n\n,,\nName,Email,Phone\nAnn,ann@x.com,\nBob,bob@x.com,555\nCid\nDee,dee@x.com,\n")
+
+	tests := []struct {
+		name  string
+		limit int
+		rows  []PreviewRow
+		more  bool
+	}{
+		{
+			name:  "row limit",
+			limit: 3,
+			rows: []PreviewRow{
+				{Line: 1, Cells: []string{"Contacts export"}, Skipped: SkipAboveHeader},
+				{Line: 3, Cells: []string{"", "", ""}, Skipped: SkipAboveHeader},
+				{Line: 5, Cells: []string{"Ann", "ann@x.com", ""}},
+				{Line: 6, Cells: []string{"Bob", "bob@x.com", "555"}},
+				{Line: 7, Cells: []string{"Cid"}, Skipped: "no Email value"},
+			},
+			more: true,
+		},
+		{
+			name:  "whole file",
+			limit: PreviewRows,
+			rows: []PreviewRow{
+				{Line: 1, Cells: []string{"Contacts export"}, Skipped: SkipAboveHeader},
+				{Line: 3, Cells: []string{"", "", ""}, Skipped: SkipAboveHeader},
+				{Line: 5, Cells: []string{"Ann", "ann@x.com", ""}},
+				{Line: 6, Cells: []string{"Bob", "bob@x.com", "555"}},
+				{Line: 7, Cells: []string{"Cid"}, Skipped: "no Email value"},
+				{Line: 8, Cells: []string{"Dee", "dee@x.com", ""}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previews, err := Preview(path, ReaderOptions{}, tt.limit)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(previews) != 1 {
+				t.Fatalf("Preview() = %d sheets, want 1", len(previews))
+			}
+			p := previews[0]
+			if p.Sheet != "" || p.HeaderRow != 4 || p.Skipped != "" || p.Columns.Name != 0 || p.Columns.Email != 1 {
+				t.Errorf("Preview() = sheet %q, header row %d, skipped %q, columns %+v, want the headers on row 4", p.Sheet, p.HeaderRow, p.Skipped, p.Columns)
+			}
+			if !reflect.DeepEqual(p.Rows, tt.rows) || p.More != tt.more {
+				t.Errorf("rows = %+v, more %v, want %+v, more %v", p.Rows, p.More, tt.rows, tt.more)
+			}
+		})
+	}
+}
+
+func TestPreviewWorkbook(t *testing.T) {
+	cell := func(ref, value string) string {
+		return `<c r="` + ref + `" t="inlineStr"><is><t>` + value + `</t></is></c>`
+	}
+	people := `<row r="1">` + cell("A1", "Name") + cell("B1", "Email") + `</row>` +
+		`<row r="2">` + cell("A2", "Ann") + cell("B2", "ann@example.com") + `</row>`
+	path := writeWorkbook(t, []testSheet{
+		{"People", people},
+		{"Notes", `<row r="1">` + cell("A1", "Note") + `</row><row r="2">` + cell("A2", "call back") + `</row>`},
+		{"Empty", ""},
+		{"Archive", people},
+	}, "", "")
+
+	previews, err := Preview(path, ReaderOptions{Mapping: ColumnMapping{SkipSheets: []string{"Archive"}}}, PreviewRows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		sheet   string
+		skipped string
+		loaded  int
+	}{
+		{"People", "", 1},
+		{"Notes", "required columns (Name, Email) not found", 0},
+		{"Empty", SkipEmptySheet, 0},
+		{"Archive", SkipNotSelected, 0},
+	}
+	if len(previews) != len(want) {
+		t.Fatalf("Preview() = %+v, want %d sheets", previews, len(want))
+	}
+	for i, w := range want {
+		p := previews[i]
+		if p.Sheet != w.sheet || p.Skipped != w.skipped || p.Loaded() != w.loaded {
+			t.Errorf("sheet %d = %q skipped %q with %d rows loaded, want %q skipped %q with %d", i, p.Sheet, p.Skipped, p.Loaded(), w.sheet, w.skipped, w.loaded)
+		}
+	}
+}
+
+func TestFillRate(t *testing.T) {
+	headers := Columns{Headers: []string{"Name", "Email", "Phone"}}
+	tests := []struct {
+		name string
+		rows []PreviewRow
+		want []float64
+	}{
+		{
+			name: "empty column",
+			rows: []PreviewRow{
+				{Cells: []string{"Ann", "ann@x.com", ""}},
+				{Cells: []string{"Bob", "bob@x.com", " "}},
+			},
+			want: []float64{1, 1, 0},
+		},
+		{
+			name: "short rows and skipped rows",
+			rows: []PreviewRow{
+				{Cells: []string{"Contacts export"}, Skipped: SkipAboveHeader},
+				{Cells: []string{"Ann", "ann@x.com", "555"}},
+				{Cells: []string{"", "bob@x.com"}},
+				{Cells: []string{"Cid", "cid@x.com"}},
+				{Cells: []string{"Dee"}, Skipped: "no Email value"},
+			},
+			want: []float64{2.0 / 3, 1, 1.0 / 3},
+		},
+		{
+			name: "no rows",
+			want: []float64{0, 0, 0},
+		},
+		{
+			name: "only skipped rows",
+			rows: []PreviewRow{{Cells: []string{"Ann"}, Skipped: "no Email value"}},
+			want: []float64{0, 0, 0},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := SheetPreview{Columns: headers, Rows: tt.rows}
+			if got := s.FillRate(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FillRate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
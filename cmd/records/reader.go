@@ -0,0 +1,266 @@
+package records
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"website-copier/cmd/utils"
+)
+
+// RecordReader streams the records of a CSV or XLSX file one row at a time,
+// so memory use does not grow with the size of the file
+type RecordReader interface {
+	// Next advances to the next record, returning false at the end of the file or on error
+	Next() bool
+	// Record returns the record Next advanced to
+	Record() Record
+	// Headers returns the headers of the sheet the current record came from
+	Headers() []string
+	// Err returns the first error met while reading, if any
+	Err() error
+	Close() error
+}
+
+// ReaderOptions controls how a RecordReader maps rows to records
+type ReaderOptions struct {
+	// EmailOnly accepts files without a name column, e.g. database files
+	EmailOnly bool
+	// Mapping pins or extends the headers used for the Name, Email and OrgName fields
+	Mapping ColumnMapping
+}
+
+// rawRow is a single row as read from the file
+type rawRow struct {
+	cells      []string
+	sheet      string
+	sheetIndex int // 0-based position of the sheet in its workbook
+	line       int
+}
+
+// rowSource yields raw rows and returns io.EOF once the file is exhausted
+type rowSource interface {
+	next() (rawRow, error)
+	Close() error
+}
+
+// csvRows streams rows from a CSV file, decoded and split as sniffed
+type csvRows struct {
+	file    *os.File
+	reader  *csv.Reader
+	sniffed *sniffedCSV
+}
+
+// openCSVRows opens a CSV file, detecting its encoding, delimiter and quote
+// character unless overridden. comma is assumed for files of a single column.
+func openCSVRows(filename string, comma rune, dialect Dialect) (*csvRows, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	sniffed, err := sniffCSV(file, dialect, comma)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+	reader := csv.NewReader(sniffed.reader)
+	reader.Comma = sniffed.delimiter
+	reader.LazyQuotes = true    // Allows for malformed CSV fields like bare quotes
+	reader.FieldsPerRecord = -1 // Allow variable number of fields per row
+	return &csvRows{file: file, reader: reader, sniffed: sniffed}, nil
+}
+
+func (c *csvRows) next() (rawRow, error) {
+	cells, err := c.reader.Read()
+	if err != nil {
+		return rawRow{}, err
+	}
+	if c.sniffed.quote != '"' {
+		unswapQuotes(cells, c.sniffed.quote)
+	}
+	line, _ := c.reader.FieldPos(0)
+	return rawRow{cells: cells, line: line}, nil
+}
+
+func (c *csvRows) Close() error {
+	return c.file.Close()
+}
+
+// openRows opens the row source matching the file extension; dialect
+// overrides what is detected about CSV and TSV files
+func openRows(filename string, dialect Dialect) (rowSource, error) {
+	ext := strings.ToLower(filepath.Ext(filename))
+	if ext == ".csv" {
+		return openCSVRows(filename, ',', dialect)
+	} else if ext == ".tsv" {
+		return openCSVRows(filename, '\t', dialect)
+	} else if ext == ".xlsx" {
+		return openXLSXRows(filename)
+	}
+	return nil, fmt.Errorf("unsupported file type: %s", ext)
+}
+
+// recordReader maps the rows of a rowSource to records, re-reading the
+// headers at the start of every sheet
+type recordReader struct {
+	filename string
+	opts     ReaderOptions
+	rows     rowSource
+
+	sheet        string
+	sheetIndex   int
+	sheetStarted bool
+	headers      []string
+	emailIndex   int
+	nameIndex    int
+	orgNameIndex int
+	columns      *Columns
+	validSheet   bool
+	foundColumns bool
+
+	record Record
+	err    error
+}
+
+// OpenRecordReader opens a streaming reader over the records of a CSV or XLSX file
+func OpenRecordReader(filename string, opts ReaderOptions) (RecordReader, error) {
+	rows, err := openRows(filename, opts.Mapping.Dialect(filename))
+	if err != nil {
+		return nil, err
+	}
+	if c, ok := rows.(*csvRows); ok {
+		if description := c.sniffed.describe(); description != "" {
+			utils.LogMessage(fmt.Sprintf("Reading %s as %s", filename, description))
+		}
+	}
+	rows = selectSheets(rows, filename, opts.Mapping, false)
+	rows = withHeaderRow(rows, filename, opts.Mapping.headerRow(filename), opts.Mapping.headerScore(filename))
+	return &recordReader{filename: filename, opts: opts, rows: rows}, nil
+}
+
+func (r *recordReader) Next() bool {
+	if r.err != nil {
+		return false
+	}
+	for {
+		row, err := r.rows.next()
+		if err == io.EOF {
+			if !r.foundColumns {
+				r.err = r.missingColumnsError()
+			}
+			return false
+		}
+		if err != nil {
+			r.err = err
+			return false
+		}
+
+		// The first row of every sheet holds its headers, once the rows above them are dropped
+		if !r.sheetStarted || row.sheet != r.sheet {
+			r.sheet, r.sheetIndex = row.sheet, row.sheetIndex
+			r.sheetStarted = true
+			r.setHeaders(row.cells)
+			continue
+		}
+		if !r.validSheet {
+			continue
+		}
+
+		cells := row.cells
+		if len(cells) <= r.emailIndex || len(cells) <= r.nameIndex {
+			// Skip rows that don't have enough columns
+			continue
+		}
+		r.record = r.buildRecord(cells)
+		r.record.Row = row.line
+		return true
+	}
+}
+
+// setHeaders finds the required column indexes through the column mapping
+func (r *recordReader) setHeaders(cells []string) {
+	r.headers = sanitizeHeaders(append([]string(nil), cells...))
+	cols := r.opts.Mapping.ResolveColumns(SheetKey(r.filename, r.sheet), r.headers)
+	r.emailIndex = cols.Email
+	r.nameIndex = cols.Name
+	r.orgNameIndex = cols.OrgName // Optional
+	r.columns = &cols
+	for _, warning := range cols.Warnings {
+		utils.LogMessage(fmt.Sprintf("%s: %s", sheetLabel(r.filename, r.sheet), warning))
+	}
+
+	r.validSheet = r.emailIndex != -1 && (r.nameIndex != -1 || r.opts.EmailOnly)
+	if r.validSheet {
+		r.foundColumns = true
+	} else if r.sheet != "" {
+		// Skip sheets if required columns are not found
+		utils.LogMessage(fmt.Sprintf("Required columns (%s) not found in sheet %s of %s, skipping...", r.requiredColumns(), r.sheet, r.filename))
+	}
+}
+
+func (r *recordReader) buildRecord(cells []string) Record {
+	record := Record{
+		Email:      cells[r.emailIndex],
+		OthersMap:  make(map[string]string),
+		FilePath:   r.filename,
+		Sheet:      r.sheet,
+		SheetIndex: r.sheetIndex,
+		Columns:    r.columns,
+	}
+	if r.nameIndex != -1 {
+		record.Name = cells[r.nameIndex]
+	}
+	if r.orgNameIndex != -1 && r.orgNameIndex < len(cells) {
+		record.OrgName = cells[r.orgNameIndex]
+	}
+
+	// Map the remaining headers to values
+	for i, header := range r.headers {
+		if i == r.emailIndex || i == r.nameIndex || i == r.orgNameIndex {
+			continue
+		}
+		value := ""
+		if i < len(cells) {
+			value = cells[i]
+		}
+		record.OthersMap[header] = value
+	}
+	return record
+}
+
+func (r *recordReader) requiredColumns() string {
+	return requiredColumns(r.opts.EmailOnly)
+}
+
+// requiredColumns lists the columns a sheet needs to be read
+func requiredColumns(emailOnly bool) string {
+	if emailOnly {
+		return "Email"
+	}
+	return "Name, Email"
+}
+
+func (r *recordReader) missingColumnsError() error {
+	if !r.sheetStarted {
+		return nil // Empty file, nothing to report
+	}
+	return fmt.Errorf("required columns (%s) not found in file: %s", r.requiredColumns(), r.filename)
+}
+
+func (r *recordReader) Record() Record {
+	return r.record
+}
+
+func (r *recordReader) Headers() []string {
+	return r.headers
+}
+
+func (r *recordReader) Err() error {
+	return r.err
+}
+
+func (r *recordReader) Close() error {
+	return r.rows.Close()
+}
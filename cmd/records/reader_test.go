@@ -0,0 +1,225 @@
+package records
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// writeFile writes a file into a temporary folder
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// testSheet is the name and raw <sheetData> rows of a worksheet
+type testSheet struct {
+	name string
+	rows string
+}
+
+// writeWorkbook writes a minimal XLSX file holding the sheets and, when not
+// empty, the shared strings and styles parts
+func writeWorkbook(t *testing.T, sheets []testSheet, sharedStrings, styles string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	add := func(name, content string) {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var sheetList, rels string
+	for i, sheet := range sheets {
+		sheetList += fmt.Sprintf(`<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, sheet.name, i+1, i+1)
+		rels += fmt.Sprintf(`<Relationship Id="rId%d" Target="worksheets/sheet%d.xml"/>`, i+1, i+1)
+		add(fmt.Sprintf("xl/worksheets/sheet%d.xml", i+1),
+			`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`+sheet.rows+`</sheetData></worksheet>`)
+	}
+	add("xl/workbook.xml", `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" `+
+		`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>`+sheetList+`</sheets></workbook>`)
+	add("xl/_rels/workbook.xml.rels", `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`+rels+`</Relationships>`)
+	if sharedStrings != "" {
+		add("xl/sharedStrings.xml", `<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`+sharedStrings+`</sst>`)
+	}
+	if styles != "" {
+		add("xl/styles.xml", `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`+styles+`</styleSheet>`)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return writeFile(t, "book.xlsx", buf.String())
+}
+
+// readAll reads every record of a file along with the headers each came with
+func readAll(t *testing.T, path string, opts ReaderOptions) ([]Record, [][]string) {
+	t.Helper()
+	reader, err := OpenRecordReader(path, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+
+	var list []Record
+	var headers [][]string
+	for reader.Next() {
+		record := reader.Record()
+		record.Columns = nil // The mapping is checked through the headers
+		list = append(list, record)
+		headers = append(headers, reader.Headers())
+	}
+	if err := reader.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return list, headers
+}
+
+func TestRecordReaderCSV(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []Record
+		headers []string
+	}{
+		{
+			name:    "byte order mark",
+			content: "\xEF\xBB\xBFEmail,Name,Phone\na@b.com,Ann,555\n",
+			want:    []Record{{Email: "a@b.com", Name: "Ann", OthersMap: map[string]string{"Phone": "555"}, Row: 2}},
+			headers: []string{"Email", "Name", "Phone"},
+		},
+		{
+			name:    "short row",
+			content: "Name,Email,Phone,City\nAnn,a@b.com\n",
+			want:    []Record{{Email: "a@b.com", Name: "Ann", OthersMap: map[string]string{"Phone": "", "City": ""}, Row: 2}},
+			headers: []string{"Name", "Email", "Phone", "City"},
+		},
+		{
+			name:    "row without an email cell",
+			content: "Name,Phone,Email\nAnn,555\nBob,556,b@c.com\n",
+			want:    []Record{{Email: "b@c.com", Name: "Bob", OthersMap: map[string]string{"Phone": "556"}, Row: 3}},
+			headers: []string{"Name", "Phone", "Email"},
+		},
+		{
+			name:    "long row",
+			content: "Name,Email\nAnn,a@b.com,extra,more\n",
+			want:    []Record{{Email: "a@b.com", Name: "Ann", OthersMap: map[string]string{}, Row: 2}},
+			headers: []string{"Name", "Email"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeFile(t, "contacts.csv", tt.content)
+			list, headers := readAll(t, path, ReaderOptions{})
+			for i := range tt.want {
+				tt.want[i].FilePath = path
+			}
+			if !reflect.DeepEqual(list, tt.want) {
+				t.Errorf("records = %#v, want %#v", list, tt.want)
+			}
+			if len(headers) > 0 && !reflect.DeepEqual(headers[0], tt.headers) {
+				t.Errorf("headers = %q, want %q", headers[0], tt.headers)
+			}
+		})
+	}
+}
+
+func TestRecordReaderXLSXSheets(t *testing.T) {
+	sharedStrings := `<si><t>Name</t></si><si><t>Email</t></si><si><r><t>Ann </t></r><r><t>Lee</t></r></si>`
+	path := writeWorkbook(t, []testSheet{
+		{"People", `<row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1" t="s"><v>1</v></c><c r="C1" t="inlineStr"><is><t>Phone</t></is></c></row>` +
+			`<row r="2"><c r="A2" t="s"><v>2</v></c><c r="B2" t="inlineStr"><is><t>ann@example.com</t></is></c><c r="C2"><v>555</v></c></row>`},
+		{"Notes", `<row r="1"><c r="A1" t="inlineStr"><is><t>Note</t></is></c></row>` +
+			`<row r="2"><c r="A2" t="inlineStr"><is><t>no people here</t></is></c></row>`},
+		{"More", `<row r="1"><c r="A1" t="inlineStr"><is><t>Email</t></is></c><c r="B1" t="inlineStr"><is><t>Name</t></is></c></row>` +
+			`<row r="2"><c r="A2" t="inlineStr"><is><t>bob@example.com</t></is></c><c r="B2" t="inlineStr"><is><r><t>Bob</t></r></is></c></row>`},
+	}, sharedStrings, "")
+
+	list, headers := readAll(t, path, ReaderOptions{})
+	ann := Record{Name: "Ann Lee", Email: "ann@example.com", OthersMap: map[string]string{"Phone": "555"}, FilePath: path, Sheet: "People", Row: 2}
+	bob := Record{Name: "Bob", Email: "bob@example.com", OthersMap: map[string]string{}, FilePath: path, Sheet: "More", SheetIndex: 2, Row: 2}
+	if want := []Record{ann, bob}; !reflect.DeepEqual(list, want) {
+		t.Errorf("records = %#v, want %#v", list, want)
+	}
+	wantHeaders := [][]string{{"Name", "Email", "Phone"}, {"Email", "Name"}}
+	if !reflect.DeepEqual(headers, wantHeaders) {
+		t.Errorf("headers = %q, want %q", headers, wantHeaders)
+	}
+
+	tests := []struct {
+		name    string
+		mapping ColumnMapping
+		want    []Record
+	}{
+		{"sheet for every workbook", ColumnMapping{Sheets: []string{"More"}}, []Record{bob}},
+		{"sheet of another file", ColumnMapping{Sheets: []string{"other.xlsx:More"}}, []Record{ann, bob}},
+		{"skipped sheet", ColumnMapping{SkipSheets: []string{"book.xlsx:People"}}, []Record{bob}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			list, _ := readAll(t, path, ReaderOptions{Mapping: tt.mapping})
+			if !reflect.DeepEqual(list, tt.want) {
+				t.Errorf("records = %#v, want %#v", list, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecordReaderXLSXCells(t *testing.T) {
+	// Style 1 is a built-in date format, 2 a custom date-time, 3 a custom
+	// number format whose color must not be taken for a date
+	styles := `<numFmts><numFmt numFmtId="164" formatCode="dd/mm/yyyy hh:mm"/><numFmt numFmtId="165" formatCode="[Red]0.00"/></numFmts>` +
+		`<cellXfs><xf numFmtId="0"/><xf numFmtId="14"/><xf numFmtId="164"/><xf numFmtId="165"/></cellXfs>`
+	// The second row skips columns B and D, which only the r= references tell
+	path := writeWorkbook(t, []testSheet{{"Sheet1",
+		`<row r="1"><c r="A1" t="inlineStr"><is><t>Email</t></is></c><c r="B1" t="inlineStr"><is><t>Name</t></is></c>` +
+			`<c r="C1" t="inlineStr"><is><t>Joined</t></is></c><c r="D1" t="inlineStr"><is><t>Seen</t></is></c>` +
+			`<c r="E1" t="inlineStr"><is><t>Score</t></is></c><c r="F1" t="inlineStr"><is><t>Active</t></is></c></row>` +
+			`<row r="2"><c r="A2" t="inlineStr"><is><t>ann@example.com</t></is></c><c r="C2" s="1"><v>45292</v></c>` +
+			`<c r="E2" s="3"><v>1.50</v></c><c r="F2" t="b"><v>1</v></c></row>` +
+			`<row r="3"><c r="A3" t="inlineStr"><is><t>bob@example.com</t></is></c><c r="B3" t="inlineStr"><is><t>Bob</t></is></c>` +
+			`<c r="D3" s="2"><v>45292.5</v></c></row>`,
+	}}, "", styles)
+
+	list, _ := readAll(t, path, ReaderOptions{})
+	want := []Record{
+		{
+			Email: "ann@example.com", FilePath: path, Sheet: "Sheet1", Row: 2,
+			OthersMap: map[string]string{"Joined": "2024-01-01", "Seen": "", "Score": "1.5", "Active": "TRUE"},
+		},
+		{
+			Email: "bob@example.com", Name: "Bob", FilePath: path, Sheet: "Sheet1", Row: 3,
+			OthersMap: map[string]string{"Joined": "", "Seen": "2024-01-01 12:00:00", "Score": "", "Active": ""},
+		},
+	}
+	if !reflect.DeepEqual(list, want) {
+		t.Errorf("records = %#v, want %#v", list, want)
+	}
+}
+
+func TestRecordReaderMissingColumns(t *testing.T) {
+	path := writeFile(t, "contacts.csv", "Phone,City\n555,Oslo\n")
+	reader, err := OpenRecordReader(path, ReaderOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+	if reader.Next() {
+		t.Fatalf("Next() = true for a file without an Email column")
+	}
+	if reader.Err() == nil {
+		t.Errorf("Err() = nil, want a missing columns error")
+	}
+}
@@ -8,380 +8,206 @@ import (
 	"path/filepath"
 	"strings"
 	"website-copier/cmd/utils"
-
-	"github.com/tealeg/xlsx"
 )
 
 type Record struct {
-	Name      string
-	OrgName   string
-	Email     string
-	Others    []string
-	OthersMap map[string]string
-	FilePath  string
+	Name       string
+	OrgName    string
+	Email      string
+	OthersMap  map[string]string
+	FilePath   string
+	Sheet      string   // Sheet of a workbook the record came from, empty for CSV and TSV files
+	SheetIndex int      // 0-based position of the sheet in the workbook
+	Row        int      // 1-based row number in the source file or sheet
+	Columns    *Columns // Header mapping of the sheet the record came from
+	Sources    []string // Files and sheets of the duplicates merged into the record, in file order
 }
 
-// Load records from CSV or XLSX file
-func LoadRecords(filename string) ([]Record, []string, error) {
+// Source names the file, and the sheet if any, the record came from
+func (r Record) Source() string {
+	return SheetKey(r.FilePath, r.Sheet)
+}
 
-	ext := strings.ToLower(filepath.Ext(filename))
-	if ext == ".csv" {
-		return loadRecordsFromCSV(filename)
-	} else if ext == ".xlsx" {
-		return loadRecordsFromXLSX(filename)
+// Before orders two records of the same file by sheet, then row
+func (r Record) Before(other Record) bool {
+	if r.SheetIndex != other.SheetIndex {
+		return r.SheetIndex < other.SheetIndex
 	}
-	return nil, nil, fmt.Errorf("unsupported file type: %s", ext)
+	return r.Row < other.Row
 }
 
-func loadRecordsFromCSV(filename string) ([]Record, []string, error) {
-	file, err := os.Open(filename)
-	if err != nil {
-		return nil, nil, err
+// Value returns a column of the record by header name. The standard fields
+// answer to both their own name and the source header they were read from.
+func (r Record) Value(header string) string {
+	if value, ok := r.OthersMap[header]; ok {
+		return value
+	}
+	if r.Columns != nil {
+		switch header {
+		case r.Columns.Header(RoleName):
+			return r.Name
+		case r.Columns.Header(RoleEmail):
+			return r.Email
+		case r.Columns.Header(RoleOrgName):
+			return r.OrgName
+		}
 	}
-	defer file.Close()
-
-	var records []Record
-	reader := csv.NewReader(file)
-	reader.LazyQuotes = true
-	reader.FieldsPerRecord = -1
-	rows, err := reader.ReadAll()
-	if err != nil {
-		return nil, nil, err
+	switch header {
+	case RoleName:
+		return r.Name
+	case RoleEmail:
+		return r.Email
+	case RoleOrgName:
+		return r.OrgName
 	}
+	return ""
+}
 
-	if len(rows) == 0 {
-		return records, nil, nil
+// Select returns a copy of the record carrying only the given columns,
+// so that writers leave every other column blank
+func (r Record) Select(headers []string) Record {
+	selected := Record{
+		OthersMap:  make(map[string]string, len(headers)),
+		FilePath:   r.FilePath,
+		Sheet:      r.Sheet,
+		SheetIndex: r.SheetIndex,
+		Row:        r.Row,
 	}
-
-	headers := sanitizeHeaders(rows[0])
-
-	// Find the required column indexes dynamically, using flexible matching
-	emailIndex := findFlexibleHeaderIndex(headers, "email")
-	nameIndex := findFlexibleHeaderIndex(headers, "name")
-	orgNameIndex := findFlexibleHeaderIndex(headers, "organization") // Optional
-
-	// Skip files if required columns are not found
-	if emailIndex == -1 || nameIndex == -1 {
-		return nil, nil, fmt.Errorf("required columns (Name, Email) not found in CSV file")
+	for _, header := range headers {
+		selected.OthersMap[header] = r.Value(header)
 	}
-
-	// Process rows, start from 1 to skip header
-	for _, row := range rows[1:] {
-		if len(row) <= emailIndex || len(row) <= nameIndex {
-			// Skip rows that don't have enough columns
-			continue
-		}
-
-		// Map headers to values
-		rowMap := make(map[string]string)
-		for i, header := range headers {
-			if i < len(row) {
-				rowMap[header] = row[i]
-			} else {
-				rowMap[header] = ""
-			}
-		}
-
-		email := rowMap[headers[emailIndex]]
-		name := rowMap[headers[nameIndex]]
-		orgName := ""
-		if orgNameIndex != -1 {
-			orgName = rowMap[headers[orgNameIndex]]
-		}
-
-		// Remove standard fields from OthersMap
-		delete(rowMap, headers[nameIndex])
-		delete(rowMap, headers[emailIndex])
-		if orgNameIndex != -1 {
-			delete(rowMap, headers[orgNameIndex])
-		}
-
-		// Collect the record
-		records = append(records, Record{
-			Name:      name,
-			OrgName:   orgName,
-			Email:     email,
-			OthersMap: rowMap,
-			FilePath:  filename,
-		})
-	}
-
-	return records, headers, nil
+	return selected
 }
 
-func loadRecordsFromXLSX(filename string) ([]Record, []string, error) {
-	file, err := xlsx.OpenFile(filename)
+// Load records from CSV or XLSX file, along with the headers of every sheet
+// they came from
+func LoadRecords(filename string, opts ReaderOptions) ([]Record, []string, error) {
+	reader, err := OpenRecordReader(filename, opts)
 	if err != nil {
 		return nil, nil, err
 	}
+	defer reader.Close()
 
 	var records []Record
 	var headers []string
-
-	for _, sheet := range file.Sheets {
-		if len(sheet.Rows) == 0 {
-			continue
-		}
-		headers = sanitizeHeadersXLSX(sheet.Rows[0].Cells)
-
-		// Find the required column indexes dynamically using flexible matching
-		emailIndex := findFlexibleHeaderIndex(headers, "email")
-		nameIndex := findFlexibleHeaderIndex(headers, "name")
-		orgNameIndex := findFlexibleHeaderIndex(headers, "organization") // Optional
-
-		// Skip files if required columns are not found
-		if emailIndex == -1 || nameIndex == -1 {
-			return nil, nil, fmt.Errorf("required columns (Name, Email) not found in XLSX file")
-		}
-
-		// Process rows, start from 1 to skip header
-		for _, row := range sheet.Rows[1:] {
-			if len(row.Cells) <= emailIndex || len(row.Cells) <= nameIndex {
-				// Skip rows that don't have enough columns
-				continue
-			}
-
-			// Map headers to values
-			rowMap := make(map[string]string)
-			for i, header := range headers {
-				if i < len(row.Cells) {
-					rowMap[header] = row.Cells[i].String()
-				} else {
-					rowMap[header] = ""
-				}
-			}
-
-			email := rowMap[headers[emailIndex]]
-			name := rowMap[headers[nameIndex]]
-			orgName := ""
-			if orgNameIndex != -1 {
-				orgName = rowMap[headers[orgNameIndex]]
-			}
-
-			// Remove standard fields from OthersMap
-			delete(rowMap, headers[nameIndex])
-			delete(rowMap, headers[emailIndex])
-			if orgNameIndex != -1 {
-				delete(rowMap, headers[orgNameIndex])
-			}
-
-			// Collect the record
-			records = append(records, Record{
-				Name:      name,
-				OrgName:   orgName,
-				Email:     email,
-				OthersMap: rowMap,
-				FilePath:  filename,
-			})
+	seen := make(map[string]bool)
+	var columns *Columns
+	for reader.Next() {
+		record := reader.Record()
+		if record.Columns != columns {
+			columns = record.Columns
+			headers = mergeHeaders(headers, seen, columns.Headers)
 		}
-
+		records = append(records, record)
 	}
-
-	return records, headers, nil
-}
-
-func LoadCSV(filename string, recordChan chan<- Record) {
-	file, err := os.Open(filename)
-	if err != nil {
-		utils.LogMessage(fmt.Sprintf("Error opening CSV file: %s - %v", filename, err))
-		return
-	}
-	defer file.Close()
-
-	reader := csv.NewReader(file)
-	reader.LazyQuotes = true    // Allows for malformed CSV fields like bare quotes
-	reader.FieldsPerRecord = -1 // Allow variable number of fields per row
-	rows, err := reader.ReadAll()
-	if err != nil {
-		utils.LogMessage(fmt.Sprintf("Error reading CSV file: %s - %v", filename, err))
-		return
+	if err := reader.Err(); err != nil {
+		return nil, nil, err
 	}
-
-	if len(rows) == 0 {
-		utils.LogMessage(fmt.Sprintf("No data found in CSV file: %s", filename))
-		return
+	if headers == nil {
+		headers = reader.Headers()
 	}
+	return records, headers, nil
+}
 
-	headers := sanitizeHeaders(rows[0])
-
-	// Find the required column indexes dynamically, using flexible matching
-	emailIndex := findFlexibleHeaderIndex(headers, "email")
-	nameIndex := findFlexibleHeaderIndex(headers, "name")
-	orgNameIndex := findFlexibleHeaderIndex(headers, "organization") // Optional
-
-	// Skip files if required columns are not found
-	if emailIndex == -1 || nameIndex == -1 {
-		utils.LogMessage(fmt.Sprintf("Required columns (Name, Email) not found in CSV file: %s, skipping...", filename))
-		return
+// mergeHeaders appends the headers not seen yet
+func mergeHeaders(headers []string, seen map[string]bool, more []string) []string {
+	for _, header := range more {
+		if !seen[header] {
+			seen[header] = true
+			headers = append(headers, header)
+		}
 	}
+	return headers
+}
 
-	// Process rows, start from 1 to skip header
-	for _, row := range rows[1:] {
-		if len(row) <= emailIndex || len(row) <= nameIndex {
-			// Skip rows that don't have enough columns
-			continue
-		}
-		email := row[emailIndex]
-		name := row[nameIndex]
-		orgName := ""
-		if orgNameIndex != -1 && len(row) > orgNameIndex {
-			orgName = row[orgNameIndex]
-		}
+// LoadCSV streams the records of a CSV file into recordChan
+func LoadCSV(filename string, opts ReaderOptions, recordChan chan<- Record) {
+	sendRecords(filename, opts, recordChan)
+}
 
-		// Send the record to the channel
-		recordChan <- Record{
-			Name:    name,
-			OrgName: orgName,
-			Email:   email,
-			Others:  excludeColumns(row, []int{nameIndex, orgNameIndex, emailIndex}),
-		}
-	}
+// LoadXLSX streams the records of every sheet of an XLSX file into recordChan
+func LoadXLSX(filename string, opts ReaderOptions, recordChan chan<- Record) {
+	sendRecords(filename, opts, recordChan)
 }
 
-func LoadXLSX(filename string, recordChan chan<- Record) {
-	file, err := xlsx.OpenFile(filename)
+func sendRecords(filename string, opts ReaderOptions, recordChan chan<- Record) {
+	reader, err := OpenRecordReader(filename, opts)
 	if err != nil {
-		utils.LogMessage(fmt.Sprintf("Error opening XLSX file: %s - %v", filename, err))
+		utils.LogMessage(fmt.Sprintf("Error opening file: %s - %v", filename, err))
 		return
 	}
+	defer reader.Close()
 
-	for _, sheet := range file.Sheets {
-		if len(sheet.Rows) == 0 {
-			utils.LogMessage(fmt.Sprintf("No data found in XLSX file: %s", filename))
-			continue
-		}
-		headers := sanitizeHeadersXLSX(sheet.Rows[0].Cells)
-
-		// Find the required column indexes dynamically using flexible matching
-		emailIndex := findFlexibleHeaderIndex(headers, "email")
-		nameIndex := findFlexibleHeaderIndex(headers, "name")
-		orgNameIndex := findFlexibleHeaderIndex(headers, "organization") // Optional
-
-		// Skip files if required columns are not found
-		if emailIndex == -1 || nameIndex == -1 {
-			utils.LogMessage(fmt.Sprintf("Required columns (Name, Email) not found in XLSX file: %s, skipping...", filename))
-			continue
-		}
-
-		// Process rows, start from 1 to skip header
-		for _, row := range sheet.Rows[1:] {
-			if len(row.Cells) <= emailIndex || len(row.Cells) <= nameIndex {
-				// Skip rows that don't have enough columns
-				continue
-			}
-			email := row.Cells[emailIndex].String()
-			name := row.Cells[nameIndex].String()
-			orgName := ""
-			if orgNameIndex != -1 && len(row.Cells) > orgNameIndex {
-				orgName = row.Cells[orgNameIndex].String()
-			}
-
-			// Send the record to the channel
-			recordChan <- Record{
-				Name:    name,
-				OrgName: orgName,
-				Email:   email,
-				Others:  getRowDataExcluding(row, []int{nameIndex, orgNameIndex, emailIndex}),
-			}
-		}
+	for reader.Next() {
+		// Send the record to the channel
+		recordChan <- reader.Record()
 	}
-}
-
-func WriteCSV(filename string, recordsMap map[string]Record) error {
-	file, err := os.Create(filename)
-	if err != nil {
-		return err
+	if err := reader.Err(); err != nil {
+		utils.LogMessage(fmt.Sprintf("Error reading file: %s - %v, skipping...", filename, err))
 	}
-	defer file.Close()
-
-	writer := csv.NewWriter(file)
-	defer writer.Flush()
+}
 
-	// Write header
-	err = writer.Write([]string{"Name", "OrgName", "Email", "Others"})
+// WriteRecords writes the records to a new CSV or XLSX file laid out under the given schema
+func WriteRecords(filename string, schema Columns, records []Record, opts WriterOptions) error {
+	writer, err := CreateSchemaWriter(filename, schema, opts)
 	if err != nil {
 		return err
 	}
 
 	// Write records
-	for _, record := range recordsMap {
-		row := append([]string{record.Name, record.OrgName, record.Email}, record.Others...)
-		err = writer.Write(row)
-		if err != nil {
+	for _, record := range records {
+		if err := writer.Write(record); err != nil {
+			writer.Close()
 			return err
 		}
 	}
 
-	return nil
+	return writer.Close()
 }
 
 func WriteFilteredCSV(filename string, headers []string, records []Record) error {
-	file, err := os.Create(filename)
+	writer, err := CreateRecordWriter(filename, headers, WriterOptions{})
 	if err != nil {
 		return err
 	}
-	defer file.Close()
 
-	writer := csv.NewWriter(file)
-	defer writer.Flush()
-
-	// Write header
-	err = writer.Write(headers)
-	if err != nil {
-		return err
-	}
 	// Write records
 	for _, record := range records {
-		// Map field names to values
-		recordMap := map[string]string{
-			"Name":    record.Name,
-			"OrgName": record.OrgName,
-			"Email":   record.Email,
-		}
-
-		// Merge with OthersMap
-		for k, v := range record.OthersMap {
-			fmt.Printf("k: %s, v: %s", k, v)
-			recordMap[k] = v
-		}
-
-		// Prepare the row data based on headers
-		var row []string
-		for _, header := range headers {
-			if val, ok := recordMap[header]; ok {
-				row = append(row, val)
-			} else {
-				// Header not found in record, append empty string
-				row = append(row, "")
-			}
-		}
-
-		fmt.Printf("Final row data: %v", row)
-
-		err = writer.Write(row)
-		if err != nil {
+		if err := writer.Write(record); err != nil {
+			writer.Close()
 			return err
 		}
 	}
 
-	return nil
+	return writer.Close()
 }
 
-// AppendCSV appends records to an existing CSV file
-func AppendCSV(filename string, recordsMap map[string]Record) error {
+// AppendRecords appends records to an existing CSV or XLSX file, laying them out
+// under its existing headers. Columns the file does not have are dropped.
+func AppendRecords(filename string, headers []string, records []Record) error {
+	schema := ColumnMapping{}.ResolveColumns(filename, headers)
+	if strings.ToLower(filepath.Ext(filename)) == ".xlsx" {
+		return appendXLSX(filename, schema, records)
+	}
+
+	// Append in the delimiter and encoding the file was written in
+	sniffed, err := sniffFile(filename)
+	if err != nil {
+		return err
+	}
 	file, err := os.OpenFile(filename, os.O_APPEND|os.O_WRONLY, 0666)
 	if err != nil {
 		return err
 	}
 	defer file.Close()
 
-	writer := csv.NewWriter(file)
+	writer := csv.NewWriter(sniffed.encode(file))
+	writer.Comma = sniffed.delimiter
 	defer writer.Flush()
 
 	// Append records
-	for _, record := range recordsMap {
-		row := append([]string{record.Name, record.OrgName, record.Email}, record.Others...)
-		err = writer.Write(row)
+	for _, record := range records {
+		err = writer.Write(schema.Row(record))
 		if err != nil {
 			return err
 		}
@@ -390,187 +216,46 @@ func AppendCSV(filename string, recordsMap map[string]Record) error {
 	return nil
 }
 
-func LoadEmailsFromCSV(filename string) (map[string]bool, error) {
-	file, err := os.Open(filename)
-	if err != nil {
-		return nil, err
-	}
-	defer file.Close()
-
-	emails := make(map[string]bool)
-	reader := csv.NewReader(file)
-	reader.LazyQuotes = true
-	reader.FieldsPerRecord = -1
-	rows, err := reader.ReadAll()
-	if err != nil {
-		return nil, err
-	}
-
-	if len(rows) == 0 {
-		return emails, nil
-	}
-
-	headers := sanitizeHeaders(rows[0])
-	emailIndex := findFlexibleHeaderIndex(headers, "email")
-	if emailIndex == -1 {
-		return nil, fmt.Errorf("email column not found in database file")
-	}
-
-	for _, row := range rows[1:] {
-		if len(row) > emailIndex {
-			email := row[emailIndex]
-			emails[email] = true
-		}
-	}
-
-	return emails, nil
-}
-
+// ValidateHeaders checks that the headers hold both a Name and an Email column
 func ValidateHeaders(headers []string) bool {
-	requiredHeaders := []string{"Name", "Email"}
-	for _, reqHeader := range requiredHeaders {
-		if findFlexibleHeaderIndex(headers, reqHeader) == -1 {
-			return false
-		}
-	}
-	return true
-}
-
-func findFlexibleHeaderIndex(headers []string, keyword string) int {
-	for i, h := range headers {
-		h = strings.TrimSpace(h)
-		h = strings.Trim(h, `"`) // Remove surrounding quotes
-		if strings.Contains(strings.ToLower(h), strings.ToLower(keyword)) {
-			return i
-		}
-	}
-	return -1
+	cols := ColumnMapping{}.ResolveColumns("", headers)
+	return cols.Name != -1 && cols.Email != -1
 }
 
 func sanitizeHeaders(headers []string) []string {
 	for i := range headers {
-		headers[i] = strings.Trim(headers[i], `"`) // Remove surrounding quotes
-		headers[i] = strings.TrimSpace(headers[i]) // Remove any extra spaces
+		headers[i] = strings.TrimPrefix(headers[i], "\ufeff") // Remove a stray byte order mark
+		headers[i] = strings.Trim(headers[i], `"`)            // Remove surrounding quotes
+		headers[i] = strings.TrimSpace(headers[i])            // Remove any extra spaces
 	}
 	return headers
 }
 
-func sanitizeHeadersXLSX(headers []*xlsx.Cell) []string {
-	var sanitizedHeaders []string
-	for _, cell := range headers {
-		header := strings.Trim(cell.String(), `"`) // Remove surrounding quotes
-		header = strings.TrimSpace(header)         // Remove any extra spaces
-		sanitizedHeaders = append(sanitizedHeaders, header)
-	}
-	return sanitizedHeaders
-}
-
-func excludeColumns(row []string, excludeIndexes []int) []string {
-	var others []string
-	for i, col := range row {
-		if !contains(excludeIndexes, i) {
-			others = append(others, col)
-		}
-	}
-	return others
-}
-
 func GetCSVHeaders(filePath string) ([]string, error) {
-
-	file, err := os.Open(filePath)
-
-	if err != nil {
-
-		return nil, err
-
-	}
-
-	defer file.Close()
-
-	reader := csv.NewReader(file)
-
-	headers, err := reader.Read()
-
+	headers, err := GetHeaders(filePath)
 	if err != nil {
-
 		return nil, err
-
 	}
-
 	log.Printf("Headers: %v", headers)
 	return headers, nil
-
 }
 
-func getRowData(row *xlsx.Row) []string {
-	var data []string
-	for _, cell := range row.Cells {
-		data = append(data, cell.String())
-	}
-	return data
-}
-
-// GetHeaders reads the headers from a CSV or XLSX file
+// GetHeaders reads the headers from a CSV or XLSX file,
+// those of every sheet in order
 func GetHeaders(filename string) ([]string, error) {
-	ext := strings.ToLower(filepath.Ext(filename))
-	if ext == ".csv" {
-		return getHeadersFromCSV(filename)
-	} else if ext == ".xlsx" {
-		return getHeadersFromXLSX(filename)
-	}
-	return nil, fmt.Errorf("unsupported file type: %s", ext)
+	return ReadHeaders(filename, ColumnMapping{})
 }
 
-func getHeadersFromCSV(filename string) ([]string, error) {
-	file, err := os.Open(filename)
+// ReadHeaders is like GetHeaders with the CSV, header row and sheet overrides of a mapping
+func ReadHeaders(filename string, mapping ColumnMapping) ([]string, error) {
+	sheets, err := GetSheetHeaders(filename, HeaderOptions{Mapping: mapping})
 	if err != nil {
 		return nil, err
 	}
-	defer file.Close()
-
-	reader := csv.NewReader(file)
-	reader.LazyQuotes = true
-	reader.FieldsPerRecord = -1
-
-	row, err := reader.Read() // Read only the first line
-	if err != nil {
-		return nil, err
+	var headers []string
+	seen := make(map[string]bool)
+	for _, sheet := range sheets {
+		headers = mergeHeaders(headers, seen, sheet.Headers)
 	}
-
-	headers := sanitizeHeaders(row)
 	return headers, nil
 }
-
-func getHeadersFromXLSX(filename string) ([]string, error) {
-	file, err := xlsx.OpenFile(filename)
-	if err != nil {
-		return nil, err
-	}
-
-	for _, sheet := range file.Sheets {
-		if len(sheet.Rows) > 0 {
-			headers := sanitizeHeadersXLSX(sheet.Rows[0].Cells)
-			return headers, nil
-		}
-	}
-	return nil, fmt.Errorf("no headers found in XLSX file")
-}
-
-func getRowDataExcluding(row *xlsx.Row, excludeIndexes []int) []string {
-	var others []string
-	for i, cell := range row.Cells {
-		if !contains(excludeIndexes, i) {
-			others = append(others, cell.String())
-		}
-	}
-	return others
-}
-
-func contains(slice []int, elem int) bool {
-	for _, e := range slice {
-		if e == elem {
-			return true
-		}
-	}
-	return false
-}
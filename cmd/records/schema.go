@@ -0,0 +1,99 @@
+package records
+
+import (
+	"sort"
+	"strings"
+)
+
+// StandardHeaders are the leading columns of a combined file
+var StandardHeaders = []string{"Name", "OrgName", "Email"}
+
+// SchemaOptions controls the column order of a combined file
+type SchemaOptions struct {
+	Order []string // Columns placed first, in this order
+	Sort  bool     // Sort the remaining columns alphabetically instead of by first appearance
+}
+
+// UnifiedSchema builds the layout of a combined file: the standard fields
+// followed by the union of every other header of the sources, in the order
+// the sources are given
+func UnifiedSchema(sources []*Columns, opts SchemaOptions) Columns {
+	// Source columns named like a standard field would shadow it, so they are left out
+	seen := make(map[string]bool)
+	standard := make(map[string]bool)
+	for _, header := range StandardHeaders {
+		standard[strings.ToLower(header)] = true
+	}
+
+	var extras []string
+	for _, source := range sources {
+		for i, header := range source.Headers {
+			if i == source.Name || i == source.Email || i == source.OrgName {
+				continue
+			}
+			if header == "" || seen[header] || standard[strings.ToLower(header)] {
+				continue
+			}
+			seen[header] = true
+			extras = append(extras, header)
+		}
+	}
+	if opts.Sort {
+		sort.SliceStable(extras, func(a, b int) bool {
+			return strings.ToLower(extras[a]) < strings.ToLower(extras[b])
+		})
+	}
+
+	// Pull the explicitly ordered columns to the front
+	headers := append(append([]string(nil), StandardHeaders...), extras...)
+	if len(opts.Order) > 0 {
+		var ordered, rest []string
+		placed := make(map[string]bool)
+		for _, want := range opts.Order {
+			for _, header := range headers {
+				if strings.EqualFold(header, strings.TrimSpace(want)) && !placed[header] {
+					ordered = append(ordered, header)
+					placed[header] = true
+				}
+			}
+		}
+		for _, header := range headers {
+			if !placed[header] {
+				rest = append(rest, header)
+			}
+		}
+		headers = append(ordered, rest...)
+	}
+
+	schema := Columns{Headers: headers, Name: -1, Email: -1, OrgName: -1}
+	for i, header := range headers {
+		switch header {
+		case "Name":
+			schema.Name = i
+		case "OrgName":
+			schema.OrgName = i
+		case "Email":
+			schema.Email = i
+		}
+	}
+	return schema
+}
+
+// Row lays a record out under these columns: the mapped columns take the
+// record's standard fields and every other column is looked up by name
+func (c *Columns) Row(record Record) []string {
+	row := make([]string, len(c.Headers))
+	for i, header := range c.Headers {
+		switch i {
+		case c.Name:
+			row[i] = record.Name
+		case c.OrgName:
+			row[i] = record.OrgName
+		case c.Email:
+			row[i] = record.Email
+		default:
+			row[i] = record.OthersMap[header]
+		}
+	}
+	return row
+}
@@ -0,0 +1,61 @@
+package records
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestUnifiedSchema(t *testing.T) {
+	people := &Columns{Headers: []string{"Name", "Email", "Phone", "City"}, Name: 0, Email: 1, OrgName: -1}
+	leads := &Columns{Headers: []string{"E-mail", "Fax", "Phone", "Company"}, Name: -1, Email: 0, OrgName: 3}
+	// The resolved name column is Contact; the source's own Name and orgname
+	// columns would shadow the standard fields
+	shadowing := &Columns{Headers: []string{"Contact", "Email", "Name", "orgname", "Notes"}, Name: 0, Email: 1, OrgName: -1}
+	duplicates := &Columns{Headers: []string{"Name", "Email", "Phone", "Phone", ""}, Name: 0, Email: 1, OrgName: -1}
+
+	tests := []struct {
+		name    string
+		sources []*Columns
+		opts    SchemaOptions
+		want    []string
+	}{
+		{"first appearance", []*Columns{people, leads}, SchemaOptions{}, []string{"Name", "OrgName", "Email", "Phone", "City", "Fax"}},
+		{"source order", []*Columns{leads, people}, SchemaOptions{}, []string{"Name", "OrgName", "Email", "Fax", "Phone", "City"}},
+		{"standard names dropped", []*Columns{shadowing}, SchemaOptions{}, []string{"Name", "OrgName", "Email", "Notes"}},
+		{"duplicate headers", []*Columns{duplicates}, SchemaOptions{}, []string{"Name", "OrgName", "Email", "Phone"}},
+		{"sorted", []*Columns{people, leads}, SchemaOptions{Sort: true}, []string{"Name", "OrgName", "Email", "City", "Fax", "Phone"}},
+		{"ordered", []*Columns{people, leads}, SchemaOptions{Order: []string{"email", " Fax", "Missing"}}, []string{"Email", "Fax", "Name", "OrgName", "Phone", "City"}},
+		{"no sources", nil, SchemaOptions{}, []string{"Name", "OrgName", "Email"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schema := UnifiedSchema(tt.sources, tt.opts)
+			if !reflect.DeepEqual(schema.Headers, tt.want) {
+				t.Errorf("headers = %q, want %q", schema.Headers, tt.want)
+			}
+			for _, role := range []string{RoleName, RoleEmail, RoleOrgName} {
+				if got := schema.Header(role); got != role {
+					t.Errorf("Header(%s) = %q, want %q", role, got, role)
+				}
+			}
+		})
+	}
+}
+
+func TestColumnsRow(t *testing.T) {
+	schema := UnifiedSchema([]*Columns{
+		{Headers: []string{"Contact", "Email", "Phone"}, Name: 0, Email: 1, OrgName: -1},
+		{Headers: []string{"Email", "City"}, Name: -1, Email: 0, OrgName: -1},
+	}, SchemaOptions{Order: []string{"Email"}})
+
+	record := Record{
+		Name:      "Ann",
+		OrgName:   "Acme",
+		Email:     "ann@acme.com",
+		OthersMap: map[string]string{"Phone": "555", "Unknown": "dropped"},
+	}
+	want := []string{"ann@acme.com", "Ann", "Acme", "555", ""}
+	if got := schema.Row(record); !reflect.DeepEqual(got, want) {
+		t.Errorf("Row() = %q, want %q", got, want)
+	}
+}
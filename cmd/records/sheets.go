@@ -0,0 +1,173 @@
+package records
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"website-copier/cmd/utils"
+)
+
+// SheetSeparator joins a workbook and one of its sheets, e.g. contacts.xlsx:Leads.
+// Sheet names can't contain it, so the last one splits the two.
+const SheetSeparator = ":"
+
+// SheetKey names a sheet of a file in mappings, or the file itself when sheet is empty
+func SheetKey(file, sheet string) string {
+	if sheet == "" {
+		return file
+	}
+	return file + SheetSeparator + sheet
+}
+
+// SplitSheet splits a key like contacts.xlsx:Leads into the workbook and the
+// sheet. Keys naming no sheet, including Windows paths like C:\leads.xlsx,
+// come back with an empty sheet.
+func SplitSheet(key string) (string, string) {
+	i := strings.LastIndex(key, SheetSeparator)
+	if i <= 0 {
+		return key, ""
+	}
+	file, sheet := key[:i], key[i+1:]
+	if sheet == "" || strings.ContainsAny(sheet, `/\`) || strings.ToLower(filepath.Ext(file)) != ".xlsx" {
+		return key, ""
+	}
+	return file, sheet
+}
+
+// SplitSheetInput is like SplitSheet for an input path, leaving alone paths
+// of existing files
+func SplitSheetInput(input string) (string, string) {
+	if _, err := os.Stat(input); err == nil {
+		return input, ""
+	}
+	return SplitSheet(input)
+}
+
+// SheetNames lists the sheets of a workbook in order, or nothing for a CSV or TSV file
+func SheetNames(filename string) ([]string, error) {
+	if strings.ToLower(filepath.Ext(filename)) != ".xlsx" {
+		return nil, nil
+	}
+	x, err := openXLSXRows(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer x.Close()
+	names := make([]string, len(x.sheets))
+	for i, sheet := range x.sheets {
+		names[i] = sheet.name
+	}
+	return names, nil
+}
+
+// SheetSelected tells whether a sheet of a file is read. Sheets limits the
+// sheets of the workbooks it names, then SkipSheets leaves sheets out.
+func (m ColumnMapping) SheetSelected(file, sheet string) bool {
+	if sheet == "" {
+		return true
+	}
+	matches := func(entry string) (applies, match bool) {
+		f, s := SplitSheet(entry)
+		if s == "" {
+			// A sheet name for every workbook
+			return true, entry == sheet
+		}
+		applies = f == file || f == filepath.Base(file)
+		return applies, applies && s == sheet
+	}
+	limited, included := false, false
+	for _, entry := range m.Sheets {
+		applies, match := matches(entry)
+		limited = limited || applies
+		included = included || match
+	}
+	if limited && !included {
+		return false
+	}
+	for _, entry := range m.SkipSheets {
+		if _, match := matches(entry); match {
+			return false
+		}
+	}
+	return true
+}
+
+// SetSheetSelected includes or excludes a sheet of a file
+func (m *ColumnMapping) SetSheetSelected(file, sheet string, selected bool) {
+	key := SheetKey(file, sheet)
+	kept := m.SkipSheets[:0]
+	for _, entry := range m.SkipSheets {
+		if entry != key {
+			kept = append(kept, entry)
+		}
+	}
+	m.SkipSheets = kept
+	if !selected {
+		m.SkipSheets = append(m.SkipSheets, key)
+	}
+}
+
+// selectedSheets drops the rows of the sheets a mapping leaves out
+type selectedSheets struct {
+	rows     rowSource
+	filename string
+	mapping  ColumnMapping
+	quiet    bool // Don't log the sheets skipped
+
+	sheet    string
+	started  bool
+	skipping bool
+	skipped  int // Sheets skipped so far
+	read     int // Sheets read so far
+}
+
+// selectSheets wraps a row source so only the selected sheets are read
+func selectSheets(rows rowSource, filename string, mapping ColumnMapping, quiet bool) rowSource {
+	if len(mapping.Sheets) == 0 && len(mapping.SkipSheets) == 0 {
+		return rows
+	}
+	return &selectedSheets{rows: rows, filename: filename, mapping: mapping, quiet: quiet}
+}
+
+func (s *selectedSheets) next() (rawRow, error) {
+	for {
+		row, err := s.rows.next()
+		if err == io.EOF && s.read == 0 && s.skipped > 0 && !s.quiet {
+			utils.LogMessage(fmt.Sprintf("None of the sheets of %s are selected", s.filename))
+		}
+		if err != nil {
+			return row, err
+		}
+		if !s.started || row.sheet != s.sheet {
+			s.started, s.sheet = true, row.sheet
+			s.skipping = !s.mapping.SheetSelected(s.filename, row.sheet)
+			if !s.skipping {
+				s.read++
+				return row, nil
+			}
+			s.skipped++
+			if !s.quiet {
+				utils.LogMessage(fmt.Sprintf("Skipping sheet %s of %s, it is not selected", row.sheet, s.filename))
+			}
+			s.skipSheet()
+			continue
+		}
+		if !s.skipping {
+			return row, nil
+		}
+	}
+}
+
+// skipSheet moves on to the next sheet of a workbook
+func (s *selectedSheets) skipSheet() {
+	if x, ok := s.rows.(*xlsxRows); ok {
+		x.skipSheet()
+	}
+}
+
+func (s *selectedSheets) Close() error {
+	return s.rows.Close()
+}
@@ -0,0 +1,408 @@
+package records
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// Encodings a CSV file can be read in
+const (
+	EncodingUTF8        = "utf-8"
+	EncodingUTF16LE     = "utf-16le" // Excel's "Unicode Text"
+	EncodingUTF16BE     = "utf-16be"
+	EncodingWindows1252 = "windows-1252"
+	EncodingLatin1      = "iso-8859-1"
+)
+
+// Encodings lists every encoding for the CLI
+var Encodings = []string{EncodingUTF8, EncodingUTF16LE, EncodingUTF16BE, EncodingWindows1252, EncodingLatin1}
+
+// QuoteNone reads every quote character as part of the field
+const QuoteNone = "none"
+
+// Dialect describes how a CSV file is written. Empty fields are detected from
+// the start of the file.
+type Dialect struct {
+	Delimiter string `json:"delimiter,omitempty"` // A single character, or "tab"
+	Quote     string `json:"quote,omitempty"`     // A single character, or "none"
+	Encoding  string `json:"encoding,omitempty"`
+}
+
+// Validate checks that the overrides can be used
+func (d Dialect) Validate() error {
+	if d.Delimiter != "" {
+		if r, ok := dialectRune(d.Delimiter); !ok || r == '"' || r == '\n' || r == '\r' {
+			return fmt.Errorf("invalid CSV delimiter: %q", d.Delimiter)
+		}
+	}
+	if d.Quote != "" && d.Quote != QuoteNone {
+		if r, ok := dialectRune(d.Quote); !ok || r >= utf8.RuneSelf {
+			return fmt.Errorf("invalid CSV quote character: %q (expected a single ASCII character or %s)", d.Quote, QuoteNone)
+		}
+	}
+	if d.Encoding != "" && lookupEncoding(d.Encoding) == nil {
+		return fmt.Errorf("unknown encoding: %s (expected one of %s)", d.Encoding, strings.Join(Encodings, ", "))
+	}
+	return nil
+}
+
+// Merge returns d with the fields set in other replacing its own
+func (d Dialect) Merge(other Dialect) Dialect {
+	if other.Delimiter != "" {
+		d.Delimiter = other.Delimiter
+	}
+	if other.Quote != "" {
+		d.Quote = other.Quote
+	}
+	if other.Encoding != "" {
+		d.Encoding = other.Encoding
+	}
+	return d
+}
+
+// dialectRune reads a delimiter or quote given as one character or by name
+func dialectRune(s string) (rune, bool) {
+	switch strings.ToLower(s) {
+	case "tab", `\t`:
+		return '\t', true
+	case "semicolon":
+		return ';', true
+	case "comma":
+		return ',', true
+	case "pipe":
+		return '|', true
+	}
+	r, size := utf8.DecodeRuneInString(s)
+	return r, r != utf8.RuneError && size == len(s)
+}
+
+func lookupEncoding(name string) encoding.Encoding {
+	switch strings.ToLower(strings.ReplaceAll(name, "_", "-")) {
+	case EncodingUTF8, "utf8":
+		return unicode.UTF8
+	case EncodingUTF16LE, "utf16le", "utf-16", "unicode":
+		return unicode.UTF16(unicode.LittleEndian, unicode.UseBOM)
+	case EncodingUTF16BE, "utf16be":
+		return unicode.UTF16(unicode.BigEndian, unicode.UseBOM)
+	case EncodingWindows1252, "cp1252", "ansi":
+		return charmap.Windows1252
+	case EncodingLatin1, "latin1", "latin-1":
+		return charmap.ISO8859_1
+	}
+	return nil
+}
+
+// sniffSize is how much of a file is looked at to detect its dialect
+const sniffSize = 64 * 1024
+
+// delimiters are the candidate delimiters, in the order ties are broken
+var delimiters = []rune{',', ';', '\t', '|'}
+
+// sniffedCSV is a CSV file decoded to UTF-8, with what was detected about it
+type sniffedCSV struct {
+	reader    io.Reader
+	encoding  string
+	bom       bool
+	delimiter rune
+	quote     rune // 0 when quotes are not special
+}
+
+// sniffCSV detects the encoding, byte order mark, delimiter and quote character
+// of a CSV file, using the overrides where given. preferred is the delimiter
+// assumed for files of a single column.
+func sniffCSV(r io.Reader, override Dialect, preferred rune) (*sniffedCSV, error) {
+	if err := override.Validate(); err != nil {
+		return nil, err
+	}
+	buffered := bufio.NewReaderSize(r, sniffSize)
+	sample, err := buffered.Peek(sniffSize)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return nil, err
+	}
+	complete := err == io.EOF
+	result := &sniffedCSV{}
+
+	// Encoding and byte order mark
+	result.encoding, result.bom = sniffEncoding(sample)
+	if override.Encoding != "" {
+		// A byte order mark is only dropped when it belongs to the encoding given
+		result.bom = result.bom && lookupEncoding(override.Encoding) == lookupEncoding(result.encoding)
+		result.encoding = strings.ToLower(override.Encoding)
+	}
+	var text []byte
+	switch enc := lookupEncoding(result.encoding); enc {
+	case unicode.UTF8:
+		if result.bom {
+			buffered.Discard(3)
+			sample = sample[3:]
+		}
+		result.reader = buffered
+		text = sample
+	default:
+		// UTF-16 decoders drop the byte order mark themselves
+		result.reader = enc.NewDecoder().Reader(buffered)
+		if !complete && len(sample)%2 == 1 {
+			sample = sample[:len(sample)-1]
+		}
+		text, _ = enc.NewDecoder().Bytes(sample)
+		text = bytes.TrimPrefix(text, []byte("\ufeff"))
+	}
+
+	lines := strings.Split(strings.ReplaceAll(string(text), "\r\n", "\n"), "\n")
+	if !complete && len(lines) > 1 {
+		// The last line was cut by the sample size
+		lines = lines[:len(lines)-1]
+	}
+
+	result.quote = '"'
+	switch {
+	case override.Quote == QuoteNone:
+		result.quote = 0
+	case override.Quote != "":
+		result.quote, _ = dialectRune(override.Quote)
+	default:
+		result.quote = sniffQuote(lines)
+	}
+	if override.Delimiter != "" {
+		result.delimiter, _ = dialectRune(override.Delimiter)
+	} else {
+		result.delimiter = sniffDelimiter(lines, result.quote, preferred)
+	}
+
+	if result.quote != '"' {
+		result.reader = &quoteSwapper{reader: result.reader, quote: result.quote}
+	}
+	return result, nil
+}
+
+// sniffFile detects the dialect of an existing CSV or TSV file
+func sniffFile(filename string) (*sniffedCSV, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	comma := ','
+	if strings.ToLower(filepath.Ext(filename)) == ".tsv" {
+		comma = '\t'
+	}
+	return sniffCSV(file, Dialect{}, comma)
+}
+
+// encode wraps w so text written to it is encoded like the sniffed file,
+// without a byte order mark. Characters the encoding lacks are replaced.
+func (s *sniffedCSV) encode(w io.Writer) io.Writer {
+	switch enc := lookupEncoding(s.encoding); enc {
+	case unicode.UTF8:
+		return w
+	case lookupEncoding(EncodingUTF16LE):
+		return unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM).NewEncoder().Writer(w)
+	case lookupEncoding(EncodingUTF16BE):
+		return unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM).NewEncoder().Writer(w)
+	default:
+		return encoding.ReplaceUnsupported(enc.NewEncoder()).Writer(w)
+	}
+}
+
+// describe tells how the file is read when it is not plain comma separated UTF-8
+func (s *sniffedCSV) describe() string {
+	var parts []string
+	if lookupEncoding(s.encoding) != unicode.UTF8 {
+		parts = append(parts, s.encoding)
+	}
+	if s.bom {
+		parts = append(parts, "byte order mark removed")
+	}
+	if s.delimiter != ',' {
+		parts = append(parts, fmt.Sprintf("delimiter %s", runeName(s.delimiter)))
+	}
+	if s.quote != '"' {
+		parts = append(parts, fmt.Sprintf("quote %s", runeName(s.quote)))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func runeName(r rune) string {
+	switch r {
+	case 0:
+		return QuoteNone
+	case '\t':
+		return "tab"
+	}
+	return fmt.Sprintf("%q", r)
+}
+
+// sniffEncoding tells the encoding of a file from its byte order mark, the
+// zero bytes of UTF-16 text, or whether it is valid UTF-8. Only the sample,
+// the first sniffSize (64KB) of the file, is looked at: a file whose first
+// non-UTF-8 byte comes later is taken for UTF-8, so give its encoding instead.
+func sniffEncoding(sample []byte) (string, bool) {
+	switch {
+	case bytes.HasPrefix(sample, []byte{0xEF, 0xBB, 0xBF}):
+		return EncodingUTF8, true
+	case bytes.HasPrefix(sample, []byte{0xFF, 0xFE}):
+		return EncodingUTF16LE, true
+	case bytes.HasPrefix(sample, []byte{0xFE, 0xFF}):
+		return EncodingUTF16BE, true
+	}
+
+	// ASCII text in UTF-16 has a zero in every other byte
+	var evenZeros, oddZeros int
+	for i, b := range sample {
+		if b == 0 {
+			if i%2 == 0 {
+				evenZeros++
+			} else {
+				oddZeros++
+			}
+		}
+	}
+	if half := len(sample) / 2; half > 0 {
+		if oddZeros > half*3/10 && evenZeros < oddZeros/10 {
+			return EncodingUTF16LE, false
+		}
+		if evenZeros > half*3/10 && oddZeros < evenZeros/10 {
+			return EncodingUTF16BE, false
+		}
+	}
+
+	// A multi-byte character may have been cut at the end of the sample
+	valid := sample
+	for i := 0; i < utf8.UTFMax-1 && len(valid) > 0 && !utf8.Valid(valid); i++ {
+		valid = valid[:len(valid)-1]
+	}
+	if utf8.Valid(valid) {
+		return EncodingUTF8, false
+	}
+	return EncodingWindows1252, false
+}
+
+// sniffQuote picks the double quote unless fields are only wrapped in single quotes
+func sniffQuote(lines []string) rune {
+	counts := make(map[rune]int)
+	for _, line := range lines {
+		for _, q := range []rune{'"', '\''} {
+			for i, r := range line {
+				if r != q {
+					continue
+				}
+				// Count quotes opening a field or closing one
+				before, _ := utf8.DecodeLastRuneInString(line[:i])
+				after, _ := utf8.DecodeRuneInString(line[i+1:])
+				if i == 0 || isDelimiter(before) || i == len(line)-1 || isDelimiter(after) {
+					counts[q]++
+				}
+			}
+		}
+	}
+	if counts['"'] == 0 && counts['\''] >= 2 {
+		return '\''
+	}
+	return '"'
+}
+
+func isDelimiter(r rune) bool {
+	for _, d := range delimiters {
+		if r == d {
+			return true
+		}
+	}
+	return false
+}
+
+// sniffDelimiter picks the delimiter found on the header line that splits the
+// most lines into as many fields as the header
+func sniffDelimiter(lines []string, quote rune, preferred rune) rune {
+	candidates := []rune{preferred}
+	for _, d := range delimiters {
+		if d != preferred {
+			candidates = append(candidates, d)
+		}
+	}
+	best, bestConsistent, bestCount := preferred, 0, 0
+	for _, d := range candidates {
+		header, consistent := -1, 0
+		for _, line := range lines {
+			if strings.TrimSpace(line) == "" {
+				continue
+			}
+			count := countOutsideQuotes(line, d, quote)
+			if header == -1 {
+				header = count
+			}
+			if count == header {
+				consistent++
+			}
+		}
+		if header <= 0 {
+			continue
+		}
+		if consistent > bestConsistent || consistent == bestConsistent && header > bestCount {
+			best, bestConsistent, bestCount = d, consistent, header
+		}
+	}
+	return best
+}
+
+func countOutsideQuotes(line string, delimiter, quote rune) int {
+	count, quoted := 0, false
+	for _, r := range line {
+		switch {
+		case quote != 0 && r == quote:
+			quoted = !quoted
+		case r == delimiter && !quoted:
+			count++
+		}
+	}
+	return count
+}
+
+// quoteSwapper swaps the quote character of a file with the double quote the
+// csv package expects; unswapQuotes turns the fields back. Both characters are
+// ASCII, so swapping bytes never touches multi-byte characters.
+type quoteSwapper struct {
+	reader io.Reader
+	quote  rune
+}
+
+func (s *quoteSwapper) Read(p []byte) (int, error) {
+	n, err := s.reader.Read(p)
+	swapQuotes(p[:n], s.quote)
+	return n, err
+}
+
+// unswapQuotes restores the quote characters of cells read through a quoteSwapper
+func unswapQuotes(cells []string, quote rune) {
+	for i, cell := range cells {
+		b := []byte(cell)
+		swapQuotes(b, quote)
+		cells[i] = string(b)
+	}
+}
+
+func swapQuotes(p []byte, quote rune) {
+	other := byte(quote)
+	if quote == 0 {
+		// Double quotes become NUL bytes when quotes are not special, so the csv
+		// package reads them as plain characters
+		other = '\x00'
+	}
+	for i, b := range p {
+		switch b {
+		case '"':
+			p[i] = other
+		case other:
+			p[i] = '"'
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package records
+
+import (
+	"encoding/csv"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// encodeText encodes UTF-8 text with an encoding, as a file would hold it;
+// UTF-16 text gets a byte order mark
+func encodeText(t *testing.T, name, text string) []byte {
+	t.Helper()
+	if name == "" {
+		return []byte(text)
+	}
+	b, err := lookupEncoding(name).NewEncoder().Bytes([]byte(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestSniffEncoding(t *testing.T) {
+	utf16le, _ := unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM).NewEncoder().Bytes([]byte("Name,Email\n"))
+	utf16be, _ := unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM).NewEncoder().Bytes([]byte("Name,Email\n"))
+	latin, _ := charmap.Windows1252.NewEncoder().Bytes([]byte("Name,Email\nRené,rene@example.com\n"))
+
+	tests := []struct {
+		name     string
+		sample   []byte
+		encoding string
+		bom      bool
+	}{
+		{"ascii", []byte("Name,Email\n"), EncodingUTF8, false},
+		{"utf-8", []byte("Name,Email\nRené,rene@example.com\n"), EncodingUTF8, false},
+		{"utf-8 bom", append([]byte{0xEF, 0xBB, 0xBF}, "Name"...), EncodingUTF8, true},
+		{"utf-16le bom", append([]byte{0xFF, 0xFE}, utf16le...), EncodingUTF16LE, true},
+		{"utf-16be bom", append([]byte{0xFE, 0xFF}, utf16be...), EncodingUTF16BE, true},
+		{"utf-16le", utf16le, EncodingUTF16LE, false},
+		{"utf-16be", utf16be, EncodingUTF16BE, false},
+		{"windows-1252", latin, EncodingWindows1252, false},
+		{"utf-8 cut in a character", []byte("Name\nRen\xc3"), EncodingUTF8, false},
+		{"empty", nil, EncodingUTF8, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoding, bom := sniffEncoding(tt.sample)
+			if encoding != tt.encoding || bom != tt.bom {
+				t.Errorf("sniffEncoding = %s, %v, want %s, %v", encoding, bom, tt.encoding, tt.bom)
+			}
+		})
+	}
+}
+
+func TestSniffCSV(t *testing.T) {
+	tests := []struct {
+		name      string
+		encoding  string // Encoding of the file, empty for UTF-8
+		content   string
+		override  Dialect
+		delimiter rune
+		quote     rune
+		want      [][]string
+	}{
+		{
+			name:      "comma",
+			content:   "Name,Email\nAnn,ann@example.com\n",
+			delimiter: ',', quote: '"',
+			want: [][]string{{"Name", "Email"}, {"Ann", "ann@example.com"}},
+		},
+		{
+			name:      "semicolon",
+			content:   "Name;Email;Notes\n\"Smith, Ann\";ann@example.com;a,b\n",
+			delimiter: ';', quote: '"',
+			want: [][]string{{"Name", "Email", "Notes"}, {"Smith, Ann", "ann@example.com", "a,b"}},
+		},
+		{
+			name:      "tab",
+			content:   "Name\tEmail\r\nAnn\tann@example.com\r\n",
+			delimiter: '\t', quote: '"',
+			want: [][]string{{"Name", "Email"}, {"Ann", "ann@example.com"}},
+		},
+		{
+			name:      "pipe",
+			content:   "Name|Email\nAnn|ann@example.com\n",
+			delimiter: '|', quote: '"',
+			want: [][]string{{"Name", "Email"}, {"Ann", "ann@example.com"}},
+		},
+		{
+			name:      "single quotes",
+			content:   "'Name','Email'\n'Smith, Ann','ann@example.com'\n'Say \"hi\"','b@example.com'\n",
+			delimiter: ',', quote: '\'',
+			want: [][]string{{"Name", "Email"}, {"Smith, Ann", "ann@example.com"}, {`Say "hi"`, "b@example.com"}},
+		},
+		{
+			name:      "single column",
+			content:   "Email\nann@example.com\n",
+			delimiter: ',', quote: '"',
+			want: [][]string{{"Email"}, {"ann@example.com"}},
+		},
+		{
+			name:      "utf-8 bom",
+			content:   "\ufeffName;Email\nRené;rene@example.com\n",
+			delimiter: ';', quote: '"',
+			want: [][]string{{"Name", "Email"}, {"René", "rene@example.com"}},
+		},
+		{
+			name:      "utf-16le bom",
+			encoding:  EncodingUTF16LE,
+			content:   "Name\tEmail\nRené\trene@example.com\n", // The encoder writes the byte order mark
+			delimiter: '\t', quote: '"',
+			want: [][]string{{"Name", "Email"}, {"René", "rene@example.com"}},
+		},
+		{
+			name:      "windows-1252",
+			encoding:  EncodingWindows1252,
+			content:   "Name;Email\nRené;rene@example.com\n",
+			delimiter: ';', quote: '"',
+			want: [][]string{{"Name", "Email"}, {"René", "rene@example.com"}},
+		},
+		{
+			name:      "overrides",
+			encoding:  EncodingLatin1,
+			content:   "Name,Email|Notes\nRené,rene@example.com|\"x\n",
+			override:  Dialect{Delimiter: "|", Quote: QuoteNone, Encoding: EncodingLatin1},
+			delimiter: '|', quote: 0,
+			want: [][]string{{"Name,Email", "Notes"}, {"René,rene@example.com", `"x`}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content := encodeText(t, tt.encoding, tt.content)
+			sniffed, err := sniffCSV(strings.NewReader(string(content)), tt.override, ',')
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sniffed.delimiter != tt.delimiter || sniffed.quote != tt.quote {
+				t.Errorf("delimiter, quote = %q, %q, want %q, %q", sniffed.delimiter, sniffed.quote, tt.delimiter, tt.quote)
+			}
+
+			reader := csv.NewReader(sniffed.reader)
+			reader.Comma = sniffed.delimiter
+			reader.LazyQuotes = true
+			var got [][]string
+			for {
+				cells, err := reader.Read()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				if sniffed.quote != '"' {
+					unswapQuotes(cells, sniffed.quote)
+				}
+				got = append(got, cells)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("rows = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
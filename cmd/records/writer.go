@@ -0,0 +1,125 @@
+package records
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// RecordWriter writes records one at a time under a fixed set of headers
+type RecordWriter interface {
+	Write(record Record) error
+	Close() error
+}
+
+// WriterOptions controls how XLSX output is split into sheets; CSV output ignores it
+type WriterOptions struct {
+	SheetRows   int    // Records per sheet before a new sheet is started; 0 means as many as Excel allows
+	SplitColumn string // Column whose value picks the sheet of each record
+}
+
+// rowWriter writes rows of cells to a CSV or XLSX file
+type rowWriter interface {
+	writeRow(cells []string) error
+	Close() error
+}
+
+// recordWriter lays out records as rows for a rowWriter
+type recordWriter struct {
+	rows   rowWriter
+	layout func(Record) []string
+}
+
+func (w *recordWriter) Write(record Record) error {
+	return w.rows.writeRow(w.layout(record))
+}
+
+func (w *recordWriter) Close() error {
+	return w.rows.Close()
+}
+
+// CheckOutputFile reports an error if the output file type is not supported
+func CheckOutputFile(filename string) error {
+	ext := strings.ToLower(filepath.Ext(filename))
+	if ext != ".csv" && ext != ".xlsx" {
+		return fmt.Errorf("output file must have a .csv or .xlsx extension: %s", filename)
+	}
+	return nil
+}
+
+// CreateRecordWriter creates the output file and writes its header row. The
+// file type is picked by extension; records are laid out by header name.
+func CreateRecordWriter(filename string, headers []string, opts WriterOptions) (RecordWriter, error) {
+	rows, err := createRowWriter(filename, headers, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &recordWriter{rows: rows, layout: func(record Record) []string {
+		return recordRow(record, headers)
+	}}, nil
+}
+
+// CreateSchemaWriter is like CreateRecordWriter but lays out records under a
+// mapped schema, so each record's Name, Email and OrgName land in the schema's
+// columns for those fields whatever their source headers were called
+func CreateSchemaWriter(filename string, schema Columns, opts WriterOptions) (RecordWriter, error) {
+	rows, err := createRowWriter(filename, schema.Headers, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &recordWriter{rows: rows, layout: schema.Row}, nil
+}
+
+func createRowWriter(filename string, headers []string, opts WriterOptions) (rowWriter, error) {
+	if err := CheckOutputFile(filename); err != nil {
+		return nil, err
+	}
+	if strings.ToLower(filepath.Ext(filename)) == ".xlsx" {
+		return createXLSXRowWriter(filename, headers, opts)
+	}
+	return createCSVRowWriter(filename, headers)
+}
+
+// csvRowWriter streams rows to a CSV file
+type csvRowWriter struct {
+	file   *os.File
+	writer *csv.Writer
+}
+
+func createCSVRowWriter(filename string, headers []string) (*csvRowWriter, error) {
+	file, err := os.Create(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	writer := csv.NewWriter(file)
+	if err := writer.Write(headers); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &csvRowWriter{file: file, writer: writer}, nil
+}
+
+func (w *csvRowWriter) writeRow(cells []string) error {
+	return w.writer.Write(cells)
+}
+
+func (w *csvRowWriter) Close() error {
+	w.writer.Flush()
+	if err := w.writer.Error(); err != nil {
+		w.file.Close()
+		return err
+	}
+	return w.file.Close()
+}
+
+// recordRow lays out the record values in header order, leaving unknown headers empty
+func recordRow(record Record, headers []string) []string {
+	row := make([]string, len(headers))
+	for i, header := range headers {
+		row[i] = record.Value(header)
+	}
+	return row
+}
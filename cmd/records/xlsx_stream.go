@@ -0,0 +1,360 @@
+package records
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tealeg/xlsx"
+)
+
+// xlsxRows streams the rows of every sheet of a workbook without loading the
+// sheets into memory. Only the shared string table and styles are kept around.
+type xlsxRows struct {
+	zip           *zip.ReadCloser
+	sheets        []xlsxSheetRef
+	sharedStrings []string
+	dateStyles    map[int]bool
+	date1904      bool
+
+	sheetIndex int
+	sheetFile  io.ReadCloser
+	decoder    *xml.Decoder
+}
+
+type xlsxSheetRef struct {
+	name string
+	file *zip.File
+}
+
+func openXLSXRows(filename string) (*xlsxRows, error) {
+	zr, err := zip.OpenReader(filename)
+	if err != nil {
+		return nil, err
+	}
+	x := &xlsxRows{zip: zr, sheetIndex: -1}
+	if err := x.readWorkbook(); err != nil {
+		zr.Close()
+		return nil, err
+	}
+	return x, nil
+}
+
+// readWorkbook loads the sheet list, the shared strings and the date styles
+func (x *xlsxRows) readWorkbook() error {
+	files := make(map[string]*zip.File)
+	for _, f := range x.zip.File {
+		files[f.Name] = f
+	}
+
+	var workbook struct {
+		WorkbookPr struct {
+			Date1904 bool `xml:"date1904,attr"`
+		} `xml:"workbookPr"`
+		Sheets []struct {
+			Name string `xml:"name,attr"`
+			ID   string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	if err := decodeZipXML(files["xl/workbook.xml"], &workbook); err != nil {
+		return fmt.Errorf("invalid XLSX workbook: %v", err)
+	}
+	x.date1904 = workbook.WorkbookPr.Date1904
+
+	var rels struct {
+		Relationships []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	if err := decodeZipXML(files["xl/_rels/workbook.xml.rels"], &rels); err != nil {
+		return fmt.Errorf("invalid XLSX workbook relations: %v", err)
+	}
+	targets := make(map[string]string)
+	for _, rel := range rels.Relationships {
+		target := rel.Target
+		if strings.HasPrefix(target, "/") {
+			target = strings.TrimPrefix(target, "/")
+		} else {
+			target = path.Join("xl", target)
+		}
+		targets[rel.ID] = target
+	}
+	for _, sheet := range workbook.Sheets {
+		if f, ok := files[targets[sheet.ID]]; ok {
+			x.sheets = append(x.sheets, xlsxSheetRef{name: sheet.Name, file: f})
+		}
+	}
+
+	if f, ok := files["xl/sharedStrings.xml"]; ok {
+		strs, err := readSharedStrings(f)
+		if err != nil {
+			return fmt.Errorf("invalid XLSX shared strings: %v", err)
+		}
+		x.sharedStrings = strs
+	}
+
+	if f, ok := files["xl/styles.xml"]; ok {
+		styles, err := readDateStyles(f)
+		if err != nil {
+			return fmt.Errorf("invalid XLSX styles: %v", err)
+		}
+		x.dateStyles = styles
+	}
+	return nil
+}
+
+// next returns the next row along with its sheet name and 1-based row number.
+// It returns io.EOF once every sheet has been read.
+func (x *xlsxRows) next() (rawRow, error) {
+	for {
+		if x.decoder == nil {
+			if err := x.openNextSheet(); err != nil {
+				return rawRow{}, err
+			}
+		}
+		row, err := x.readRow()
+		if err == io.EOF {
+			x.sheetFile.Close()
+			x.sheetFile = nil
+			x.decoder = nil
+			continue
+		}
+		if err != nil {
+			return rawRow{}, err
+		}
+		row.sheet = x.sheets[x.sheetIndex].name
+		row.sheetIndex = x.sheetIndex
+		return row, nil
+	}
+}
+
+// skipSheet moves past the rest of the current sheet without decoding it
+func (x *xlsxRows) skipSheet() {
+	if x.sheetFile != nil {
+		x.sheetFile.Close()
+		x.sheetFile = nil
+	}
+	x.decoder = nil
+}
+
+func (x *xlsxRows) openNextSheet() error {
+	x.sheetIndex++
+	if x.sheetIndex >= len(x.sheets) {
+		return io.EOF
+	}
+	f, err := x.sheets[x.sheetIndex].file.Open()
+	if err != nil {
+		return err
+	}
+	x.sheetFile = f
+	x.decoder = xml.NewDecoder(f)
+	return nil
+}
+
+// readRow decodes the next <row> element of the current sheet
+func (x *xlsxRows) readRow() (rawRow, error) {
+	for {
+		tok, err := x.decoder.Token()
+		if err != nil {
+			return rawRow{}, err
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok || start.Name.Local != "row" {
+			continue
+		}
+		var row struct {
+			R     int `xml:"r,attr"`
+			Cells []struct {
+				R  string `xml:"r,attr"`
+				T  string `xml:"t,attr"`
+				S  int    `xml:"s,attr"`
+				V  string `xml:"v"`
+				Is struct {
+					T string `xml:"t"`
+					R []struct {
+						T string `xml:"t"`
+					} `xml:"r"`
+				} `xml:"is"`
+			} `xml:"c"`
+		}
+		if err := x.decoder.DecodeElement(&row, &start); err != nil {
+			return rawRow{}, err
+		}
+
+		var cells []string
+		for i, c := range row.Cells {
+			col := i
+			if c.R != "" {
+				col = xlsx.ColLettersToIndex(strings.TrimRight(c.R, "0123456789"))
+			}
+			for len(cells) < col {
+				cells = append(cells, "")
+			}
+			var value string
+			switch c.T {
+			case "s":
+				idx, err := strconv.Atoi(c.V)
+				if err == nil && idx >= 0 && idx < len(x.sharedStrings) {
+					value = x.sharedStrings[idx]
+				}
+			case "inlineStr":
+				value = c.Is.T
+				for _, r := range c.Is.R {
+					value += r.T
+				}
+			case "b":
+				if c.V == "1" {
+					value = "TRUE"
+				} else {
+					value = "FALSE"
+				}
+			case "str", "e":
+				value = c.V
+			default:
+				value = x.formatNumber(c.V, c.S)
+			}
+			if col < len(cells) {
+				cells[col] = value
+			} else {
+				cells = append(cells, value)
+			}
+		}
+		return rawRow{cells: cells, line: row.R}, nil
+	}
+}
+
+// formatNumber renders numeric cells the way they show up in Excel for the common cases
+func (x *xlsxRows) formatNumber(v string, style int) string {
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return v
+	}
+	if x.dateStyles[style] {
+		t := xlsx.TimeFromExcelTime(f, x.date1904)
+		if _, frac := math.Modf(f); frac == 0 {
+			return t.Format("2006-01-02")
+		}
+		return t.Format(time.DateTime)
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func (x *xlsxRows) Close() error {
+	if x.sheetFile != nil {
+		x.sheetFile.Close()
+	}
+	return x.zip.Close()
+}
+
+func decodeZipXML(f *zip.File, v interface{}) error {
+	if f == nil {
+		return fmt.Errorf("missing part")
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return xml.NewDecoder(rc).Decode(v)
+}
+
+func readSharedStrings(f *zip.File) ([]string, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	var strs []string
+	decoder := xml.NewDecoder(rc)
+	for {
+		tok, err := decoder.Token()
+		if err == io.EOF {
+			return strs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok || start.Name.Local != "si" {
+			continue
+		}
+		var si struct {
+			T string `xml:"t"`
+			R []struct {
+				T string `xml:"t"`
+			} `xml:"r"`
+		}
+		if err := decoder.DecodeElement(&si, &start); err != nil {
+			return nil, err
+		}
+		value := si.T
+		for _, r := range si.R {
+			value += r.T
+		}
+		strs = append(strs, value)
+	}
+}
+
+// readDateStyles returns the cell style indexes that format numbers as dates
+func readDateStyles(f *zip.File) (map[int]bool, error) {
+	var styles struct {
+		NumFmts []struct {
+			ID   int    `xml:"numFmtId,attr"`
+			Code string `xml:"formatCode,attr"`
+		} `xml:"numFmts>numFmt"`
+		CellXfs []struct {
+			NumFmtID int `xml:"numFmtId,attr"`
+		} `xml:"cellXfs>xf"`
+	}
+	if err := decodeZipXML(f, &styles); err != nil {
+		return nil, err
+	}
+
+	dateFormats := make(map[int]bool)
+	for id := 14; id <= 22; id++ {
+		dateFormats[id] = true
+	}
+	for id := 45; id <= 47; id++ {
+		dateFormats[id] = true
+	}
+	for _, nf := range styles.NumFmts {
+		dateFormats[nf.ID] = isDateFormatCode(nf.Code)
+	}
+
+	dateStyles := make(map[int]bool)
+	for i, xf := range styles.CellXfs {
+		if dateFormats[xf.NumFmtID] {
+			dateStyles[i] = true
+		}
+	}
+	return dateStyles, nil
+}
+
+// isDateFormatCode checks a custom number format for date or time placeholders,
+// ignoring quoted literals and bracketed colors
+func isDateFormatCode(code string) bool {
+	inQuote, inBracket := false, false
+	for _, r := range strings.ToLower(code) {
+		switch {
+		case r == '"':
+			inQuote = !inQuote
+		case inQuote:
+		case r == '[':
+			inBracket = true
+		case r == ']':
+			inBracket = false
+		case inBracket:
+		case r == 'y' || r == 'm' || r == 'd' || r == 'h' || r == 's':
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,427 @@
+package records
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/tealeg/xlsx"
+)
+
+const (
+	// maxSheetRows is the number of data rows that fit on a sheet under its header
+	maxSheetRows = 1048575
+	// maxSplitSheets caps the sheets created when splitting by a column value
+	maxSplitSheets = 250
+	// Column widths in characters
+	minColumnWidth = 8
+	maxColumnWidth = 60
+)
+
+// Style indexes into the cellXfs of xlsxStyles
+const (
+	styleText   = 1 // Text format, so values such as zip codes keep their leading zeros
+	styleHeader = 2 // Bold text
+)
+
+// xlsxRowWriter streams rows into an XLSX workbook. Each sheet is buffered in a
+// temporary file so that column widths can be measured before the sheet is
+// written; memory use does not grow with the number of rows.
+type xlsxRowWriter struct {
+	filename string
+	headers  []string
+	opts     WriterOptions
+	splitCol int
+
+	sheets  []*xlsxSheet
+	byName  map[string]*xlsxSheet // Sheets by lowercased name, as Excel compares them
+	byValue map[string]*xlsxSheet // Sheet taking the rows of each split value
+	current *xlsxSheet
+}
+
+// xlsxSheet is a sheet being written
+type xlsxSheet struct {
+	name   string
+	tmp    *os.File
+	buf    *bufio.Writer
+	rows   int // Rows written, including the header
+	widths []int
+}
+
+func createXLSXRowWriter(filename string, headers []string, opts WriterOptions) (*xlsxRowWriter, error) {
+	// Fail early if the output cannot be created
+	file, err := os.Create(filename)
+	if err != nil {
+		return nil, err
+	}
+	file.Close()
+
+	w := &xlsxRowWriter{
+		filename: filename,
+		headers:  headers,
+		opts:     opts,
+		splitCol: -1,
+		byName:   make(map[string]*xlsxSheet),
+		byValue:  make(map[string]*xlsxSheet),
+	}
+	if opts.SplitColumn != "" {
+		for i, header := range headers {
+			if strings.EqualFold(strings.TrimSpace(header), strings.TrimSpace(opts.SplitColumn)) {
+				w.splitCol = i
+				break
+			}
+		}
+		if w.splitCol == -1 {
+			os.Remove(filename)
+			return nil, fmt.Errorf("column to split sheets by not found in the output: %s", opts.SplitColumn)
+		}
+	}
+	return w, nil
+}
+
+// writeRow writes a data row to the sheet picked by the split options
+func (w *xlsxRowWriter) writeRow(cells []string) error {
+	sheet, err := w.sheetFor(cells)
+	if err != nil {
+		return err
+	}
+	return sheet.writeRow(cells, styleText)
+}
+
+// sheetFor returns the sheet a row goes to, starting new sheets as needed
+func (w *xlsxRowWriter) sheetFor(cells []string) (*xlsxSheet, error) {
+	limit := w.opts.SheetRows
+	if limit <= 0 || limit > maxSheetRows {
+		limit = maxSheetRows
+	}
+
+	if w.splitCol == -1 {
+		if w.current != nil && w.current.rows-1 < limit {
+			return w.current, nil
+		}
+		return w.addSheet(fmt.Sprintf("Sheet%d", len(w.sheets)+1))
+	}
+
+	// Values differing only in case share a sheet, named after the first one seen
+	value := ""
+	if w.splitCol < len(cells) {
+		value = strings.TrimSpace(cells[w.splitCol])
+	}
+	if value == "" {
+		value = "(blank)"
+	}
+	key := strings.ToLower(value)
+	if sheet := w.byValue[key]; sheet != nil && sheet.rows-1 < limit {
+		return sheet, nil
+	}
+	sheet, err := w.addSheet(w.uniqueSheetName(sanitizeSheetName(value)))
+	if err != nil {
+		return nil, err
+	}
+	w.byValue[key] = sheet
+	return sheet, nil
+}
+
+// uniqueSheetName numbers a sheet name taken by another sheet, e.g. once a
+// value overflows its sheet or two values sanitize or truncate to the same name
+func (w *xlsxRowWriter) uniqueSheetName(base string) string {
+	name := base
+	for n := 2; w.byName[strings.ToLower(name)] != nil; n++ {
+		suffix := fmt.Sprintf(" (%d)", n)
+		name = truncateRunes(base, 31-len(suffix)) + suffix
+	}
+	return name
+}
+
+func (w *xlsxRowWriter) addSheet(name string) (*xlsxSheet, error) {
+	if len(w.sheets) >= maxSplitSheets && w.splitCol != -1 {
+		return nil, fmt.Errorf("splitting by %s would create more than %d sheets", w.opts.SplitColumn, maxSplitSheets)
+	}
+	sheet, err := newXLSXSheet(name, len(w.headers))
+	if err != nil {
+		return nil, err
+	}
+	if err := sheet.writeRow(w.headers, styleHeader); err != nil {
+		sheet.discard()
+		return nil, err
+	}
+	w.sheets = append(w.sheets, sheet)
+	w.byName[strings.ToLower(name)] = sheet
+	w.current = sheet
+	return sheet, nil
+}
+
+func newXLSXSheet(name string, columns int) (*xlsxSheet, error) {
+	tmp, err := os.CreateTemp("", "datamerge-sheet-*.xml")
+	if err != nil {
+		return nil, err
+	}
+	return &xlsxSheet{name: name, tmp: tmp, buf: bufio.NewWriter(tmp), widths: make([]int, columns)}, nil
+}
+
+// writeRow appends a row of inline string cells to the sheet's temporary file
+func (s *xlsxSheet) writeRow(cells []string, style int) error {
+	s.rows++
+	fmt.Fprintf(s.buf, `<row r="%d">`, s.rows)
+	for i, value := range cells {
+		for len(s.widths) <= i {
+			s.widths = append(s.widths, 0)
+		}
+		if n := utf8.RuneCountInString(value); n > s.widths[i] {
+			s.widths[i] = n
+		}
+		if value == "" && style != styleHeader {
+			continue
+		}
+		fmt.Fprintf(s.buf, `<c r="%s%d" s="%d" t="inlineStr"><is><t xml:space="preserve">`, xlsx.ColIndexToLetters(i), s.rows, style)
+		if err := xml.EscapeText(s.buf, []byte(value)); err != nil {
+			return err
+		}
+		s.buf.WriteString(`</t></is></c>`)
+	}
+	_, err := s.buf.WriteString("</row>\n")
+	return err
+}
+
+// writeTo writes the complete worksheet XML
+func (s *xlsxSheet) writeTo(out io.Writer) error {
+	if err := s.buf.Flush(); err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(out)
+	bw.WriteString(xml.Header)
+	bw.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">`)
+	// Freeze the header row
+	bw.WriteString(`<sheetViews><sheetView workbookViewId="0"><pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/><selection pane="bottomLeft"/></sheetView></sheetViews>`)
+	if len(s.widths) > 0 {
+		bw.WriteString("<cols>")
+		for i, width := range s.widths {
+			width += 2
+			if width < minColumnWidth {
+				width = minColumnWidth
+			}
+			if width > maxColumnWidth {
+				width = maxColumnWidth
+			}
+			fmt.Fprintf(bw, `<col min="%d" max="%d" width="%d" customWidth="1" style="%d"/>`, i+1, i+1, width, styleText)
+		}
+		bw.WriteString("</cols>")
+	}
+	bw.WriteString("<sheetData>")
+	if _, err := s.tmp.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := io.Copy(bw, s.tmp); err != nil {
+		return err
+	}
+	bw.WriteString("</sheetData></worksheet>")
+	return bw.Flush()
+}
+
+func (s *xlsxSheet) discard() {
+	s.tmp.Close()
+	os.Remove(s.tmp.Name())
+}
+
+// Close assembles the workbook from the buffered sheets
+func (w *xlsxRowWriter) Close() error {
+	defer func() {
+		for _, sheet := range w.sheets {
+			sheet.discard()
+		}
+	}()
+	// A workbook needs at least one sheet
+	if len(w.sheets) == 0 {
+		name := "Sheet1"
+		if w.splitCol != -1 {
+			name = "Sheet"
+		}
+		if _, err := w.addSheet(name); err != nil {
+			return err
+		}
+	}
+
+	file, err := os.Create(w.filename)
+	if err != nil {
+		return err
+	}
+	zw := zip.NewWriter(file)
+	if err := w.writeParts(zw); err != nil {
+		zw.Close()
+		file.Close()
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func (w *xlsxRowWriter) writeParts(zw *zip.Writer) error {
+	var contentTypes, workbook, rels strings.Builder
+	contentTypes.WriteString(xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>`)
+	workbook.WriteString(xml.Header + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>`)
+	rels.WriteString(xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`)
+
+	for i, sheet := range w.sheets {
+		n := i + 1
+		fmt.Fprintf(&contentTypes, `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, n)
+		workbook.WriteString(`<sheet name="`)
+		xml.EscapeText(&workbook, []byte(sheet.name))
+		fmt.Fprintf(&workbook, `" sheetId="%d" r:id="rId%d"/>`, n, n)
+		fmt.Fprintf(&rels, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`, n, n)
+
+		part, err := zw.Create(fmt.Sprintf("xl/worksheets/sheet%d.xml", n))
+		if err != nil {
+			return err
+		}
+		if err := sheet.writeTo(part); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(&rels, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>`, len(w.sheets)+1)
+	contentTypes.WriteString(`</Types>`)
+	workbook.WriteString(`</sheets></workbook>`)
+	rels.WriteString(`</Relationships>`)
+
+	parts := []struct{ name, content string }{
+		{"[Content_Types].xml", contentTypes.String()},
+		{"_rels/.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`},
+		{"xl/workbook.xml", workbook.String()},
+		{"xl/_rels/workbook.xml.rels", rels.String()},
+		{"xl/styles.xml", xlsxStyles},
+	}
+	for _, p := range parts {
+		part, err := zw.Create(p.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(part, p.content); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// xlsxStyles holds the default style, a text style and a bold text style for headers
+const xlsxStyles = xml.Header + `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+	`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>` +
+	`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+	`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+	`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+	`<cellXfs count="3">` +
+	`<xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+	`<xf numFmtId="49" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+	`<xf numFmtId="49" fontId="1" fillId="0" borderId="0" xfId="0" applyNumberFormat="1" applyFont="1"/>` +
+	`</cellXfs>` +
+	`<cellStyles count="1"><cellStyle name="Normal" xfId="0" builtinId="0"/></cellStyles>` +
+	`</styleSheet>`
+
+// sanitizeSheetName turns a value into a valid sheet name: at most 31
+// characters and none of []:*?/\
+func sanitizeSheetName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		switch r {
+		case '[', ']', ':', '*', '?', '/', '\\':
+			return '_'
+		}
+		if r < 0x20 {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.Trim(truncateRunes(strings.TrimSpace(name), 31), "'")
+	if name == "" {
+		return "Sheet"
+	}
+	return name
+}
+
+func truncateRunes(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
+
+// appendXLSX rewrites an XLSX file with the records added to its first sheet.
+// The rows of every sheet are copied over as text; formatting is not kept.
+func appendXLSX(filename string, schema Columns, records []Record) error {
+	rows, err := openXLSXRows(filename)
+	if err != nil {
+		return err
+	}
+
+	tmpName := filename + ".tmp"
+	w, err := createXLSXRowWriter(tmpName, schema.Headers, WriterOptions{})
+	if err != nil {
+		rows.Close()
+		return err
+	}
+	fail := func(err error) error {
+		rows.Close()
+		for _, sheet := range w.sheets {
+			sheet.discard()
+		}
+		os.Remove(tmpName)
+		return err
+	}
+
+	// Copy the existing sheets, the first row of each being its header
+	for {
+		row, err := rows.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fail(err)
+		}
+		if sheet := w.byName[strings.ToLower(row.sheet)]; sheet != nil {
+			if err := sheet.writeRow(row.cells, styleText); err != nil {
+				return fail(err)
+			}
+			continue
+		}
+		sheet, err := newXLSXSheet(row.sheet, len(row.cells))
+		if err != nil {
+			return fail(err)
+		}
+		w.sheets = append(w.sheets, sheet)
+		w.byName[strings.ToLower(row.sheet)] = sheet
+		if err := sheet.writeRow(row.cells, styleHeader); err != nil {
+			return fail(err)
+		}
+	}
+
+	if len(w.sheets) == 0 {
+		if _, err := w.addSheet("Sheet1"); err != nil {
+			return fail(err)
+		}
+	}
+	first := w.sheets[0]
+	if first.rows+len(records) > maxSheetRows+1 {
+		return fail(fmt.Errorf("appending %d records would exceed the row limit of sheet %s", len(records), first.name))
+	}
+	for _, record := range records {
+		if err := first.writeRow(schema.Row(record), styleText); err != nil {
+			return fail(err)
+		}
+	}
+
+	rows.Close()
+	if err := w.Close(); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+	return os.Rename(tmpName, filename)
+}
@@ -0,0 +1,268 @@
+package records
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// readSheets reads back the rows of every sheet of a workbook, in sheet order
+func readSheets(t *testing.T, path string) ([]string, map[string][][]string) {
+	t.Helper()
+	rows, err := openXLSXRows(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	var names []string
+	sheets := make(map[string][][]string)
+	for {
+		row, err := rows.next()
+		if err == io.EOF {
+			return names, sheets
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := sheets[row.sheet]; !ok {
+			names = append(names, row.sheet)
+		}
+		sheets[row.sheet] = append(sheets[row.sheet], row.cells)
+	}
+}
+
+// readPart returns a part of a zip file
+func readPart(t *testing.T, path, name string) string {
+	t.Helper()
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zr.Close()
+	for _, f := range zr.File {
+		if f.Name == name {
+			rc, err := f.Open()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer rc.Close()
+			b, err := io.ReadAll(rc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return string(b)
+		}
+	}
+	t.Fatalf("%s has no part %s", path, name)
+	return ""
+}
+
+// xlsxHeader is the header row written by writeXLSX
+var xlsxHeader = []string{"Name", "Email", "Zip", "City"}
+
+// writeXLSX writes rows of Name, Email, Zip and City under those headers
+func writeXLSX(t *testing.T, opts WriterOptions, rows ...[]string) (string, error) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "out.xlsx")
+	writer, err := CreateRecordWriter(path, xlsxHeader, opts)
+	if err != nil {
+		return path, err
+	}
+	for _, row := range rows {
+		record := Record{Name: row[0], Email: row[1], OthersMap: map[string]string{"Zip": row[2], "City": row[3]}}
+		if err := writer.Write(record); err != nil {
+			writer.Close()
+			return path, err
+		}
+	}
+	return path, writer.Close()
+}
+
+func TestXLSXWriterTextCells(t *testing.T) {
+	path, err := writeXLSX(t, WriterOptions{},
+		[]string{"Ann", "ann@example.com", "00123", "Oslo"},
+		[]string{"Bob <&>", "bob@example.com", "12345678901234567890", ""},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names, sheets := readSheets(t, path)
+	want := [][]string{
+		xlsxHeader,
+		{"Ann", "ann@example.com", "00123", "Oslo"},
+		{"Bob <&>", "bob@example.com", "12345678901234567890"},
+	}
+	if !reflect.DeepEqual(names, []string{"Sheet1"}) || !reflect.DeepEqual(sheets["Sheet1"], want) {
+		t.Errorf("sheets = %q %q, want Sheet1 %q", names, sheets, want)
+	}
+
+	sheet := readPart(t, path, "xl/worksheets/sheet1.xml")
+	if !strings.Contains(sheet, `<c r="C2" s="1" t="inlineStr"><is><t xml:space="preserve">00123</t></is></c>`) {
+		t.Errorf("zip code not written as a text cell:\n%s", sheet)
+	}
+	if !strings.Contains(sheet, `<pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/>`) {
+		t.Errorf("header row not frozen:\n%s", sheet)
+	}
+	if !strings.Contains(sheet, `<c r="A1" s="2" t="inlineStr">`) {
+		t.Errorf("header row not styled:\n%s", sheet)
+	}
+	if styles := readPart(t, path, "xl/styles.xml"); !strings.Contains(styles, `<font><b/>`) {
+		t.Errorf("header style is not bold:\n%s", styles)
+	}
+}
+
+func TestXLSXWriterSheets(t *testing.T) {
+	long := strings.Repeat("Northern region ", 2) // 32 characters, so both values truncate alike
+	tests := []struct {
+		name  string
+		opts  WriterOptions
+		rows  [][]string
+		names []string
+		cells map[string][]string // Sheet name to the names on it
+	}{
+		{
+			name:  "rows per sheet",
+			opts:  WriterOptions{SheetRows: 2},
+			rows:  [][]string{{"A", "a@x", "", ""}, {"B", "b@x", "", ""}, {"C", "c@x", "", ""}},
+			names: []string{"Sheet1", "Sheet2"},
+			cells: map[string][]string{"Sheet1": {"A", "B"}, "Sheet2": {"C"}},
+		},
+		{
+			name:  "split column",
+			opts:  WriterOptions{SplitColumn: " city"},
+			rows:  [][]string{{"A", "a@x", "", "Oslo"}, {"B", "b@x", "", "Bergen"}, {"C", "c@x", "", "OSLO"}, {"D", "d@x", "", ""}},
+			names: []string{"Oslo", "Bergen", "(blank)"},
+			cells: map[string][]string{"Oslo": {"A", "C"}, "Bergen": {"B"}, "(blank)": {"D"}},
+		},
+		{
+			name:  "split and overflow",
+			opts:  WriterOptions{SplitColumn: "City", SheetRows: 1},
+			rows:  [][]string{{"A", "a@x", "", "Oslo"}, {"B", "b@x", "", "Oslo"}, {"C", "c@x", "", "Bergen"}},
+			names: []string{"Oslo", "Oslo (2)", "Bergen"},
+			cells: map[string][]string{"Oslo": {"A"}, "Oslo (2)": {"B"}, "Bergen": {"C"}},
+		},
+		{
+			name:  "sanitized names",
+			opts:  WriterOptions{SplitColumn: "City"},
+			rows:  [][]string{{"A", "a@x", "", "North/South"}, {"B", "b@x", "", "North_South"}, {"C", "c@x", "", "'Q1: [draft]?'"}},
+			names: []string{"North_South", "North_South (2)", "Q1_ _draft__"},
+			cells: map[string][]string{"North_South": {"A"}, "North_South (2)": {"B"}, "Q1_ _draft__": {"C"}},
+		},
+		{
+			name:  "truncation collision",
+			opts:  WriterOptions{SplitColumn: "City"},
+			rows:  [][]string{{"A", "a@x", "", long + "East"}, {"B", "b@x", "", long + "West"}, {"C", "c@x", "", long + "East"}},
+			names: []string{long[:31], long[:27] + " (2)"},
+			cells: map[string][]string{long[:31]: {"A", "C"}, long[:27] + " (2)": {"B"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path, err := writeXLSX(t, tt.opts, tt.rows...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			names, sheets := readSheets(t, path)
+			if !reflect.DeepEqual(names, tt.names) {
+				t.Errorf("sheets = %q, want %q", names, tt.names)
+			}
+			for name, want := range tt.cells {
+				rows := sheets[name]
+				if len(rows) == 0 || !reflect.DeepEqual(rows[0], xlsxHeader) {
+					t.Errorf("sheet %q has no header: %q", name, rows)
+					continue
+				}
+				var got []string
+				for _, row := range rows[1:] {
+					got = append(got, row[0])
+				}
+				if !reflect.DeepEqual(got, want) {
+					t.Errorf("sheet %q rows = %q, want %q", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestXLSXWriterAppend(t *testing.T) {
+	path, err := writeXLSX(t, WriterOptions{SheetRows: 1},
+		[]string{"Ann", "ann@example.com", "00123", "Oslo"},
+		[]string{"Bob", "bob@example.com", "", "Bergen"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	appended := []Record{{Name: "Cid", Email: "cid@example.com", OthersMap: map[string]string{"Zip": "007", "City": "Rome"}}}
+	if err := AppendRecords(path, xlsxHeader, appended); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("temporary file left behind: %v", err)
+	}
+
+	names, sheets := readSheets(t, path)
+	want := map[string][][]string{
+		"Sheet1": {xlsxHeader, {"Ann", "ann@example.com", "00123", "Oslo"}, {"Cid", "cid@example.com", "007", "Rome"}},
+		"Sheet2": {xlsxHeader, {"Bob", "bob@example.com", "", "Bergen"}},
+	}
+	if !reflect.DeepEqual(names, []string{"Sheet1", "Sheet2"}) || !reflect.DeepEqual(sheets, want) {
+		t.Errorf("sheets = %q %q, want %q", names, sheets, want)
+	}
+}
+
+func TestXLSXWriterRemovesTemporaryFiles(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("TMPDIR", tmp)
+
+	t.Run("too many sheets", func(t *testing.T) {
+		var rows [][]string
+		for i := 0; i <= maxSplitSheets; i++ {
+			rows = append(rows, []string{"A", "a@x", "", fmt.Sprintf("City %d", i)})
+		}
+		if _, err := writeXLSX(t, WriterOptions{SplitColumn: "City"}, rows...); err == nil {
+			t.Errorf("writing %d split sheets did not fail", len(rows))
+		}
+	})
+	t.Run("output removed", func(t *testing.T) {
+		dir := filepath.Join(t.TempDir(), "out")
+		if err := os.Mkdir(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		writer, err := CreateRecordWriter(filepath.Join(dir, "out.xlsx"), xlsxHeader, WriterOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := writer.Write(Record{Name: "Ann", Email: "ann@example.com"}); err != nil {
+			t.Fatal(err)
+		}
+		os.RemoveAll(dir)
+		if err := writer.Close(); err == nil {
+			t.Errorf("Close() = nil after the output folder was removed")
+		}
+	})
+	t.Run("split column not found", func(t *testing.T) {
+		path, err := writeXLSX(t, WriterOptions{SplitColumn: "Region"})
+		if err == nil {
+			t.Fatal("unknown split column accepted")
+		}
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("output left behind: %v", err)
+		}
+	})
+
+	left, err := filepath.Glob(filepath.Join(tmp, "datamerge-sheet-*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(left) > 0 {
+		t.Errorf("temporary sheets left behind: %q", left)
+	}
+}
@@ -0,0 +1,70 @@
+package suppress
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// Hash algorithms of hashed lists. Entries of a hashed list are hex digests of
+// normalized addresses; input addresses are normalized and hashed the same way.
+const (
+	HashAuto   = ""       // Detect digests by their length
+	HashNone   = "none"   // Plain text list
+	HashMD5    = "md5"    // 32 hex digits
+	HashSHA1   = "sha1"   // 40 hex digits
+	HashSHA256 = "sha256" // 64 hex digits
+)
+
+// Hashes lists the hash settings for the CLI and GUI
+var Hashes = []string{HashAuto, HashNone, HashMD5, HashSHA1, HashSHA256}
+
+// hashAlgorithms in the order input addresses are hashed
+var hashAlgorithms = []string{HashMD5, HashSHA1, HashSHA256}
+
+// digestLengths maps the hex length of a digest to its algorithm
+var digestLengths = map[int]string{32: HashMD5, 40: HashSHA1, 64: HashSHA256}
+
+// ParseHash accepts a hash setting in any case, with or without a dash
+func ParseHash(s string) (string, error) {
+	h := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(s), "-", ""))
+	switch h {
+	case "", "auto":
+		return HashAuto, nil
+	case HashNone, HashMD5, HashSHA1, HashSHA256:
+		return h, nil
+	}
+	return "", fmt.Errorf("unknown hash algorithm: %s", s)
+}
+
+// digestAlgorithm returns the algorithm of a hex digest, or an empty string
+// when the value is not one. With a fixed algorithm only its length is accepted.
+func digestAlgorithm(value, algorithm string) string {
+	detected, ok := digestLengths[len(value)]
+	if !ok || (algorithm != HashAuto && algorithm != detected) {
+		return ""
+	}
+	if _, err := hex.DecodeString(value); err != nil {
+		return ""
+	}
+	return detected
+}
+
+// hashAddress returns the hex digest of an address
+func hashAddress(algorithm, address string) string {
+	switch algorithm {
+	case HashMD5:
+		sum := md5.Sum([]byte(address))
+		return hex.EncodeToString(sum[:])
+	case HashSHA1:
+		sum := sha1.Sum([]byte(address))
+		return hex.EncodeToString(sum[:])
+	case HashSHA256:
+		sum := sha256.Sum256([]byte(address))
+		return hex.EncodeToString(sum[:])
+	}
+	return ""
+}
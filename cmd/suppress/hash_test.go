@@ -0,0 +1,85 @@
+package suppress
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHashMatch(t *testing.T) {
+	md5 := hashAddress(HashMD5, "ann@example.com")
+	sha1 := hashAddress(HashSHA1, "bob@example.com")
+	sha256 := hashAddress(HashSHA256, "cat@example.com")
+
+	tests := []struct {
+		name    string
+		hash    string
+		entries []string
+		email   string
+		want    string // Rule of the match, empty for none
+	}{
+		{"md5", HashAuto, []string{md5}, " Ann@Example.com ", HashMD5},
+		{"sha1", HashAuto, []string{sha1}, "bob@example.com", HashSHA1},
+		{"sha256 upper case", HashAuto, []string{strings.ToUpper(sha256)}, "cat@example.com", HashSHA256},
+		{"other address", HashAuto, []string{md5, sha1, sha256}, "dan@example.com", ""},
+		{"fixed algorithm", HashSHA1, []string{sha1}, "bob@example.com", HashSHA1},
+		{"mixed with rules", HashAuto, []string{md5, "@example.com"}, "ann@example.com", HashMD5},
+		{"plain list", HashNone, []string{"ann@example.com"}, "ann@example.com", RuleEmail},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			set := newSet(t, tt.hash, tt.entries...)
+			matches := set.Match(tt.email)
+			got := ""
+			if len(matches) > 0 {
+				got = matches[0].Rule
+			}
+			if got != tt.want {
+				t.Errorf("Match(%q) = %v, want rule %q", tt.email, matches, tt.want)
+			}
+		})
+	}
+}
+
+func TestDigestAlgorithm(t *testing.T) {
+	tests := []struct {
+		value     string
+		algorithm string
+		want      string
+	}{
+		{hashAddress(HashMD5, "a@b.com"), HashAuto, HashMD5},
+		{hashAddress(HashSHA1, "a@b.com"), HashAuto, HashSHA1},
+		{hashAddress(HashSHA256, "a@b.com"), HashAuto, HashSHA256},
+		{hashAddress(HashSHA1, "a@b.com"), HashMD5, ""},
+		{hashAddress(HashMD5, "a@b.com"), HashMD5, HashMD5},
+		{strings.Repeat("z", 32), HashAuto, ""},
+		{"a@b.com", HashAuto, ""},
+		{"", HashAuto, ""},
+	}
+	for _, tt := range tests {
+		if got := digestAlgorithm(tt.value, tt.algorithm); got != tt.want {
+			t.Errorf("digestAlgorithm(%q, %q) = %q, want %q", tt.value, tt.algorithm, got, tt.want)
+		}
+	}
+}
+
+func TestParseHash(t *testing.T) {
+	tests := []struct {
+		s    string
+		want string
+	}{
+		{"", HashAuto},
+		{"Auto", HashAuto},
+		{"none", HashNone},
+		{"MD5", HashMD5},
+		{"SHA-1", HashSHA1},
+		{" sha256 ", HashSHA256},
+	}
+	for _, tt := range tests {
+		if got, err := ParseHash(tt.s); err != nil || got != tt.want {
+			t.Errorf("ParseHash(%q) = %q, %v, want %q", tt.s, got, err, tt.want)
+		}
+	}
+	if _, err := ParseHash("crc32"); err == nil {
+		t.Error("an unknown algorithm should be an error")
+	}
+}
@@ -0,0 +1,332 @@
+package suppress
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"website-copier/cmd/normalize"
+	"website-copier/cmd/records"
+	"website-copier/cmd/utils"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// Kinds of suppression rules, recognised by the way an entry is written
+const (
+	RuleEmail     = "email"     // alice@example.com
+	RuleDomain    = "domain"    // @example.com, or example.com when its top-level domain is known
+	RuleSubdomain = "subdomain" // *.example.com for every subdomain, .example.com for the domain and its subdomains
+	RuleTLD       = "tld"       // *.gov or .gov
+	RuleRegex     = "regex"     // /^sales@/, matched against the whole address ignoring case
+	RuleCategory  = "category"  // A domain category excluded by the filter, e.g. disposable; not part of any list file
+	// Hashed addresses are reported with the name of their algorithm: md5, sha1 or sha256
+)
+
+// List is a labelled suppression source, e.g. unsubscribes or bounces
+type List struct {
+	Label   string
+	Path    string   // CSV, TSV or XLSX file
+	Columns []string // Columns holding the entries; empty means every email-like column, or the rule columns of sheets without one
+	Hash    string   // Hash algorithm of the entries, one of Hashes; detected by default
+}
+
+// Match tells which entry of which list suppressed an address
+type Match struct {
+	List  string // Label of the list
+	Entry string // The list entry as written in the file
+	Rule  string // Kind of rule the entry is
+}
+
+// Pattern reports whether the match came from a domain, wildcard, regex or
+// category rule, which unlike addresses can each match many records
+func (m Match) Pattern() bool {
+	switch m.Rule {
+	case RuleDomain, RuleSubdomain, RuleTLD, RuleRegex, RuleCategory:
+		return true
+	}
+	return false
+}
+
+// Reason describes the match for reports
+func (m Match) Reason() string {
+	switch m.Rule {
+	case RuleEmail:
+		return fmt.Sprintf("address listed in %s", m.List)
+	case HashMD5, HashSHA1, HashSHA256:
+		return fmt.Sprintf("%s hash of address listed in %s", m.Rule, m.List)
+	case RuleDomain:
+		return fmt.Sprintf("domain %s listed in %s", m.Entry, m.List)
+	case RuleSubdomain:
+		return fmt.Sprintf("subdomain wildcard %s listed in %s", m.Entry, m.List)
+	case RuleTLD:
+		return fmt.Sprintf("top-level domain %s listed in %s", m.Entry, m.List)
+	case RuleRegex:
+		return fmt.Sprintf("pattern %s listed in %s", m.Entry, m.List)
+	case RuleCategory:
+		return fmt.Sprintf("%s email domain", m.Entry)
+	}
+	return fmt.Sprintf("listed in %s", m.List)
+}
+
+// Set holds the rules of every suppression list
+type Set struct {
+	lists      []List
+	normalizer *normalize.Normalizer
+	emails     map[string][]rule            // normalized address -> rules
+	hashed     map[string]map[string][]rule // algorithm -> hex digest -> rules
+	domains    *domainTrie
+	regexes    []regexRule
+	seen       map[string]bool
+	rules      []rule        // Every rule in load order
+	matched    map[rule]bool // Rules that matched an address
+}
+
+// rule is one list entry
+type rule struct {
+	list  int
+	kind  string
+	entry string
+}
+
+type regexRule struct {
+	rule
+	re *regexp.Regexp
+}
+
+// DefaultLabel derives a list label from its file name
+func DefaultLabel(path string) string {
+	return strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+}
+
+// Load reads every list, keying addresses with the given normalization rules
+func Load(lists []List, opts normalize.Options, mapping records.ColumnMapping) (*Set, error) {
+	s := &Set{
+		lists:      lists,
+		normalizer: normalize.New(opts),
+		emails:     make(map[string][]rule),
+		hashed:     make(map[string]map[string][]rule),
+		domains:    newDomainTrie(),
+		seen:       make(map[string]bool),
+		matched:    make(map[rule]bool),
+	}
+	for i, list := range lists {
+		if list.Label == "" {
+			s.lists[i].Label = DefaultLabel(list.Path)
+		}
+		raw, err := records.LoadDatabaseEntries(list.Path, records.DatabaseOptions{Columns: list.Columns, Mapping: mapping})
+		if err != nil {
+			return nil, fmt.Errorf("failed to load list %s: %v", s.lists[i].Label, err)
+		}
+
+		// Sort so that the entry reported for a match does not depend on map order
+		values := make([]string, 0, len(raw))
+		for value := range raw {
+			values = append(values, value)
+		}
+		sort.Strings(values)
+		counts := make(map[string]int)
+		skipped := 0
+		for _, value := range values {
+			kind, err := s.Add(i, value)
+			if err != nil {
+				// Report the first bad entry only, lists can hold many
+				if skipped == 0 {
+					utils.LogMessage(fmt.Sprintf("List %s: skipping entry %q: %v", s.lists[i].Label, value, err))
+				}
+				skipped++
+				continue
+			}
+			counts[kind]++
+		}
+		utils.LogMessage(fmt.Sprintf("Loaded list %s from %s: %d addresses, %d hashed addresses, %d domains, %d wildcards, %d patterns, %d skipped",
+			s.lists[i].Label, list.Path, counts[RuleEmail], counts[HashMD5]+counts[HashSHA1]+counts[HashSHA256],
+			counts[RuleDomain], counts[RuleSubdomain]+counts[RuleTLD], counts[RuleRegex], skipped))
+	}
+	return s, nil
+}
+
+// Add parses an entry and files it under a list, returning the kind of rule it is
+func (s *Set) Add(list int, value string) (string, error) {
+	value = strings.TrimSpace(value)
+	r := rule{list: list, entry: value}
+
+	// Hashed lists hold nothing but digests
+	hash := s.lists[list].Hash
+	if hash != HashNone {
+		if algorithm := digestAlgorithm(strings.ToLower(value), hash); algorithm != "" {
+			r.kind = algorithm
+			digest := strings.ToLower(value)
+			if !s.isNew(r, digest) {
+				return r.kind, nil
+			}
+			if s.hashed[algorithm] == nil {
+				s.hashed[algorithm] = make(map[string][]rule)
+			}
+			s.hashed[algorithm][digest] = append(s.hashed[algorithm][digest], r)
+			return r.kind, nil
+		}
+		if hash != HashAuto {
+			return "", fmt.Errorf("not a %s digest", hash)
+		}
+	}
+
+	switch {
+	case len(value) > 2 && strings.HasPrefix(value, "/") && strings.HasSuffix(value, "/"):
+		re, err := regexp.Compile("(?i)" + value[1:len(value)-1])
+		if err != nil {
+			return "", fmt.Errorf("invalid pattern: %v", err)
+		}
+		r.kind = RuleRegex
+		if !s.isNew(r, value) {
+			return r.kind, nil
+		}
+		s.regexes = append(s.regexes, regexRule{rule: r, re: re})
+
+	case strings.HasPrefix(value, "*.") || strings.HasPrefix(value, "."):
+		domain := s.normalizeDomain(strings.TrimPrefix(strings.TrimPrefix(value, "*"), "."))
+		if !validDomain(domain) {
+			return "", fmt.Errorf("invalid domain")
+		}
+		r.kind = RuleSubdomain
+		if !strings.Contains(domain, ".") {
+			r.kind = RuleTLD
+		}
+		if !s.isNew(r, domain) {
+			return r.kind, nil
+		}
+		s.domains.addSubdomains(domain, r)
+		if strings.HasPrefix(value, ".") {
+			s.domains.addDomain(domain, r)
+		}
+
+	case strings.HasPrefix(value, "@") || (!strings.Contains(value, "@") && strings.Contains(value, ".")):
+		domain := s.normalizeDomain(strings.TrimPrefix(value, "@"))
+		if !validDomain(domain) {
+			return "", fmt.Errorf("invalid domain")
+		}
+		// Without the @ a dotted value may as well be a name such as john.doe
+		if !strings.HasPrefix(value, "@") && !knownTLD(domain) {
+			return "", fmt.Errorf("not a known top-level domain, write @%s to suppress the domain", value)
+		}
+		r.kind = RuleDomain
+		if !s.isNew(r, domain) {
+			return r.kind, nil
+		}
+		s.domains.addDomain(domain, r)
+
+	case strings.Contains(value, "@"):
+		address := s.normalizer.Normalize(value)
+		if address == "" {
+			return "", fmt.Errorf("empty address")
+		}
+		r.kind = RuleEmail
+		if !s.isNew(r, address) {
+			return r.kind, nil
+		}
+		s.emails[address] = append(s.emails[address], r)
+
+	default:
+		return "", fmt.Errorf("not an address, domain or /pattern/")
+	}
+	return r.kind, nil
+}
+
+// isNew reports whether the list does not have the rule yet, remembering it
+func (s *Set) isNew(r rule, pattern string) bool {
+	key := fmt.Sprintf("%d|%s|%s", r.list, r.kind, pattern)
+	if s.seen[key] {
+		return false
+	}
+	s.seen[key] = true
+	s.rules = append(s.rules, r)
+	return true
+}
+
+// normalizeDomain applies the address normalization rules to a domain, so that
+// e.g. international domains compare the same on both sides
+func (s *Set) normalizeDomain(domain string) string {
+	address := s.normalizer.Normalize("x@" + strings.TrimSpace(domain))
+	return strings.TrimSuffix(strings.ToLower(address[strings.LastIndex(address, "@")+1:]), ".")
+}
+
+func validDomain(domain string) bool {
+	if domain == "" || strings.ContainsAny(domain, " @*/") {
+		return false
+	}
+	for _, label := range strings.Split(domain, ".") {
+		if label == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// knownTLD reports whether the last label of a domain is a top-level domain
+// of the public suffix list
+func knownTLD(domain string) bool {
+	_, icann := publicsuffix.PublicSuffix(domain[strings.LastIndex(domain, ".")+1:])
+	return icann
+}
+
+// Labels returns the list labels in load order
+func (s *Set) Labels() []string {
+	labels := make([]string, len(s.lists))
+	for i, list := range s.lists {
+		labels[i] = list.Label
+	}
+	return labels
+}
+
+// Match returns the rules suppressing an address in list order, or nil
+func (s *Set) Match(email string) []Match {
+	address := s.normalizer.Normalize(email)
+	if address == "" {
+		return nil
+	}
+
+	rules := append([]rule(nil), s.emails[address]...)
+	for _, algorithm := range hashAlgorithms {
+		if digests := s.hashed[algorithm]; len(digests) > 0 {
+			rules = append(rules, digests[hashAddress(algorithm, address)]...)
+		}
+	}
+	if at := strings.LastIndex(address, "@"); at != -1 {
+		rules = append(rules, s.domains.match(strings.TrimSuffix(strings.ToLower(address[at+1:]), "."))...)
+	}
+	for _, r := range s.regexes {
+		if r.re.MatchString(address) {
+			rules = append(rules, r.rule)
+		}
+	}
+	if len(rules) == 0 {
+		return nil
+	}
+
+	sort.SliceStable(rules, func(a, b int) bool {
+		return rules[a].list < rules[b].list
+	})
+	matches := make([]Match, len(rules))
+	for i, r := range rules {
+		s.matched[r] = true
+		matches[i] = s.match(r)
+	}
+	return matches
+}
+
+// Unmatched returns the list entries that have not matched any address yet, in list order
+func (s *Set) Unmatched() []Match {
+	var unmatched []Match
+	for _, r := range s.rules {
+		if !s.matched[r] {
+			unmatched = append(unmatched, s.match(r))
+		}
+	}
+	return unmatched
+}
+
+func (s *Set) match(r rule) Match {
+	return Match{List: s.lists[r.list].Label, Entry: r.entry, Rule: r.kind}
+}
@@ -0,0 +1,86 @@
+package suppress
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"website-copier/cmd/normalize"
+	"website-copier/cmd/records"
+)
+
+// writeList writes a suppression list file into a temporary folder
+func writeList(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func loadList(t *testing.T, path string, columns ...string) *Set {
+	t.Helper()
+	set, err := Load([]List{{Label: "Unsubscribes", Path: path, Columns: columns}}, normalize.DefaultOptions(), records.ColumnMapping{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return set
+}
+
+// A Domain column next to the Email column of an export describes the
+// addresses; it must not suppress every address of the domain
+func TestDomainColumnBesideEmails(t *testing.T) {
+	path := writeList(t, "unsubscribes.csv", "Email,Domain,Unsubscribed At\nalice@gmail.com,gmail.com,2026-01-02\n")
+	set := loadList(t, path)
+
+	tests := []struct {
+		email      string
+		suppressed bool
+	}{
+		{"alice@gmail.com", true},
+		{"Alice@Gmail.com", true},
+		{"bob@gmail.com", false},
+		{"carl@corp.com", false},
+	}
+	for _, tt := range tests {
+		matches := set.Match(tt.email)
+		if got := len(matches) > 0; got != tt.suppressed {
+			t.Errorf("Match(%q) = %v, want suppressed %v", tt.email, matches, tt.suppressed)
+		}
+		for _, m := range matches {
+			if m.Rule != RuleEmail {
+				t.Errorf("Match(%q) used a %s rule for %s", tt.email, m.Rule, m.Entry)
+			}
+		}
+	}
+}
+
+func TestRuleColumns(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		columns []string
+		email   string
+		want    string // Rule of the match, empty for none
+	}{
+		{"domain list", "Domain\ngmail.com\n", nil, "bob@gmail.com", RuleDomain},
+		{"domain column picked", "Email,Domain\nalice@gmail.com,gmail.com\n", []string{"Domain"}, "bob@gmail.com", RuleDomain},
+		{"name-like entry skipped", "Domain\njohn.doe\n@doe.example\n", nil, "x@john.doe", ""},
+		{"email column only", "Email,Domain\nalice@gmail.com,gmail.com\n", nil, "bob@gmail.com", ""},
+		{"hash list", "SHA256\n" + hashAddress(HashSHA256, "bob@gmail.com") + "\n", nil, "Bob@Gmail.com", HashSHA256},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			set := loadList(t, writeList(t, "list.csv", tt.content), tt.columns...)
+			matches := set.Match(tt.email)
+			got := ""
+			if len(matches) > 0 {
+				got = matches[0].Rule
+			}
+			if got != tt.want {
+				t.Errorf("Match(%q) = %v, want rule %q", tt.email, matches, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package suppress
+
+import "strings"
+
+// domainTrie stores domain rules under their labels in reverse order, so that
+// example.com is found at com -> example. Looking up a domain walks one node
+// per label whatever the number of rules.
+type domainTrie struct {
+	root *trieNode
+}
+
+type trieNode struct {
+	children map[string]*trieNode
+	exact    []rule // Rules matching this domain itself
+	below    []rule // Rules matching every subdomain of this domain
+}
+
+func newDomainTrie() *domainTrie {
+	return &domainTrie{root: &trieNode{}}
+}
+
+// node returns the node of a domain, creating it when needed
+func (t *domainTrie) node(domain string) *trieNode {
+	n := t.root
+	labels := strings.Split(domain, ".")
+	for i := len(labels) - 1; i >= 0; i-- {
+		if n.children == nil {
+			n.children = make(map[string]*trieNode)
+		}
+		child, ok := n.children[labels[i]]
+		if !ok {
+			child = &trieNode{}
+			n.children[labels[i]] = child
+		}
+		n = child
+	}
+	return n
+}
+
+// addDomain adds a rule matching the domain only
+func (t *domainTrie) addDomain(domain string, r rule) {
+	n := t.node(domain)
+	n.exact = append(n.exact, r)
+}
+
+// addSubdomains adds a rule matching every subdomain of the domain, but not the domain itself
+func (t *domainTrie) addSubdomains(domain string, r rule) {
+	n := t.node(domain)
+	n.below = append(n.below, r)
+}
+
+// match returns the rules matching a domain: the subdomain rules of every
+// parent domain, then the rules of the domain itself
+func (t *domainTrie) match(domain string) []rule {
+	var rules []rule
+	n := t.root
+	labels := strings.Split(domain, ".")
+	for i := len(labels) - 1; i >= 0; i-- {
+		child, ok := n.children[labels[i]]
+		if !ok {
+			return rules
+		}
+		n = child
+		if i > 0 {
+			rules = append(rules, n.below...)
+		}
+	}
+	return append(rules, n.exact...)
+}
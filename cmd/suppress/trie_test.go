@@ -0,0 +1,115 @@
+package suppress
+
+import (
+	"reflect"
+	"testing"
+
+	"website-copier/cmd/normalize"
+	"website-copier/cmd/records"
+)
+
+// newSet creates a set holding one list with the given entries
+func newSet(t *testing.T, hash string, entries ...string) *Set {
+	t.Helper()
+	set, err := Load(nil, normalize.DefaultOptions(), records.ColumnMapping{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	set.lists = []List{{Label: "Rules", Hash: hash}}
+	for _, entry := range entries {
+		if _, err := set.Add(0, entry); err != nil {
+			t.Fatalf("Add(%q): %v", entry, err)
+		}
+	}
+	return set
+}
+
+func TestDomainTrie(t *testing.T) {
+	trie := newDomainTrie()
+	trie.addDomain("example.com", rule{entry: "example.com"})
+	trie.addSubdomains("corp.com", rule{entry: "*.corp.com"})
+	trie.addSubdomains("gov", rule{entry: "*.gov"})
+	trie.addDomain("gov", rule{entry: ".gov"})
+
+	tests := []struct {
+		domain string
+		want   []string
+	}{
+		{"example.com", []string{"example.com"}},
+		{"mail.example.com", nil},
+		{"com", nil},
+		{"corp.com", nil},
+		{"eu.corp.com", []string{"*.corp.com"}},
+		{"a.eu.corp.com", []string{"*.corp.com"}},
+		{"gov", []string{".gov"}},
+		{"irs.gov", []string{"*.gov"}},
+		{"gov.uk", nil},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, r := range trie.match(tt.domain) {
+			got = append(got, r.entry)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("match(%q) = %q, want %q", tt.domain, got, tt.want)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	set := newSet(t, HashNone,
+		"alice@example.com",
+		"@blocked.com",
+		"@intranet.local",
+		"*.corp.com",
+		".agency.org",
+		".gov",
+		"/^sales@/",
+		"München.de",
+	)
+	tests := []struct {
+		email string
+		want  string // Rule of the match, empty for none
+	}{
+		{"alice@example.com", RuleEmail},
+		{" Alice@EXAMPLE.com", RuleEmail},
+		{"bob@example.com", ""},
+		{"x@blocked.com", RuleDomain},
+		{"x@Blocked.com.", RuleDomain},
+		{"x@mail.blocked.com", ""},
+		{"x@notblocked.com", ""},
+		{"x@intranet.local", RuleDomain},
+		{"x@corp.com", ""},
+		{"x@eu.corp.com", RuleSubdomain},
+		{"x@a.eu.corp.com", RuleSubdomain},
+		{"x@agency.org", RuleSubdomain},
+		{"x@hq.agency.org", RuleSubdomain},
+		{"x@irs.gov", RuleTLD},
+		{"x@gov.uk", ""},
+		{"Sales@shop.com", RuleRegex},
+		{"presales@shop.com", ""},
+		{"info@xn--mnchen-3ya.de", RuleDomain},
+		{"info@münchen.de", RuleDomain},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		matches := set.Match(tt.email)
+		got := ""
+		if len(matches) > 0 {
+			got = matches[0].Rule
+		}
+		if got != tt.want {
+			t.Errorf("Match(%q) = %v, want rule %q", tt.email, matches, tt.want)
+		}
+	}
+}
+
+func TestAddInvalid(t *testing.T) {
+	set := newSet(t, HashNone)
+	for _, entry := range []string{"", "*.", ".", "@", "@bad..com", "not a rule", "/(/", "john.doe", "smith.j", "intranet.local"} {
+		if kind, err := set.Add(0, entry); err == nil {
+			t.Errorf("Add(%q) = %s, want an error", entry, kind)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package typos
+
+import (
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"website-copier/cmd/records"
+)
+
+// Columns of the corrections report
+const (
+	ColumnSourceFile = "Source File"
+	ColumnSourceRow  = "Source Row"
+	ColumnEmail      = "Email"
+	ColumnDistance   = "Edit Distance"
+	ColumnAction     = "Action"
+)
+
+var reportColumns = []string{ColumnSourceFile, ColumnSourceRow, ColumnEmail, Column, ColumnDistance, ColumnAction}
+
+// ReportPath returns the corrections report next to an output file
+func ReportPath(outputFilePath string) string {
+	ext := filepath.Ext(outputFilePath)
+	return strings.TrimSuffix(outputFilePath, ext) + "_corrections" + ext
+}
+
+// WriteReport writes every correction with where it came from
+func WriteReport(filename string, corrections []Correction, opts records.WriterOptions) error {
+	writer, err := records.CreateRecordWriter(filename, reportColumns, opts)
+	if err != nil {
+		return err
+	}
+	for _, c := range corrections {
+		err := writer.Write(records.Record{OthersMap: map[string]string{
+			ColumnSourceFile: c.Record.Source(),
+			ColumnSourceRow:  strconv.Itoa(c.Record.Row),
+			ColumnEmail:      c.Record.Email,
+			Column:           c.Suggested,
+			ColumnDistance:   strconv.Itoa(c.Distance),
+			ColumnAction:     c.Action,
+		}})
+		if err != nil {
+			writer.Close()
+			return err
+		}
+	}
+	return writer.Close()
+}
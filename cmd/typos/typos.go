@@ -0,0 +1,273 @@
+package typos
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"website-copier/cmd/domains"
+	"website-copier/cmd/records"
+)
+
+// What happened to a record with a misspelt domain
+const (
+	Corrected = "corrected" // The email was replaced
+	Flagged   = "flagged"   // The fix was only suggested
+)
+
+// Column holds the suggested email of flagged records
+const Column = "Suggested Email"
+
+// DefaultMaxDistance is the largest edit distance corrected unless told otherwise
+const DefaultMaxDistance = 2
+
+// shortDomain is the length under which a known domain is only suggested for
+// a domain of the same length one edit away, so e.g. ge.com is not taken for me.com
+const shortDomain = 8
+
+// shortName is the longest first label of a domain never corrected: names
+// like gm.com or ibm.com are as likely real domains as typos
+const shortName = 3
+
+//go:embed known.txt
+var bundled string
+
+// Options switches typo correction on for a job
+type Options struct {
+	Enabled      bool
+	AutoCorrect  bool   // Replace the email instead of only suggesting the fix
+	MaxDistance  int    // Largest edit distance corrected; 0 means DefaultMaxDistance
+	KnownDomains string // File of extra known domains; the bundled list is always used
+}
+
+// Correction is a record whose domain looked like a typo of a known domain
+type Correction struct {
+	Record    records.Record // The record as it was read
+	Suggested string         // The email with the known domain
+	Distance  int
+	Action    string
+}
+
+// Corrector compares the domain of each record to the known domains. It is
+// not safe for concurrent use.
+type Corrector struct {
+	opts        Options
+	known       []string // In order of preference
+	isKnown     map[string]bool
+	suggestions map[string]suggestion // Domains already looked up
+	corrections []Correction
+}
+
+type suggestion struct {
+	domain   string
+	distance int
+}
+
+// KnownPath is the file whose domains extend the bundled list for every job,
+// next to the domain category lists
+func KnownPath() string {
+	dir := domains.ListsDir()
+	if dir == "" {
+		return ""
+	}
+	return filepath.Join(dir, "known.txt")
+}
+
+// Load reads the bundled known domains, then those of KnownPath and of
+// opts.KnownDomains when given. A domain listed with a leading ! is taken off.
+func Load(opts Options) (*Corrector, error) {
+	if opts.MaxDistance <= 0 {
+		opts.MaxDistance = DefaultMaxDistance
+	}
+	c := &Corrector{opts: opts, isKnown: make(map[string]bool), suggestions: make(map[string]suggestion)}
+	if err := c.read(strings.NewReader(bundled)); err != nil {
+		return nil, err
+	}
+	for _, path := range []string{KnownPath(), opts.KnownDomains} {
+		if path == "" {
+			continue
+		}
+		file, err := os.Open(path)
+		if os.IsNotExist(err) && path != opts.KnownDomains {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read known domains: %v", err)
+		}
+		err = c.read(file)
+		file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read known domains %s: %v", path, err)
+		}
+	}
+	return c, nil
+}
+
+func (c *Corrector) read(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "!") {
+			domain := domains.Canonical(line[1:])
+			delete(c.isKnown, domain)
+			for i, d := range c.known {
+				if d == domain {
+					c.known = append(c.known[:i], c.known[i+1:]...)
+					break
+				}
+			}
+			continue
+		}
+		if domain := domains.Canonical(line); !c.isKnown[domain] {
+			c.isKnown[domain] = true
+			c.known = append(c.known, domain)
+		}
+	}
+	return scanner.Err()
+}
+
+// Len returns the number of known domains
+func (c *Corrector) Len() int {
+	return len(c.known)
+}
+
+// Suggest returns the known domain a domain is most likely a typo of, and the
+// edit distance between them. It returns "" for known domains, for domains
+// with a short name and for domains not close to any. A typo is expected to
+// keep the first letter.
+func (c *Corrector) Suggest(domain string) (string, int) {
+	domain = domains.Canonical(domain)
+	if domain == "" || c.isKnown[domain] {
+		return "", 0
+	}
+	if name, _, _ := strings.Cut(domain, "."); len(name) <= shortName {
+		return "", 0
+	}
+	best, bestDistance := "", c.opts.MaxDistance+1
+	for _, known := range c.known {
+		if known[0] != domain[0] {
+			// e.g. tmail.com is its own domain, not a typo of gmail.com
+			continue
+		}
+		limit := c.opts.MaxDistance
+		if len(known) < shortDomain {
+			if len(known) != len(domain) {
+				continue
+			}
+			limit = 1
+		}
+		if sameName(domain, known) {
+			// gmail.co is a typo, yahoo.fr is another country
+			limit = 1
+		}
+		if d := Distance(domain, known); d <= limit && d < bestDistance {
+			best, bestDistance = known, d
+		}
+	}
+	if best == "" {
+		return "", 0
+	}
+	return best, bestDistance
+}
+
+// Correct looks up the domain of a record. The email of a misspelt record is
+// replaced when auto-correcting, or suggested in the Column otherwise.
+func (c *Corrector) Correct(record records.Record) records.Record {
+	at := strings.LastIndex(record.Email, "@")
+	if at == -1 {
+		return record
+	}
+	domain := domains.Domain(record.Email)
+	s, ok := c.suggestions[domain]
+	if !ok {
+		s.domain, s.distance = c.Suggest(domain)
+		c.suggestions[domain] = s
+	}
+	if s.domain == "" {
+		return record
+	}
+
+	fixed := strings.TrimSpace(record.Email[:at]) + "@" + s.domain
+	correction := Correction{Record: record, Suggested: fixed, Distance: s.distance, Action: Flagged}
+	if c.opts.AutoCorrect {
+		correction.Action = Corrected
+		record.Email = fixed
+	} else {
+		record.OthersMap[Column] = fixed
+	}
+	c.corrections = append(c.corrections, correction)
+	return record
+}
+
+// Count returns the number of records corrected or flagged
+func (c *Corrector) Count() int {
+	return len(c.corrections)
+}
+
+// Summary describes the corrections made
+func (c *Corrector) Summary() string {
+	action := Flagged
+	if c.opts.AutoCorrect {
+		action = Corrected
+	}
+	return fmt.Sprintf("Domain typos: %d records %s against %d known domains", len(c.corrections), action, len(c.known))
+}
+
+// Corrections returns the corrections in file order, then sheet and row order
+func (c *Corrector) Corrections(files []string) []Correction {
+	rank := make(map[string]int)
+	for i, file := range files {
+		rank[file] = i
+	}
+	sorted := append([]Correction(nil), c.corrections...)
+	sort.SliceStable(sorted, func(a, b int) bool {
+		ra, rb := sorted[a].Record, sorted[b].Record
+		if rank[ra.FilePath] != rank[rb.FilePath] {
+			return rank[ra.FilePath] < rank[rb.FilePath]
+		}
+		return ra.Before(rb)
+	})
+	return sorted
+}
+
+// sameName reports whether two domains differ only in their last label
+func sameName(a, b string) bool {
+	dotA, dotB := strings.LastIndex(a, "."), strings.LastIndex(b, ".")
+	return dotA != -1 && dotB != -1 && a[:dotA] == b[:dotB]
+}
+
+// Distance returns the number of insertions, deletions, substitutions and
+// swaps of adjacent characters turning a into b
+func Distance(a, b string) int {
+	s, t := []rune(a), []rune(b)
+	// Three rows of the optimal string alignment matrix
+	prev2 := make([]int, len(t)+1)
+	prev := make([]int, len(t)+1)
+	cur := make([]int, len(t)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(s); i++ {
+		cur[0] = i
+		for j := 1; j <= len(t); j++ {
+			cost := 1
+			if s[i-1] == t[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && s[i-1] == t[j-2] && s[i-2] == t[j-1] {
+				cur[j] = min(cur[j], prev2[j-2]+1)
+			}
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+	return prev[len(t)]
+}
@@ -0,0 +1,77 @@
+package typos
+
+import "testing"
+
+func TestDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"gmail.com", "gmail.com", 0},
+		{"gmial.com", "gmail.com", 1}, // Transposition
+		{"gmal.com", "gmail.com", 1},  // Deletion
+		{"gmaill.com", "gmail.com", 1},
+		{"gnail.com", "gmail.com", 1}, // Substitution
+		{"hotmial.co", "hotmail.com", 2},
+		{"", "abc", 3},
+		{"abc", "", 3},
+		{"ca", "abc", 3},
+		{"bücher.de", "bucher.de", 1},
+	}
+	for _, tt := range tests {
+		if got := Distance(tt.a, tt.b); got != tt.want {
+			t.Errorf("Distance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+// loadCorrector loads the bundled known domains only
+func loadCorrector(t *testing.T) *Corrector {
+	t.Helper()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	c, err := Load(Options{Enabled: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestSuggest(t *testing.T) {
+	c := loadCorrector(t)
+	tests := []struct {
+		domain string
+		want   string
+	}{
+		// Typos of known domains
+		{"gmial.com", "gmail.com"},
+		{"gmal.com", "gmail.com"},
+		{"gmail.co", "gmail.com"},
+		{"hotmial.com", "hotmail.com"},
+		{"yaho.com", "yahoo.com"},
+		{"yahooo.com", "yahoo.com"},
+		{"outlok.com", "outlook.com"},
+		{"icloud.co", "icloud.com"},
+		{"Gmial.COM", "gmail.com"},
+
+		// Known and real domains are left alone
+		{"gmail.com", ""},
+		{"email.com", ""},
+		{"mail.com", ""},
+		{"yahoo.fr", ""},
+		{"gm.com", ""},
+		{"ge.com", ""},
+		{"ibm.com", ""},
+		{"tmail.com", ""},
+		{"fmail.com", ""},
+		{"acme.com", ""},
+		{"corp.com", ""},
+		{"mx.com", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got, _ := c.Suggest(tt.domain); got != tt.want {
+			t.Errorf("Suggest(%q) = %q, want %q", tt.domain, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package validate
+
+import (
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"website-copier/cmd/records"
+)
+
+// Columns added to the quarantine output
+const (
+	ColumnSourceFile = "Source File"
+	ColumnSourceRow  = "Source Row"
+	ColumnStatus     = "Email Status"
+	ColumnProblem    = "Email Problem"
+)
+
+// QuarantinePath returns the quarantine file next to an output file
+func QuarantinePath(outputFilePath string) string {
+	ext := filepath.Ext(outputFilePath)
+	return strings.TrimSuffix(outputFilePath, ext) + "_quarantine" + ext
+}
+
+// QuarantineWriter writes quarantined records with where they came from and why
+type QuarantineWriter struct {
+	writer  records.RecordWriter
+	headers []string
+}
+
+// CreateQuarantineWriter creates a quarantine file laying records out under headers
+func CreateQuarantineWriter(filename string, headers []string, opts records.WriterOptions) (*QuarantineWriter, error) {
+	all := append(append([]string(nil), headers...), ColumnSourceFile, ColumnSourceRow, ColumnStatus, ColumnProblem)
+	writer, err := records.CreateRecordWriter(filename, all, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &QuarantineWriter{writer: writer, headers: headers}, nil
+}
+
+func (w *QuarantineWriter) Write(q Quarantined) error {
+	out := q.Record.Select(w.headers)
+	out.OthersMap[ColumnSourceFile] = q.Record.Source()
+	out.OthersMap[ColumnSourceRow] = strconv.Itoa(q.Record.Row)
+	out.OthersMap[ColumnStatus] = q.Status
+	out.OthersMap[ColumnProblem] = q.Problem
+	return w.writer.Write(out)
+}
+
+func (w *QuarantineWriter) Close() error {
+	return w.writer.Close()
+}
@@ -0,0 +1,237 @@
+package validate
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"unicode/utf8"
+
+	"website-copier/cmd/normalize"
+
+	"golang.org/x/net/idna"
+)
+
+// Statuses an email cell is classified as
+const (
+	Valid         = "valid"
+	InvalidSyntax = "invalid-syntax"
+	Multiple      = "multiple-addresses"
+	RoleAccount   = "role-account" // A valid address of a function rather than a person, e.g. info@
+	Empty         = "empty"        // Blank or a placeholder such as n/a
+)
+
+// Statuses lists every status in the order they are reported
+var Statuses = []string{Valid, InvalidSyntax, Multiple, RoleAccount, Empty}
+
+// RoleAccounts are the local parts of shared mailboxes
+var RoleAccounts = map[string]bool{
+	"info": true, "admin": true, "administrator": true, "contact": true, "hello": true,
+	"office": true, "sales": true, "support": true, "help": true, "helpdesk": true,
+	"billing": true, "accounts": true, "accounting": true, "finance": true, "marketing": true,
+	"newsletter": true, "noreply": true, "no-reply": true, "donotreply": true, "do-not-reply": true,
+	"postmaster": true, "hostmaster": true, "webmaster": true, "abuse": true, "security": true,
+	"root": true, "team": true, "enquiries": true, "enquiry": true, "inquiries": true,
+	"jobs": true, "careers": true, "hr": true, "recruitment": true, "press": true,
+	"media": true, "service": true, "customerservice": true, "orders": true, "mail": true,
+}
+
+// placeholders are cell values meaning that there is no address
+var placeholders = map[string]bool{
+	"-": true, "--": true, "n/a": true, "na": true, "none": true, "null": true, "nil": true,
+	"unknown": true, "no email": true, "noemail": true, "tbd": true, "?": true,
+}
+
+// Result is the classification of an email cell
+type Result struct {
+	Status    string
+	Address   string   // The address without display name or mailto: prefix, when there is one
+	Addresses []string // Every address of a cell holding several
+	Problem   string   // Why the cell is not a valid address
+}
+
+// Check classifies an email cell. Addresses are checked against the addr-spec
+// of RFC 5322, allowing UTF-8 in the local part and domain as RFC 6531 does.
+func Check(value string) Result {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return Result{Status: Empty, Problem: "no email"}
+	}
+	if placeholders[strings.ToLower(value)] {
+		return Result{Status: Empty, Problem: fmt.Sprintf("placeholder %q", value)}
+	}
+
+	// Look for several addresses first, as "A <a@x.com>, B <b@y.com>" would
+	// otherwise pass as the last one
+	if addresses := splitAddresses(value); len(addresses) > 1 {
+		return Result{Status: Multiple, Addresses: addresses, Problem: fmt.Sprintf("%d addresses in one cell", len(addresses))}
+	}
+
+	address, problem := checkAddress(value)
+	if problem != "" {
+		return Result{Status: InvalidSyntax, Problem: problem}
+	}
+	if isRoleAccount(address) {
+		return Result{Status: RoleAccount, Address: address, Problem: "shared mailbox"}
+	}
+	return Result{Status: Valid, Address: address}
+}
+
+// splitAddresses splits a cell at separators and returns the parts that look
+// like addresses, or nil when there are fewer than two
+func splitAddresses(value string) []string {
+	parts := strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == ';' || r == '|' || r == '\n' || r == '\r' || r == '\t'
+	})
+	if len(parts) < 2 {
+		parts = strings.Fields(value)
+	}
+	var addresses []string
+	for _, part := range parts {
+		if part = strings.TrimSpace(part); strings.Contains(part, "@") {
+			addresses = append(addresses, part)
+		}
+	}
+	if len(addresses) < 2 {
+		return nil
+	}
+	return addresses
+}
+
+// checkAddress strips a display name and mailto: prefix and checks the address,
+// returning it or the problem found
+func checkAddress(value string) (string, string) {
+	address := normalize.StripDisplayName(normalize.Trim(value))
+	if len(address) > 254 {
+		return "", "longer than 254 characters"
+	}
+	at := strings.LastIndex(address, "@")
+	if at == -1 {
+		return "", "missing @"
+	}
+	local, domain := address[:at], address[at+1:]
+	if problem := checkLocal(local); problem != "" {
+		return "", problem
+	}
+	if problem := checkDomain(domain); problem != "" {
+		return "", problem
+	}
+	return address, ""
+}
+
+// checkLocal checks a dot-atom or quoted-string local part
+func checkLocal(local string) string {
+	switch {
+	case local == "":
+		return "missing local part"
+	case len(local) > 64:
+		return "local part longer than 64 characters"
+	case !utf8.ValidString(local):
+		return "local part is not valid UTF-8"
+	}
+
+	if len(local) >= 2 && local[0] == '"' && local[len(local)-1] == '"' {
+		quoted := local[1 : len(local)-1]
+		for i := 0; i < len(quoted); i++ {
+			switch c := quoted[i]; {
+			case c == '\\':
+				i++ // quoted-pair
+				if i == len(quoted) {
+					return "quoted local part ends with a backslash"
+				}
+			case c == '"':
+				return "unescaped quote in local part"
+			case c < 0x20 && c != '\t' || c == 0x7f:
+				return "control character in local part"
+			}
+		}
+		return ""
+	}
+
+	if local[0] == '.' || local[len(local)-1] == '.' || strings.Contains(local, "..") {
+		return "misplaced dot in local part"
+	}
+	for _, r := range local {
+		if r != '.' && !isAtext(r) {
+			return fmt.Sprintf("invalid character %q in local part", r)
+		}
+	}
+	return ""
+}
+
+// isAtext reports whether r may appear unquoted in a local part
+func isAtext(r rune) bool {
+	switch {
+	case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		return true
+	case r >= 0x80:
+		return r != utf8.RuneError // UTF8-non-ascii, RFC 6531
+	}
+	return strings.ContainsRune("!#$%&'*+-/=?^_`{|}~", r)
+}
+
+// checkDomain checks a host name or a bracketed IP address literal
+func checkDomain(domain string) string {
+	if domain == "" {
+		return "missing domain"
+	}
+	if strings.HasPrefix(domain, "[") && strings.HasSuffix(domain, "]") {
+		literal := domain[1 : len(domain)-1]
+		if ip := strings.TrimPrefix(literal, "IPv6:"); ip != literal {
+			if parsed := net.ParseIP(ip); parsed != nil && parsed.To4() == nil {
+				return ""
+			}
+		} else if parsed := net.ParseIP(literal); parsed != nil && parsed.To4() != nil {
+			return ""
+		}
+		return "invalid address literal"
+	}
+
+	ascii := domain
+	for _, r := range domain {
+		if r >= 0x80 {
+			converted, err := idna.Lookup.ToASCII(domain)
+			if err != nil {
+				return "invalid international domain"
+			}
+			ascii = converted
+			break
+		}
+	}
+	if len(ascii) > 253 {
+		return "domain longer than 253 characters"
+	}
+	labels := strings.Split(ascii, ".")
+	if len(labels) < 2 {
+		return "domain has no dot"
+	}
+	for _, label := range labels {
+		if label == "" {
+			return "misplaced dot in domain"
+		}
+		if len(label) > 63 {
+			return "domain label longer than 63 characters"
+		}
+		if label[0] == '-' || label[len(label)-1] == '-' {
+			return "domain label starts or ends with a hyphen"
+		}
+		for i := 0; i < len(label); i++ {
+			c := label[i]
+			if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-') {
+				return fmt.Sprintf("invalid character %q in domain", c)
+			}
+		}
+	}
+	if tld := labels[len(labels)-1]; strings.Trim(tld, "0123456789") == "" {
+		return "numeric top-level domain"
+	}
+	return ""
+}
+
+// isRoleAccount reports whether the local part, without +tag, names a shared mailbox
+func isRoleAccount(address string) bool {
+	local := strings.ToLower(address[:strings.LastIndex(address, "@")])
+	if i := strings.Index(local, "+"); i > 0 {
+		local = local[:i]
+	}
+	return RoleAccounts[local]
+}
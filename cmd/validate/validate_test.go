@@ -0,0 +1,118 @@
+package validate
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"website-copier/cmd/records"
+)
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		value   string
+		status  string
+		address string
+	}{
+		// Valid addresses
+		{"ann@example.com", Valid, "ann@example.com"},
+		{"  Ann.Lee+news@Example.co.uk ", Valid, "Ann.Lee+news@Example.co.uk"},
+		{`"Lee, Ann" <ann@example.com>`, Valid, "ann@example.com"},
+		{"mailto:ann@example.com", Valid, "ann@example.com"},
+		{`"ann lee"@example.com`, Valid, `"ann lee"@example.com`},
+		{"o'brien@example.ie", Valid, "o'brien@example.ie"},
+		{"josé@münchen.de", Valid, "josé@münchen.de"},
+		{"ann@[192.0.2.1]", Valid, "ann@[192.0.2.1]"},
+		{"ann@[IPv6:2001:db8::1]", Valid, "ann@[IPv6:2001:db8::1]"},
+
+		// Role accounts
+		{"info@example.com", RoleAccount, "info@example.com"},
+		{"Sales+eu@example.com", RoleAccount, "Sales+eu@example.com"},
+		{"no-reply@example.com", RoleAccount, "no-reply@example.com"},
+
+		// Blanks and placeholders
+		{"", Empty, ""},
+		{"   ", Empty, ""},
+		{"N/A", Empty, ""},
+		{"unknown", Empty, ""},
+
+		// Several addresses
+		{"ann@example.com; bob@example.com", Multiple, ""},
+		{"Ann <ann@example.com>, Bob <bob@example.com>", Multiple, ""},
+		{"ann@example.com bob@example.com", Multiple, ""},
+
+		// Invalid syntax
+		{"ann.example.com", InvalidSyntax, ""},
+		{"@example.com", InvalidSyntax, ""},
+		{"ann@", InvalidSyntax, ""},
+		{"ann@localhost", InvalidSyntax, ""},
+		{".ann@example.com", InvalidSyntax, ""},
+		{"ann..lee@example.com", InvalidSyntax, ""},
+		{"ann lee@example.com", InvalidSyntax, ""},
+		{`"ann"lee"@example.com`, InvalidSyntax, ""},
+		{"ann@example..com", InvalidSyntax, ""},
+		{"ann@-example.com", InvalidSyntax, ""},
+		{"ann@exa_mple.com", InvalidSyntax, ""},
+		{"ann@example.123", InvalidSyntax, ""},
+		{"ann@[300.0.0.1]", InvalidSyntax, ""},
+		{"ann@[IPv6:192.0.2.1]", InvalidSyntax, ""},
+		{strings.Repeat("a", 65) + "@example.com", InvalidSyntax, ""},
+		{"ann@" + strings.Repeat("a", 64) + ".com", InvalidSyntax, ""},
+		{"ann\xff@example.com", InvalidSyntax, ""},
+	}
+	for _, tt := range tests {
+		got := Check(tt.value)
+		if got.Status != tt.status || got.Address != tt.address {
+			t.Errorf("Check(%q) = %s %q (%s), want %s %q", tt.value, got.Status, got.Address, got.Problem, tt.status, tt.address)
+		}
+		if got.Status != Valid && got.Problem == "" {
+			t.Errorf("Check(%q) = %s without a problem", tt.value, got.Status)
+		}
+	}
+}
+
+func TestCheckMultiple(t *testing.T) {
+	got := Check("Ann <ann@example.com>; bob@example.com | n/a")
+	want := []string{"Ann <ann@example.com>", "bob@example.com"}
+	if got.Status != Multiple || !reflect.DeepEqual(got.Addresses, want) {
+		t.Errorf("Check = %s %q, want %s %q", got.Status, got.Addresses, Multiple, want)
+	}
+}
+
+func TestValidator(t *testing.T) {
+	cells := []string{"ann@example.com", "info@example.com", "n/a", "bob@", "cat@example.com, info@example.com, dan@"}
+	tests := []struct {
+		name        string
+		opts        Options
+		kept        []string
+		quarantined []string // Statuses of the records quarantined
+	}{
+		{"defaults", Options{Enabled: true}, []string{"ann@example.com", "info@example.com"}, []string{Empty, InvalidSyntax, Multiple}},
+		{"role accounts", Options{Enabled: true, QuarantineRoles: true}, []string{"ann@example.com"}, []string{RoleAccount, Empty, InvalidSyntax, Multiple}},
+		{"split", Options{Enabled: true, SplitMultiple: true}, []string{"ann@example.com", "info@example.com", "cat@example.com", "info@example.com"}, []string{Empty, InvalidSyntax, InvalidSyntax}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := New(tt.opts)
+			var kept, quarantined []string
+			for _, cell := range cells {
+				keep, q := v.Check(records.Record{Email: cell, OthersMap: map[string]string{"Phone": "555"}})
+				for _, r := range keep {
+					kept = append(kept, r.Email)
+				}
+				for _, r := range q {
+					quarantined = append(quarantined, r.Status)
+				}
+			}
+			if !reflect.DeepEqual(kept, tt.kept) {
+				t.Errorf("kept %q, want %q", kept, tt.kept)
+			}
+			if !reflect.DeepEqual(quarantined, tt.quarantined) {
+				t.Errorf("quarantined %q, want %q", quarantined, tt.quarantined)
+			}
+			if v.Quarantined() != len(tt.quarantined) {
+				t.Errorf("Quarantined() = %d, want %d", v.Quarantined(), len(tt.quarantined))
+			}
+		})
+	}
+}
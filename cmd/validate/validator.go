@@ -0,0 +1,92 @@
+package validate
+
+import (
+	"fmt"
+	"strings"
+
+	"website-copier/cmd/records"
+)
+
+// Options switches email validation on for a job
+type Options struct {
+	Enabled         bool
+	SplitMultiple   bool // Split cells holding several addresses into one record each instead of quarantining them
+	QuarantineRoles bool // Quarantine role accounts such as info@ as well
+}
+
+// Quarantined is a record set aside because of its email
+type Quarantined struct {
+	Record  records.Record
+	Status  string
+	Problem string
+}
+
+// Validator classifies the records of a job, counting every status. Only the
+// counts are kept, the quarantined records go back to the caller to be written
+// out as they come. It is not safe for concurrent use.
+type Validator struct {
+	opts        Options
+	counts      map[string]int
+	quarantined int
+}
+
+// New creates a validator
+func New(opts Options) *Validator {
+	return &Validator{opts: opts, counts: make(map[string]int)}
+}
+
+// Check classifies a record and returns the records to carry on with: none when
+// it is quarantined, the record itself, or one record per address when a cell
+// holding several is split, along with the records quarantined.
+func (v *Validator) Check(record records.Record) ([]records.Record, []Quarantined) {
+	result := Check(record.Email)
+	if result.Status == Multiple && v.opts.SplitMultiple {
+		// Count the cell, then each of its addresses
+		v.counts[Multiple]++
+		var keep []records.Record
+		var quarantined []Quarantined
+		for _, address := range result.Addresses {
+			part := copyRecord(record)
+			part.Email = address
+			k, q := v.Check(part)
+			keep = append(keep, k...)
+			quarantined = append(quarantined, q...)
+		}
+		return keep, quarantined
+	}
+
+	v.counts[result.Status]++
+	if result.Status == Valid || result.Status == RoleAccount && !v.opts.QuarantineRoles {
+		return []records.Record{record}, nil
+	}
+	v.quarantined++
+	return nil, []Quarantined{{Record: record, Status: result.Status, Problem: result.Problem}}
+}
+
+// Count returns the number of addresses classified with a status
+func (v *Validator) Count(status string) int {
+	return v.counts[status]
+}
+
+// Summary describes the counts of every status
+func (v *Validator) Summary() string {
+	parts := make([]string, len(Statuses))
+	for i, status := range Statuses {
+		parts[i] = fmt.Sprintf("%d %s", v.counts[status], status)
+	}
+	return "Email validation: " + strings.Join(parts, ", ")
+}
+
+// Quarantined returns the number of records quarantined so far
+func (v *Validator) Quarantined() int {
+	return v.quarantined
+}
+
+func copyRecord(r records.Record) records.Record {
+	others := make(map[string]string, len(r.OthersMap))
+	for k, v := range r.OthersMap {
+		others[k] = v
+	}
+	r.OthersMap = others
+	return r
+}
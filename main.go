@@ -1,8 +1,13 @@
 package main
 
 import (
-	"website-copier/cmd/combine"
-	"website-copier/cmd/filter"
+	"fmt"
+	"os"
+
+	"website-copier/cmd/cli"
+	"website-copier/cmd/gui/combine"
+	"website-copier/cmd/gui/compare"
+	"website-copier/cmd/gui/filter"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/app"
@@ -11,6 +16,15 @@ import (
 )
 
 func main() {
+	// Run headless when a subcommand is given, e.g. "datamerge combine --in DIR --out FILE"
+	if len(os.Args) > 1 && cli.IsCommand(os.Args[1]) {
+		if err := cli.Run(os.Args[1:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	// Create the GUI application
 	myApp := app.New()
 	myWindow := myApp.NewWindow("DataMerge Pro")
@@ -21,6 +35,7 @@ func main() {
 	// Create content containers for each screen
 	combineScreen := combine.CreateCombineScreen(myWindow)
 	filterScreen := filter.CreateFilterScreen(myWindow)
+	compareScreen := compare.CreateCompareScreen(myWindow)
 
 	// Create a container to hold the current screen content
 	contentContainer := container.NewMax()
@@ -39,7 +54,11 @@ func main() {
 		switchScreen(filterScreen)
 	})
 
-	menu.Objects = []fyne.CanvasObject{combineBtn, filterBtn}
+	compareBtn := widget.NewButton("Compare Files", func() {
+		switchScreen(compareScreen)
+	})
+
+	menu.Objects = []fyne.CanvasObject{combineBtn, filterBtn, compareBtn}
 
 	// Initial screen
 	switchScreen(combineScreen)